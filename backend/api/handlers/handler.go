@@ -1,17 +1,29 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/log"
 	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/clusters"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/configs"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/deployments"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/events"
+	kubehibernate "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/hibernate"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/hpa"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/jobs"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/nodes"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/pods"
 	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/kubernetes/service"
 	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/prometheus"
 	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
 	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware/metrics"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/autorollback"
 	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/config"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/hibernate"
 	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
 	prometheusclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/prometheus_client"
 )
@@ -21,10 +33,20 @@ type Handler struct {
 	authMiddleware *middleware.AuthenticationMiddleware
 	router         *fiber.App
 	promClient     *prometheusclient.Client
-	kubeClient     *kuberclient.Client
+	clusterMW      *middleware.ClusterMiddleware
+	revealMW       *middleware.RevealMiddleware
+	kubeClusters   *clusters.Handler
 	kubeMetrics    *kubernetes.MetricsHandler
 	promMetrics    *prometheus.MetricsHandler
 	kubeService    *service.Handler
+	kubeDeploy     *deployments.Handler
+	kubePods       *pods.Handler
+	kubeNodes      *nodes.Handler
+	kubeEvents     *events.Handler
+	kubeHPA        *hpa.Handler
+	kubeConfigs    *configs.Handler
+	kubeHibernate  *kubehibernate.Handler
+	kubeJobs       *jobs.Handler
 }
 
 func (h *Handler) Run() error {
@@ -40,37 +62,82 @@ func NewHandler(log *slog.Logger, auth *middleware.AuthenticationMiddleware) *Ha
 	cfg := config.NewConfig()
 	promClient := prometheusclient.NewClient(cfg.PrometheusURL)
 
-	// Initialize Kubernetes client once
-	kubeClient, err := kuberclient.NewClient()
+	// Initialize one Kubernetes client per configured cluster, or a fake one in sandbox mode
+	var registry *kuberclient.Registry
+	var err error
+	if cfg.Sandbox {
+		log.Warn("Sandbox mode, serving an in-memory fake cluster", "fixture", cfg.SandboxFixture)
+		registry, err = kuberclient.NewSandboxRegistry(cfg.SandboxFixture)
+	} else {
+		var clusterConfigs []kuberclient.ClusterConfig
+		clusterConfigs, err = kuberclient.ParseClusters(cfg.KubeClusters)
+		if err == nil {
+			registry, err = kuberclient.NewRegistry(clusterConfigs, cfg.KubeDefaultCluster)
+		}
+	}
 	if err != nil {
-		log.Error("Failed to initialize Kubernetes client", "error", err)
-		// Continue without Kubernetes client
+		log.Error("Failed to initialize Kubernetes clients", "error", err)
+		// Continue without Kubernetes clients
+	} else {
+		for _, cluster := range registry.Names() {
+			if _, err := registry.Get(cluster); err != nil {
+				log.Error("Failed to initialize Kubernetes client", "cluster", cluster, "error", err)
+			}
+		}
+		if cfg.KubeCache {
+			// Read endpoints query the API server directly until the caches have synced
+			registry.StartCaches(context.Background())
+		}
 	}
 
-	// Pass the Kubernetes client to both the metrics handler and service handler
-	kubeMetrics := kubernetes.NewMetricsHandler(log, kubeClient)
-	promMetrics := prometheus.NewMetricsHandler(log, cfg.PrometheusURL)
+	rollbackPolicy, err := autorollback.ParsePolicy(cfg.AutoRollback)
+	if err != nil {
+		log.Error("Failed to parse auto rollback namespaces, only requests can opt in", "error", err)
+	}
 
-	// Initialize Kubernetes service handler with the same client
-	var kubeService *service.Handler
-	if kubeClient != nil {
-		kubeService, err = service.NewHandler(log, kubeClient)
-		if err != nil {
-			log.Error("Failed to initialize Kubernetes service handler", "error", err)
-			// Continue without Kubernetes service handler
-		}
-	} else {
-		log.Warn("Skipping Kubernetes service handler initialization due to missing Kubernetes client")
+	hibernateSchedules, err := hibernate.LoadSchedules(cfg.HibernateSchedules)
+	if err != nil {
+		log.Error("Failed to read hibernation schedules, nothing goes to sleep", "error", err, "file", cfg.HibernateSchedules)
 	}
+	scheduler, err := hibernate.NewScheduler(log, registry, hibernateSchedules)
+	if err != nil {
+		log.Error("Invalid hibernation schedules, nothing goes to sleep", "error", err)
+		scheduler, _ = hibernate.NewScheduler(log, registry, nil)
+	}
+	scheduler.Start(context.Background())
+
+	// The cluster middleware hands the selected cluster's client to the Kubernetes handlers
+	kubeClusters := clusters.NewHandler(log, registry)
+	kubeMetrics := kubernetes.NewMetricsHandler(log)
+	promMetrics := prometheus.NewMetricsHandler(log, cfg.PrometheusURL)
+	kubeService := service.NewHandler(log, cfg.PrometheusURL, rollbackPolicy)
+	kubeDeploy := deployments.NewHandler(log)
+	kubePods := pods.NewHandler(log)
+	kubeNodes := nodes.NewHandler(log)
+	kubeEvents := events.NewHandler(log)
+	kubeHPA := hpa.NewHandler(log)
+	kubeConfigs := configs.NewHandler(log)
+	kubeHibernate := kubehibernate.NewHandler(log, scheduler)
+	kubeJobs := jobs.NewHandler(log)
 
 	return &Handler{
 		log:            log,
 		authMiddleware: auth,
 		promClient:     promClient,
-		kubeClient:     kubeClient,
+		clusterMW:      middleware.NewClusterMiddleware(registry),
+		revealMW:       middleware.NewRevealMiddleware(cfg.RevealKeys),
+		kubeClusters:   kubeClusters,
 		kubeMetrics:    kubeMetrics,
 		promMetrics:    promMetrics,
 		kubeService:    kubeService,
+		kubeDeploy:     kubeDeploy,
+		kubePods:       kubePods,
+		kubeNodes:      kubeNodes,
+		kubeEvents:     kubeEvents,
+		kubeHPA:        kubeHPA,
+		kubeConfigs:    kubeConfigs,
+		kubeHibernate:  kubeHibernate,
+		kubeJobs:       kubeJobs,
 	}
 }
 
@@ -86,13 +153,20 @@ func (h *Handler) InitRoutes(cfg fiber.Config) {
 	// Unsecure ping
 	api.Get("/ping", h.ping)
 
+	// Unsecure readiness, fails until the Kubernetes caches have synced
+	api.Get("/ready", h.kubeClusters.Ready)
+
 	v1 := api.Group("/v1")
 	// v1.Use(h.authMiddleware.Authenticate)
 
 	// Kubernetes metrics endpoints
 	kubernetes := v1.Group("/kubernetes")
 
-	kubeMetrics := kubernetes.Group("/metrics")
+	// Configured clusters and their health
+	kubernetes.Get("/clusters", h.kubeClusters.ListClusters)
+
+	// Every other Kubernetes route takes a ?cluster= parameter, see ClusterMiddleware
+	kubeMetrics := kubernetes.Group("/metrics", h.clusterMW.Resolve)
 	kubeMetrics.Get("/cluster", h.kubeMetrics.GetClusterMetrics)
 	kubeMetrics.Get("/nodes", h.kubeMetrics.GetNodeMetrics)
 	kubeMetrics.Get("/pods", h.kubeMetrics.GetPodMetrics)
@@ -100,13 +174,80 @@ func (h *Handler) InitRoutes(cfg fiber.Config) {
 	kubeMetrics.Get("/deployments", h.kubeMetrics.GetDeploymentsMetrics)
 	kubeMetrics.Get("/deployments/:name", h.kubeMetrics.GetDeploymentStatus)
 
+	// Kubernetes deployment operations
+	kubeDeployGroup := kubernetes.Group("/deployments", h.clusterMW.Resolve)
+	kubeDeployGroup.Get("/:name/history", h.kubeDeploy.GetHistory)
+	kubeDeployGroup.Get("/:name/diff", h.kubeDeploy.GetDiff)
+	kubeDeployGroup.Get("/:name/rollout", h.kubeDeploy.GetRolloutStatus)
+	kubeDeployGroup.Get("/:name/rollout/stream", h.kubeDeploy.StreamRolloutStatus)
+	kubeDeployGroup.Get("/:name/events", h.kubeEvents.GetDeploymentEvents)
+	kubeDeployGroup.Get("/:name/diagnose", h.kubeDeploy.Diagnose)
+
+	// Kubernetes events
+	kubeEventsGroup := kubernetes.Group("/events", h.clusterMW.Resolve)
+	kubeEventsGroup.Get("/", h.kubeEvents.GetNamespaceEvents)
+
+	// Kubernetes horizontal pod autoscalers
+	kubeHPAGroup := kubernetes.Group("/hpa", h.clusterMW.Resolve)
+	kubeHPAGroup.Get("/", h.kubeHPA.ListHPAs)
+	kubeHPAGroup.Get("/:name", h.kubeHPA.GetHPA)
+	kubeHPAGroup.Patch("/:name", h.kubeHPA.UpdateBounds)
+
+	// Kubernetes CronJobs and Jobs
+	kubeCronJobsGroup := kubernetes.Group("/cronjobs", h.clusterMW.Resolve)
+	kubeCronJobsGroup.Get("/", h.kubeJobs.ListCronJobs)
+	kubeCronJobsGroup.Get("/:name", h.kubeJobs.GetCronJob)
+	kubeCronJobsGroup.Post("/:name/trigger", h.kubeJobs.TriggerCronJob)
+	kubeCronJobsGroup.Post("/:name/suspend", h.kubeJobs.SuspendCronJob)
+	kubeCronJobsGroup.Post("/:name/resume", h.kubeJobs.ResumeCronJob)
+
+	kubeJobsGroup := kubernetes.Group("/jobs", h.clusterMW.Resolve)
+	kubeJobsGroup.Get("/", h.kubeJobs.ListJobs)
+	kubeJobsGroup.Get("/:name", h.kubeJobs.GetJob)
+	kubeJobsGroup.Delete("/:name", h.kubeJobs.DeleteJob)
+
+	// Kubernetes ConfigMaps and Secrets, revealing secret values needs a reveal key, see RevealMiddleware
+	kubeConfigMapsGroup := kubernetes.Group("/configmaps", h.clusterMW.Resolve)
+	kubeConfigMapsGroup.Get("/", h.kubeConfigs.ListConfigMaps)
+	kubeConfigMapsGroup.Get("/:name", h.kubeConfigs.GetConfigMap)
+	kubeConfigMapsGroup.Patch("/:name", h.kubeConfigs.PatchConfigMap)
+
+	kubeSecretsGroup := kubernetes.Group("/secrets", h.clusterMW.Resolve, h.revealMW.Authorize)
+	kubeSecretsGroup.Get("/", h.kubeConfigs.ListSecrets)
+	kubeSecretsGroup.Get("/:name", h.kubeConfigs.GetSecret)
+	kubeSecretsGroup.Patch("/:name", h.kubeConfigs.PatchSecret)
+
+	// Kubernetes node operations
+	kubeNodesGroup := kubernetes.Group("/nodes", h.clusterMW.Resolve)
+	kubeNodesGroup.Get("/:name", h.kubeNodes.GetNode)
+	kubeNodesGroup.Post("/:name/cordon", h.kubeNodes.CordonNode)
+	kubeNodesGroup.Post("/:name/uncordon", h.kubeNodes.UncordonNode)
+	kubeNodesGroup.Post("/:name/drain", h.kubeNodes.DrainNode)
+
+	// Kubernetes pod operations
+	kubePodsGroup := kubernetes.Group("/pods", h.clusterMW.Resolve)
+	kubePodsGroup.Get("/:namespace/:name", h.kubePods.GetPod)
+	kubePodsGroup.Get("/:namespace/:name/logs", h.kubePods.GetLogs)
+
+	// Hibernation schedules, each names its own cluster so they do not take ?cluster=
+	kubeHibernateGroup := kubernetes.Group("/hibernate")
+	kubeHibernateGroup.Get("/", h.kubeHibernate.ListSchedules)
+	kubeHibernateGroup.Post("/:name/skip", h.kubeHibernate.SkipSleep)
+	kubeHibernateGroup.Post("/:name/sleep", h.kubeHibernate.ForceSleep)
+	kubeHibernateGroup.Post("/:name/wake", h.kubeHibernate.ForceWake)
+
 	// Kubernetes service operations
-	kubeServiceGroup := kubernetes.Group("/service")
+	kubeServiceGroup := kubernetes.Group("/service", h.clusterMW.Resolve)
 	kubeServiceGroup.Post("/scale", h.kubeService.ScaleService)
 	kubeServiceGroup.Post("/restart", h.kubeService.RestartService)
 	kubeServiceGroup.Post("/rollback", h.kubeService.RollbackService)
 	kubeServiceGroup.Post("/update", h.kubeService.UpdateService)
+	kubeServiceGroup.Post("/pause", h.kubeService.PauseService)
+	kubeServiceGroup.Post("/resume", h.kubeService.ResumeService)
 	kubeServiceGroup.Post("/status", h.kubeService.GetServiceStatus)
+	kubeServiceGroup.Get("/watches", h.kubeService.ListWatches)
+	kubeServiceGroup.Post("/watches/cancel", h.kubeService.CancelWatch)
+	kubeServiceGroup.Get("/canaries/:id", h.kubeService.GetCanaryRun)
 
 	// Prometheus metrics endpoints
 	prometheusGroup := v1.Group("/prometheus")
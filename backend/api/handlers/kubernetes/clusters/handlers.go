@@ -0,0 +1,96 @@
+package clusters
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+type Handler struct {
+	log      *slog.Logger
+	registry *kuberclient.Registry
+}
+
+// NewHandler creates a new Kubernetes clusters handler
+func NewHandler(log *slog.Logger, registry *kuberclient.Registry) *Handler {
+	return &Handler{
+		log:      log,
+		registry: registry,
+	}
+}
+
+// ListClusters returns the configured clusters together with a health check of each
+func (h *Handler) ListClusters(c fiber.Ctx) error {
+	op := "ListClusters" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	if h.registry == nil {
+		log.Error("Kubernetes client not available", "error", "cluster registry is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clusters := h.registry.Health(ctx)
+
+	healthy := 0
+	for _, cluster := range clusters {
+		if cluster.Healthy {
+			healthy++
+		} else {
+			log.Warn("Cluster is unhealthy", "cluster", cluster.Name, "error", cluster.Error)
+		}
+	}
+
+	log.Info("Cluster health checked", "clusters", len(clusters), "healthy", healthy)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Clusters retrieved successfully",
+		"data": fiber.Map{
+			"default":  h.registry.Default(),
+			"clusters": clusters,
+		},
+	})
+}
+
+// Ready is a readiness probe: it fails until the informer caches of all available clusters have synced
+func (h *Handler) Ready(c fiber.Ctx) error {
+	op := "Ready" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	if h.registry == nil {
+		log.Error("Kubernetes client not available", "error", "cluster registry is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	synced, caches := h.registry.CachesSynced()
+	if !synced {
+		log.Debug("Kubernetes caches are not synced yet")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes caches are not synced yet",
+			"data": fiber.Map{
+				"caches": caches,
+			},
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Ready",
+		"data": fiber.Map{
+			"caches": caches,
+		},
+	})
+}
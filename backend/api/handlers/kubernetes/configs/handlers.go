@@ -0,0 +1,320 @@
+package configs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/listquery"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+type Handler struct {
+	log *slog.Logger
+}
+
+// NewHandler creates a new ConfigMap and Secret handler
+func NewHandler(log *slog.Logger) *Handler {
+	return &Handler{
+		log: log,
+	}
+}
+
+type PatchRequest struct {
+	Set     map[string]string `json:"set,omitempty"`
+	Remove  []string          `json:"remove,omitempty"`
+	DryRun  bool              `json:"dryRun,omitempty"`
+	Restart bool              `json:"restart,omitempty"` // Restart the deployments using the object once it changed
+}
+
+// ListConfigMaps returns the ConfigMaps of a namespace with their keys
+func (h *Handler) ListConfigMaps(c fiber.Ctx) error {
+	return h.list(c, "ListConfigMaps", kuberclient.KindConfigMap)
+}
+
+// GetConfigMap returns a ConfigMap with its values and the deployments using it
+func (h *Handler) GetConfigMap(c fiber.Ctx) error {
+	return h.get(c, "GetConfigMap", kuberclient.KindConfigMap)
+}
+
+// PatchConfigMap sets and removes keys of a ConfigMap
+func (h *Handler) PatchConfigMap(c fiber.Ctx) error {
+	return h.patch(c, "PatchConfigMap", kuberclient.KindConfigMap)
+}
+
+// ListSecrets returns the Secrets of a namespace with their keys, never their values
+func (h *Handler) ListSecrets(c fiber.Ctx) error {
+	return h.list(c, "ListSecrets", kuberclient.KindSecret)
+}
+
+// GetSecret returns a Secret and the deployments using it. Values are redacted unless the request
+// passed RevealMiddleware with ?reveal=true.
+func (h *Handler) GetSecret(c fiber.Ctx) error {
+	return h.get(c, "GetSecret", kuberclient.KindSecret)
+}
+
+// PatchSecret sets and removes keys of a Secret, the response never holds the values
+func (h *Handler) PatchSecret(c fiber.Ctx) error {
+	return h.patch(c, "PatchSecret", kuberclient.KindSecret)
+}
+
+func (h *Handler) list(c fiber.Ctx, opName, kind string) error {
+	op := opName + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+
+	query, err := listquery.Parse(c)
+	if err != nil {
+		log.Error("Invalid list query", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid list query",
+			"error":   err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	objects, page, err := kubeClient.ListConfigObjects(ctx, namespace, kind, query)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, kuberclient.ErrInvalidListQuery) {
+			status = fiber.StatusBadRequest
+		}
+		log.Error("Failed to list "+plural(kind), "error", err, "namespace", namespace)
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to list " + plural(kind),
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Config objects listed successfully", "kind", kind, "namespace", namespace, "count", len(objects))
+	listquery.SetPageHeaders(c, page)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": kind + "s retrieved successfully",
+		"data": fiber.Map{
+			"namespace":  namespace,
+			plural(kind): objects,
+		},
+	})
+}
+
+func (h *Handler) get(c fiber.Ctx, opName, kind string) error {
+	op := opName + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": kind + " name is required",
+		})
+	}
+
+	reveal := kind == kuberclient.KindSecret && middleware.Revealed(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	object, err := kubeClient.GetConfigObject(ctx, namespace, kind, name, reveal)
+	if err != nil {
+		log.Error("Failed to get "+kind, "error", err, "name", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get " + kind,
+			"error":   err.Error(),
+		})
+	}
+
+	if reveal {
+		log.Warn("Secret values revealed", "secret", name, "namespace", namespace, "ip", c.IP())
+	} else {
+		log.Info("Config object retrieved successfully", "kind", kind, "name", name, "namespace", namespace)
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": kind + " retrieved successfully",
+		"data":    object,
+	})
+}
+
+func (h *Handler) patch(c fiber.Ctx, opName, kind string) error {
+	op := opName + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": kind + " name is required",
+		})
+	}
+
+	var req PatchRequest
+	if err := c.Bind().Body(&req); err != nil {
+		log.Error("Failed to parse patch request", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := kubeClient.PatchConfigObject(ctx, kuberclient.ConfigPatch{
+		Namespace: namespace,
+		Name:      name,
+		Kind:      kind,
+		Set:       req.Set,
+		Remove:    req.Remove,
+		DryRun:    req.DryRun,
+	})
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, kuberclient.ErrInvalidConfigPatch):
+			status = fiber.StatusBadRequest
+		case errors.Is(err, kuberclient.ErrImmutableConfig):
+			status = fiber.StatusConflict
+		}
+		log.Error("Failed to patch "+kind, "error", err, "name", name, "namespace", namespace)
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to patch " + kind,
+			"error":   err.Error(),
+		})
+	}
+
+	data := fiber.Map{
+		"kind":      kind,
+		"name":      name,
+		"namespace": namespace,
+		"added":     result.Added,
+		"updated":   result.Updated,
+		"removed":   result.Removed,
+	}
+
+	if req.DryRun {
+		data["dryRun"] = true
+		data["changes"] = result.Changes
+
+		log.Info("Config object patch dry run completed", "kind", kind, "name", name, "namespace", namespace)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "success",
+			"message": "Dry run completed, no changes were persisted",
+			"data":    data,
+		})
+	}
+
+	if !result.Changed() {
+		log.Info("Config object already up to date", "kind", kind, "name", name, "namespace", namespace)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "success",
+			"message": kind + " already up to date",
+			"data":    data,
+		})
+	}
+
+	if req.Restart {
+		restarted, failed, err := restartConsumers(ctx, kubeClient, namespace, kind, name)
+		if err != nil {
+			log.Error("Failed to find deployments using the object", "error", err, "kind", kind, "name", name, "namespace", namespace)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"status":  "error",
+				"message": kind + " patched, but the deployments using it were not restarted",
+				"error":   err.Error(),
+				"data":    data,
+			})
+		}
+		data["restarted"] = restarted
+		if len(failed) > 0 {
+			data["restartErrors"] = failed
+			log.Error("Failed to restart deployments using the object", "kind", kind, "name", name, "namespace", namespace, "failed", len(failed))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"status":  "error",
+				"message": kind + " patched, but some deployments using it failed to restart",
+				"data":    data,
+			})
+		}
+	}
+
+	log.Info("Config object patched successfully", "kind", kind, "name", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": kind + " patched successfully",
+		"data":    data,
+	})
+}
+
+// restartConsumers restarts every deployment using a ConfigMap or Secret so its pods pick up the new values.
+// It returns the restarted deployments and the error of each deployment that failed to restart.
+func restartConsumers(ctx context.Context, kubeClient *kuberclient.Client, namespace, kind, name string) ([]string, map[string]string, error) {
+	consumers, err := kubeClient.ConfigConsumers(ctx, namespace, kind, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restarted := []string{}
+	failed := make(map[string]string)
+	for _, deployment := range consumers {
+		_, err := kubeClient.RestartDeployment(ctx, kuberclient.ServiceConfig{
+			Namespace: namespace,
+			Name:      deployment,
+		})
+		if err != nil {
+			failed[deployment] = err.Error()
+			continue
+		}
+		restarted = append(restarted, deployment)
+	}
+	return restarted, failed, nil
+}
+
+// plural names the list of a kind in responses, e.g. configMaps
+func plural(kind string) string {
+	return strings.ToLower(kind[:1]) + kind[1:] + "s"
+}
@@ -0,0 +1,58 @@
+package deployments
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+)
+
+// Diagnose inspects a deployment and its pods and returns the problems found, most severe first
+func (h *Handler) Diagnose(c fiber.Ctx) error {
+	op := "Diagnose" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Deployment name is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	diagnosis, err := kubeClient.DiagnoseDeployment(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to diagnose deployment", "error", err, "deployment", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to diagnose deployment",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Deployment diagnosed successfully", "deployment", name, "namespace", namespace,
+		"healthy", diagnosis.Healthy, "findings", len(diagnosis.Findings))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Deployment diagnosed successfully",
+		"data":    diagnosis,
+	})
+}
@@ -0,0 +1,84 @@
+package deployments
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// GetDiff compares the pod templates of two revisions of a deployment, or a revision and the live template.
+// ?from and ?to take a revision number, a ReplicaSet name or "live". With ?format=text the unified diff
+// is returned as plain text, ready to paste into a chat.
+func (h *Handler) GetDiff(c fiber.Ctx) error {
+	op := "GetDiff" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Deployment name is required",
+		})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "text" {
+		log.Error("Invalid format", "error", "unknown format "+format)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Format must be json or text",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	diff, err := kubeClient.DiffDeploymentRevisions(ctx, namespace, name, c.Query("from"), c.Query("to"))
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, kuberclient.ErrRevisionNotFound) {
+			status = fiber.StatusNotFound
+		}
+		log.Error("Failed to diff deployment revisions", "error", err, "deployment", name, "namespace", namespace)
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to diff deployment revisions",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Deployment revisions diffed successfully", "deployment", name, "namespace", namespace,
+		"from", diff.From.String(), "to", diff.To.String())
+
+	if format == "text" {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		if diff.Diff == "" {
+			return c.Status(fiber.StatusOK).SendString("No changes between " + diff.From.String() + " and " + diff.To.String() + "\n")
+		}
+		return c.Status(fiber.StatusOK).SendString(diff.Diff)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Deployment revisions diffed successfully",
+		"data":    diff,
+	})
+}
@@ -0,0 +1,72 @@
+package deployments
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+)
+
+type Handler struct {
+	log *slog.Logger
+}
+
+// NewHandler creates a new Kubernetes deployments handler
+func NewHandler(log *slog.Logger) *Handler {
+	return &Handler{
+		log: log,
+	}
+}
+
+// GetHistory returns the revision history of a deployment
+func (h *Handler) GetHistory(c fiber.Ctx) error {
+	op := "GetHistory" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Deployment name is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	history, err := kubeClient.GetDeploymentHistory(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to get deployment history", "error", err, "deployment", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get deployment history",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Deployment history retrieved successfully", "deployment", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Deployment history retrieved successfully",
+		"data": fiber.Map{
+			"name":      name,
+			"namespace": namespace,
+			"revisions": history,
+		},
+	})
+}
@@ -0,0 +1,119 @@
+package deployments
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/sse"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// Default time to follow a rollout stream when ?timeout is not set
+const defaultRolloutTimeout = 5 * time.Minute
+
+// GetRolloutStatus returns the current rollout status of a deployment
+func (h *Handler) GetRolloutStatus(c fiber.Ctx) error {
+	op := "GetRolloutStatus" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Deployment name is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	status, err := kubeClient.GetRolloutStatus(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to get rollout status", "error", err, "deployment", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get rollout status",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Rollout status retrieved successfully",
+		"data":    status,
+	})
+}
+
+// StreamRolloutStatus streams rollout progress of a deployment as Server-Sent Events.
+// Every observed status is sent as a "progress" event, followed by a final "done" or "error" event.
+func (h *Handler) StreamRolloutStatus(c fiber.Ctx) error {
+	op := "StreamRolloutStatus" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Deployment name is required",
+		})
+	}
+
+	timeout := defaultRolloutTimeout
+	if seconds := fiber.Query[int](c, "timeout"); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	sse.SetHeaders(c)
+
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		status, err := kubeClient.WaitForRollout(ctx, namespace, name, func(status kuberclient.RolloutStatus) {
+			// A failed flush means the client went away
+			if err := sse.WriteEvent(w, "progress", status); err != nil {
+				cancel()
+			}
+		})
+		if err != nil {
+			log.Error("Rollout did not complete", "error", err, "deployment", name, "namespace", namespace)
+			_ = sse.WriteEvent(w, "error", fiber.Map{
+				"error":   err.Error(),
+				"rollout": status,
+			})
+			return
+		}
+
+		log.Info("Rollout completed", "deployment", name, "namespace", namespace)
+		_ = sse.WriteEvent(w, "done", status)
+	})
+}
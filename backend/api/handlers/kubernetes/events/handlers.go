@@ -0,0 +1,136 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+type Handler struct {
+	log *slog.Logger
+}
+
+// NewHandler creates a new Kubernetes events handler
+func NewHandler(log *slog.Logger) *Handler {
+	return &Handler{
+		log: log,
+	}
+}
+
+// GetNamespaceEvents returns the events of a whole namespace
+func (h *Handler) GetNamespaceEvents(c fiber.Ctx) error {
+	op := "GetNamespaceEvents" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	filter := parseFilter(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	events, err := kubeClient.GetNamespaceEvents(ctx, namespace, filter)
+	if err != nil {
+		log.Error("Failed to get namespace events", "error", err, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get events",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Namespace events retrieved successfully", "namespace", namespace, "count", len(events))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Events retrieved successfully",
+		"data": fiber.Map{
+			"namespace": namespace,
+			"events":    events,
+		},
+	})
+}
+
+// GetDeploymentEvents returns the events of a deployment, its ReplicaSets and pods
+func (h *Handler) GetDeploymentEvents(c fiber.Ctx) error {
+	op := "GetDeploymentEvents" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Deployment name is required",
+		})
+	}
+
+	filter := parseFilter(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	events, err := kubeClient.GetDeploymentEvents(ctx, namespace, name, filter)
+	if err != nil {
+		log.Error("Failed to get deployment events", "error", err, "deployment", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get deployment events",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Deployment events retrieved successfully", "deployment", name, "namespace", namespace, "count", len(events))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Deployment events retrieved successfully",
+		"data": fiber.Map{
+			"name":      name,
+			"namespace": namespace,
+			"events":    events,
+		},
+	})
+}
+
+// parseFilter reads the comma separated type and reason query parameters
+func parseFilter(c fiber.Ctx) kuberclient.EventFilter {
+	return kuberclient.EventFilter{
+		Types:   splitList(c.Query("type")),
+		Reasons: splitList(c.Query("reason")),
+	}
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
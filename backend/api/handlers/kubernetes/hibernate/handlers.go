@@ -0,0 +1,152 @@
+package hibernate
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/hibernate"
+)
+
+type Handler struct {
+	log       *slog.Logger
+	scheduler *hibernate.Scheduler
+}
+
+// NewHandler creates a new hibernation schedules handler
+func NewHandler(log *slog.Logger, scheduler *hibernate.Scheduler) *Handler {
+	return &Handler{
+		log:       log,
+		scheduler: scheduler,
+	}
+}
+
+type SkipRequest struct {
+	Cancel bool `json:"cancel,omitempty"` // Withdraw an earlier skip
+}
+
+// ListSchedules returns the hibernation schedules with their next sleep and wake times and last run
+func (h *Handler) ListSchedules(c fiber.Ctx) error {
+	op := "ListSchedules" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	schedules := h.scheduler.List()
+
+	log.Info("Hibernation schedules listed", "count", len(schedules))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Hibernation schedules retrieved successfully",
+		"data":    schedules,
+	})
+}
+
+// SkipSleep skips the next scheduled sleep of a schedule, or withdraws the skip
+func (h *Handler) SkipSleep(c fiber.Ctx) error {
+	op := "SkipSleep" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	name := c.Params("name")
+
+	var req SkipRequest
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			log.Error("Failed to parse skip request", "error", err)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Invalid request format",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	schedule, err := h.scheduler.Skip(name, req.Cancel)
+	if err != nil {
+		log.Error("Failed to skip sleep", "error", err, "schedule", name)
+		return c.Status(scheduleErrorStatus(err)).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to skip sleep",
+			"error":   err.Error(),
+		})
+	}
+
+	message := "Next sleep skipped successfully"
+	if req.Cancel {
+		message = "Sleep skip cancelled successfully"
+	}
+
+	log.Info(message, "schedule", name)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": message,
+		"data":    schedule,
+	})
+}
+
+// ForceSleep puts the namespaces of a schedule to sleep right away
+func (h *Handler) ForceSleep(c fiber.Ctx) error {
+	return h.forceRun(c, "ForceSleep", hibernate.ActionSleep)
+}
+
+// ForceWake wakes the namespaces of a schedule up right away
+func (h *Handler) ForceWake(c fiber.Ctx) error {
+	return h.forceRun(c, "ForceWake", hibernate.ActionWake)
+}
+
+// forceRun runs a schedule on request and responds with the run
+func (h *Handler) forceRun(c fiber.Ctx, op, action string) error {
+	op += uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	name := c.Params("name")
+
+	var run *hibernate.Run
+	var err error
+	if action == hibernate.ActionSleep {
+		run, err = h.scheduler.Sleep(name)
+	} else {
+		run, err = h.scheduler.Wake(name)
+	}
+	if err != nil {
+		log.Error("Failed to run hibernation schedule", "error", err, "schedule", name, "action", action)
+		return c.Status(scheduleErrorStatus(err)).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to run hibernation schedule",
+			"error":   err.Error(),
+		})
+	}
+
+	if run.Error != "" {
+		log.Error("Hibernation run failed", "error", run.Error, "schedule", name, "action", action)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Hibernation run failed",
+			"error":   run.Error,
+			"data":    run,
+		})
+	}
+
+	message := "Namespaces put to sleep successfully"
+	if action == hibernate.ActionWake {
+		message = "Namespaces woken up successfully"
+	}
+
+	log.Info(message, "schedule", name)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": message,
+		"data":    run,
+	})
+}
+
+// scheduleErrorStatus maps scheduler errors to HTTP status codes
+func scheduleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, hibernate.ErrUnknownSchedule):
+		return fiber.StatusNotFound
+	case errors.Is(err, hibernate.ErrRunning):
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
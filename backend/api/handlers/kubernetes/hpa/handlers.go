@@ -0,0 +1,201 @@
+package hpa
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+)
+
+type Handler struct {
+	log *slog.Logger
+}
+
+// NewHandler creates a new HorizontalPodAutoscaler handler
+func NewHandler(log *slog.Logger) *Handler {
+	return &Handler{
+		log: log,
+	}
+}
+
+type UpdateBoundsRequest struct {
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	DryRun      bool   `json:"dryRun,omitempty"`
+}
+
+// ListHPAs returns the HorizontalPodAutoscalers of a namespace
+func (h *Handler) ListHPAs(c fiber.Ctx) error {
+	op := "ListHPAs" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	hpas, err := kubeClient.ListHPAs(ctx, namespace)
+	if err != nil {
+		log.Error("Failed to list horizontal pod autoscalers", "error", err, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to list horizontal pod autoscalers",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Horizontal pod autoscalers listed successfully", "namespace", namespace, "count", len(hpas))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Horizontal pod autoscalers retrieved successfully",
+		"data": fiber.Map{
+			"namespace": namespace,
+			"hpas":      hpas,
+		},
+	})
+}
+
+// GetHPA returns the status of a single HorizontalPodAutoscaler
+func (h *Handler) GetHPA(c fiber.Ctx) error {
+	op := "GetHPA" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "HPA name is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	hpa, err := kubeClient.GetHPA(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to get horizontal pod autoscaler", "error", err, "hpa", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get horizontal pod autoscaler",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Horizontal pod autoscaler retrieved successfully", "hpa", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Horizontal pod autoscaler retrieved successfully",
+		"data":    hpa,
+	})
+}
+
+// UpdateBounds patches the min and/or max replicas of a HorizontalPodAutoscaler
+func (h *Handler) UpdateBounds(c fiber.Ctx) error {
+	op := "UpdateHPABounds" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "HPA name is required",
+		})
+	}
+
+	var req UpdateBoundsRequest
+	if err := c.Bind().Body(&req); err != nil {
+		log.Error("Failed to parse update request", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+	}
+
+	if req.MinReplicas == nil && req.MaxReplicas == nil {
+		log.Error("Invalid bounds", "error", "neither minReplicas nor maxReplicas set")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "minReplicas or maxReplicas is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := kubeClient.UpdateHPABounds(ctx, namespace, name, req.MinReplicas, req.MaxReplicas, req.DryRun)
+	if err != nil {
+		log.Error("Failed to update horizontal pod autoscaler", "error", err, "hpa", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to update horizontal pod autoscaler",
+			"error":   err.Error(),
+		})
+	}
+
+	data := fiber.Map{
+		"name":      name,
+		"namespace": namespace,
+	}
+	if req.MinReplicas != nil {
+		data["minReplicas"] = *req.MinReplicas
+	}
+	if req.MaxReplicas != nil {
+		data["maxReplicas"] = *req.MaxReplicas
+	}
+
+	if req.DryRun {
+		data["dryRun"] = true
+		data["changes"] = result.Changes
+
+		log.Info("HPA update dry run completed", "hpa", name, "namespace", namespace)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "success",
+			"message": "Dry run completed, no changes were persisted",
+			"data":    data,
+		})
+	}
+
+	log.Info("Horizontal pod autoscaler updated successfully", "hpa", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Horizontal pod autoscaler updated successfully",
+		"data":    data,
+	})
+}
@@ -0,0 +1,342 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+type Handler struct {
+	log *slog.Logger
+}
+
+// NewHandler creates a new CronJobs and Jobs handler
+func NewHandler(log *slog.Logger) *Handler {
+	return &Handler{
+		log: log,
+	}
+}
+
+// ListCronJobs returns the CronJobs of a namespace with their last runs
+func (h *Handler) ListCronJobs(c fiber.Ctx) error {
+	op := "ListCronJobs" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cronJobs, err := kubeClient.ListCronJobs(ctx, namespace)
+	if err != nil {
+		log.Error("Failed to list cronjobs", "error", err, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to list cronjobs",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("CronJobs listed successfully", "namespace", namespace, "count", len(cronJobs))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "CronJobs retrieved successfully",
+		"data": fiber.Map{
+			"namespace": namespace,
+			"cronJobs":  cronJobs,
+		},
+	})
+}
+
+// GetCronJob returns a single CronJob with its Jobs
+func (h *Handler) GetCronJob(c fiber.Ctx) error {
+	op := "GetCronJob" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cronJob, err := kubeClient.GetCronJob(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to get cronjob", "error", err, "cronjob", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get cronjob",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("CronJob retrieved successfully", "cronjob", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "CronJob retrieved successfully",
+		"data":    cronJob,
+	})
+}
+
+// TriggerCronJob runs a CronJob right away by creating a Job from its template
+func (h *Handler) TriggerCronJob(c fiber.Ctx) error {
+	op := "TriggerCronJob" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	job, err := kubeClient.TriggerCronJob(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to trigger cronjob", "error", err, "cronjob", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to trigger cronjob",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("CronJob triggered successfully", "cronjob", name, "namespace", namespace, "job", job.Name)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "success",
+		"message": "CronJob triggered successfully",
+		"data":    job,
+	})
+}
+
+// SuspendCronJob stops a CronJob from scheduling new Jobs
+func (h *Handler) SuspendCronJob(c fiber.Ctx) error {
+	return h.setSuspended(c, "SuspendCronJob", true)
+}
+
+// ResumeCronJob lets a suspended CronJob schedule Jobs again
+func (h *Handler) ResumeCronJob(c fiber.Ctx) error {
+	return h.setSuspended(c, "ResumeCronJob", false)
+}
+
+// setSuspended suspends or resumes a CronJob
+func (h *Handler) setSuspended(c fiber.Ctx, op string, suspend bool) error {
+	op += uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	action := "resume"
+	if suspend {
+		action = "suspend"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var changed bool
+	var err error
+	if suspend {
+		changed, err = kubeClient.SuspendCronJob(ctx, namespace, name)
+	} else {
+		changed, err = kubeClient.ResumeCronJob(ctx, namespace, name)
+	}
+	if err != nil {
+		log.Error("Failed to "+action+" cronjob", "error", err, "cronjob", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to " + action + " cronjob",
+			"error":   err.Error(),
+		})
+	}
+
+	message := "CronJob resumed successfully"
+	if suspend {
+		message = "CronJob suspended successfully"
+	}
+
+	log.Info(message, "cronjob", name, "namespace", namespace, "skipped", !changed)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": message,
+		"data": fiber.Map{
+			"name":      name,
+			"namespace": namespace,
+			"suspended": suspend,
+			"skipped":   !changed,
+		},
+	})
+}
+
+// ListJobs returns the Jobs of a namespace newest first, ?status=failed keeps only the failed ones
+func (h *Handler) ListJobs(c fiber.Ctx) error {
+	op := "ListJobs" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	status := c.Query("status")
+
+	switch status {
+	case "", kuberclient.JobRunning, kuberclient.JobSucceeded, kuberclient.JobFailed, kuberclient.JobSuspended, kuberclient.JobPending:
+	default:
+		log.Error("Invalid status", "error", "unknown status "+status)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Status must be running, succeeded, failed, suspended or pending",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	jobs, err := kubeClient.ListJobs(ctx, namespace, status)
+	if err != nil {
+		log.Error("Failed to list jobs", "error", err, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to list jobs",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Jobs listed successfully", "namespace", namespace, "count", len(jobs))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Jobs retrieved successfully",
+		"data": fiber.Map{
+			"namespace": namespace,
+			"jobs":      jobs,
+		},
+	})
+}
+
+// GetJob returns a single Job with its failed pods
+func (h *Handler) GetJob(c fiber.Ctx) error {
+	op := "GetJob" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	job, err := kubeClient.GetJob(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to get job", "error", err, "job", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get job",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Job retrieved successfully", "job", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Job retrieved successfully",
+		"data":    job,
+	})
+}
+
+// DeleteJob deletes a failed Job and its pods
+func (h *Handler) DeleteJob(c fiber.Ctx) error {
+	op := "DeleteJob" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Query("namespace", "default")
+	name := c.Params("name")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := kubeClient.DeleteFailedJob(ctx, namespace, name); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, kuberclient.ErrJobNotFailed) {
+			status = fiber.StatusConflict
+		}
+		log.Error("Failed to delete job", "error", err, "job", name, "namespace", namespace)
+		return c.Status(status).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to delete job",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Job deleted successfully", "job", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Job deleted successfully",
+		"data": fiber.Map{
+			"name":      name,
+			"namespace": namespace,
+		},
+	})
+}
@@ -2,24 +2,26 @@ package kubernetes
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/listquery"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
 	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
 )
 
 type MetricsHandler struct {
-	log        *slog.Logger
-	kubeClient *kuberclient.Client
+	log *slog.Logger
 }
 
-func NewMetricsHandler(log *slog.Logger, client *kuberclient.Client) *MetricsHandler {
+func NewMetricsHandler(log *slog.Logger) *MetricsHandler {
 	return &MetricsHandler{
-		log:        log,
-		kubeClient: client,
+		log: log,
 	}
 }
 
@@ -35,26 +37,40 @@ type ClusterMetrics struct {
 }
 
 type NodeMetrics struct {
-	Name        string                 `json:"name"`
-	Status      string                 `json:"status"`
-	Allocatable map[string]interface{} `json:"allocatable"`
-	Capacity    map[string]interface{} `json:"capacity"`
-	Labels      map[string]string      `json:"labels"`
+	Name             string                   `json:"name"`
+	Status           string                   `json:"status"`
+	Unschedulable    bool                     `json:"unschedulable"`
+	KubeletVersion   string                   `json:"kubeletVersion"`
+	Pressure         kuberclient.NodePressure `json:"pressure"`
+	Taints           []kuberclient.NodeTaint  `json:"taints"`
+	Allocated        kuberclient.Allocated    `json:"allocated"`
+	Allocatable      map[string]interface{}   `json:"allocatable"`
+	Capacity         map[string]interface{}   `json:"capacity"`
+	Labels           map[string]string        `json:"labels"`
+	Usage            *kuberclient.Usage       `json:"usage,omitempty"`
+	UsageUnavailable string                   `json:"usageUnavailable,omitempty"`
 }
 
 type PodMetrics struct {
-	Name       string `json:"name"`
-	Namespace  string `json:"namespace"`
-	Status     string `json:"status"`
-	HostIP     string `json:"hostIP"`
-	PodIP      string `json:"podIP"`
-	StartTime  string `json:"startTime,omitempty"`
-	Containers int    `json:"containers"`
+	Name             string             `json:"name"`
+	Namespace        string             `json:"namespace"`
+	Status           string             `json:"status"`
+	HostIP           string             `json:"hostIP"`
+	PodIP            string             `json:"podIP"`
+	StartTime        string             `json:"startTime,omitempty"`
+	Containers       int                `json:"containers"`
+	Restarts         int32              `json:"restarts"`
+	Usage            *kuberclient.Usage `json:"usage,omitempty"`
+	UsageUnavailable string             `json:"usageUnavailable,omitempty"`
+	// Container statuses and owner, only with ?detail=true
+	Detail *kuberclient.PodDetail `json:"detail,omitempty"`
 }
 
 type DeploymentMetrics struct {
+	Kind      string `json:"kind"`
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
+	Status    string `json:"status"`
 	Replicas  int32  `json:"replicas"`
 	Available int32  `json:"available"`
 	Ready     int32  `json:"ready"`
@@ -64,7 +80,8 @@ func (h *MetricsHandler) GetClusterMetrics(c fiber.Ctx) error {
 	op := "GetClusterMetrics" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
-	if h.kubeClient == nil {
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
 		log.Error("Kubernetes client not available", "error", "kuber client is nil")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"error": "Kubernetes client not available",
@@ -72,7 +89,7 @@ func (h *MetricsHandler) GetClusterMetrics(c fiber.Ctx) error {
 	}
 
 	ctx := context.Background()
-	metrics, err := h.kubeClient.GetClusterMetrics(ctx)
+	metrics, err := kubeClient.GetClusterMetrics(ctx)
 	if err != nil {
 		log.Error("Failed to fetch cluster metrics", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -92,6 +109,7 @@ func (h *MetricsHandler) GetClusterMetrics(c fiber.Ctx) error {
 		Timestamp:     time.Now().Format(time.RFC3339),
 	}
 
+	setCacheHeaders(c, kubeClient)
 	return c.Status(fiber.StatusOK).JSON(clusterMetrics)
 }
 
@@ -99,7 +117,8 @@ func (h *MetricsHandler) GetNodeMetrics(c fiber.Ctx) error {
 	op := "GetNodeMetrics" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
-	if h.kubeClient == nil {
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
 		log.Error("Kubernetes client not available", "error", "kuber client is nil")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"error": "Kubernetes client not available",
@@ -109,7 +128,21 @@ func (h *MetricsHandler) GetNodeMetrics(c fiber.Ctx) error {
 	ctx := context.Background()
 	nodeName := c.Query("name", "") // Optional node name filter
 
-	metrics, err := h.kubeClient.GetNodeMetrics(ctx, nodeName)
+	query, err := listquery.Parse(c)
+	if err != nil {
+		log.Error("Invalid list query", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	metrics, page, err := kubeClient.GetNodeMetrics(ctx, nodeName, query)
+	if errors.Is(err, kuberclient.ErrInvalidListQuery) {
+		log.Error("Invalid list query", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		log.Error("Failed to fetch node metrics", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -125,6 +158,26 @@ func (h *MetricsHandler) GetNodeMetrics(c fiber.Ctx) error {
 			Status: nodeData["status"].(string),
 		}
 
+		if unschedulable, ok := nodeData["unschedulable"].(bool); ok {
+			node.Unschedulable = unschedulable
+		}
+
+		if version, ok := nodeData["kubeletVersion"].(string); ok {
+			node.KubeletVersion = version
+		}
+
+		if pressure, ok := nodeData["pressure"].(kuberclient.NodePressure); ok {
+			node.Pressure = pressure
+		}
+
+		if taints, ok := nodeData["taints"].([]kuberclient.NodeTaint); ok {
+			node.Taints = taints
+		}
+
+		if allocated, ok := nodeData["allocated"].(kuberclient.Allocated); ok {
+			node.Allocated = allocated
+		}
+
 		if allocatable, ok := nodeData["allocatable"].(map[string]interface{}); ok {
 			node.Allocatable = allocatable
 		}
@@ -137,9 +190,19 @@ func (h *MetricsHandler) GetNodeMetrics(c fiber.Ctx) error {
 			node.Labels = convertMapToStringString(labels)
 		}
 
+		if usage, ok := nodeData["usage"].(*kuberclient.Usage); ok {
+			node.Usage = usage
+		}
+
+		if reason, ok := nodeData["usageUnavailable"].(string); ok {
+			node.UsageUnavailable = reason
+		}
+
 		nodeMetrics = append(nodeMetrics, node)
 	}
 
+	setCacheHeaders(c, kubeClient)
+	listquery.SetPageHeaders(c, page)
 	return c.Status(fiber.StatusOK).JSON(nodeMetrics)
 }
 
@@ -147,7 +210,8 @@ func (h *MetricsHandler) GetPodMetrics(c fiber.Ctx) error {
 	op := "GetPodMetrics" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
-	if h.kubeClient == nil {
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
 		log.Error("Kubernetes client not available", "error", "kuber client is nil")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"error": "Kubernetes client not available",
@@ -156,8 +220,23 @@ func (h *MetricsHandler) GetPodMetrics(c fiber.Ctx) error {
 
 	ctx := context.Background()
 	namespace := c.Query("namespace", "") // Optional namespace filter
+	detailed := fiber.Query[bool](c, "detail")
 
-	metrics, err := h.kubeClient.GetPodMetrics(ctx, namespace)
+	query, err := listquery.Parse(c)
+	if err != nil {
+		log.Error("Invalid list query", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	metrics, page, err := kubeClient.GetPodMetrics(ctx, namespace, query, detailed)
+	if errors.Is(err, kuberclient.ErrInvalidListQuery) {
+		log.Error("Invalid list query", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		log.Error("Failed to fetch pod metrics", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -173,6 +252,7 @@ func (h *MetricsHandler) GetPodMetrics(c fiber.Ctx) error {
 			Namespace:  podData["namespace"].(string),
 			Status:     podData["status"].(string),
 			Containers: podData["containers"].(int),
+			Restarts:   podData["restarts"].(int32),
 		}
 
 		if hostIP, ok := podData["hostIP"].(string); ok {
@@ -183,6 +263,18 @@ func (h *MetricsHandler) GetPodMetrics(c fiber.Ctx) error {
 			pod.PodIP = podIP
 		}
 
+		if usage, ok := podData["usage"].(*kuberclient.Usage); ok {
+			pod.Usage = usage
+		}
+
+		if reason, ok := podData["usageUnavailable"].(string); ok {
+			pod.UsageUnavailable = reason
+		}
+
+		if detail, ok := podData["detail"].(*kuberclient.PodDetail); ok {
+			pod.Detail = detail
+		}
+
 		// Handle startTime which could be a time.Time or string
 		if startTimeData, ok := podData["startTime"]; ok {
 			switch st := startTimeData.(type) {
@@ -205,6 +297,8 @@ func (h *MetricsHandler) GetPodMetrics(c fiber.Ctx) error {
 		podMetrics = append(podMetrics, pod)
 	}
 
+	setCacheHeaders(c, kubeClient)
+	listquery.SetPageHeaders(c, page)
 	return c.Status(fiber.StatusOK).JSON(podMetrics)
 }
 
@@ -212,7 +306,8 @@ func (h *MetricsHandler) GetNamespaceMetrics(c fiber.Ctx) error {
 	op := "GetNamespaceMetrics" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
-	if h.kubeClient == nil {
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
 		log.Error("Kubernetes client not available", "error", "kuber client is nil")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"error": "Kubernetes client not available",
@@ -221,67 +316,27 @@ func (h *MetricsHandler) GetNamespaceMetrics(c fiber.Ctx) error {
 
 	ctx := context.Background()
 
-	// Get pod information to group by namespace
-	pods, err := h.kubeClient.GetPodMetrics(ctx, "")
+	// Pods are grouped by namespace from the cache, without live usage
+	namespaceMetrics, err := kubeClient.GetNamespaceMetrics(ctx)
 	if err != nil {
-		log.Error("Failed to fetch pod metrics", "error", err)
+		log.Error("Failed to fetch namespace metrics", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to fetch pod metrics: %v", err),
+			"error": fmt.Sprintf("Failed to fetch namespace metrics: %v", err),
 		})
 	}
 
-	// Group pods by namespace
-	type NamespaceMetrics struct {
-		Name      string `json:"name"`
-		PodCount  int    `json:"podCount"`
-		Running   int    `json:"running"`
-		Pending   int    `json:"pending"`
-		Failed    int    `json:"failed"`
-		Succeeded int    `json:"succeeded"`
-	}
-
-	namespaceMap := make(map[string]*NamespaceMetrics)
-
-	for _, pod := range pods {
-		namespace := pod["namespace"].(string)
-		if _, exists := namespaceMap[namespace]; !exists {
-			namespaceMap[namespace] = &NamespaceMetrics{
-				Name: namespace,
-			}
-		}
-
-		// Increment pod count
-		namespaceMap[namespace].PodCount++
-
-		// Update status counts
-		status := pod["status"].(string)
-		switch status {
-		case "Running":
-			namespaceMap[namespace].Running++
-		case "Pending":
-			namespaceMap[namespace].Pending++
-		case "Failed":
-			namespaceMap[namespace].Failed++
-		case "Succeeded":
-			namespaceMap[namespace].Succeeded++
-		}
-	}
-
-	// Convert map to slice for response
-	namespaceMetrics := make([]NamespaceMetrics, 0, len(namespaceMap))
-	for _, metrics := range namespaceMap {
-		namespaceMetrics = append(namespaceMetrics, *metrics)
-	}
-
+	setCacheHeaders(c, kubeClient)
 	return c.Status(fiber.StatusOK).JSON(namespaceMetrics)
 }
 
-// GetDeployments returns a list of all deployments or deployments in a specific namespace
+// GetDeploymentsMetrics returns a list of all deployments, statefulsets and daemonsets,
+// optionally filtered by namespace and kind
 func (h *MetricsHandler) GetDeploymentsMetrics(c fiber.Ctx) error {
 	op := "GetDeploymentsMetrics" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
-	if h.kubeClient == nil {
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
 		log.Error("Kubernetes client not available", "error", "kuber client is nil")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"error": "Kubernetes client not available",
@@ -290,8 +345,32 @@ func (h *MetricsHandler) GetDeploymentsMetrics(c fiber.Ctx) error {
 
 	ctx := context.Background()
 	namespace := c.Query("namespace", "") // Optional namespace filter
+	kind := c.Query("kind", "")           // Optional kind filter
+
+	if kind != "" {
+		if _, err := kuberclient.ParseKind(kind); err != nil {
+			log.Error("Invalid kind", "error", err)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	query, err := listquery.Parse(c)
+	if err != nil {
+		log.Error("Invalid list query", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-	deployments, err := h.kubeClient.ListDeployments(ctx, namespace)
+	deployments, page, err := kubeClient.ListWorkloads(ctx, namespace, kind, query)
+	if errors.Is(err, kuberclient.ErrInvalidListQuery) {
+		log.Error("Invalid list query", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		log.Error("Failed to fetch deployments", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -303,8 +382,10 @@ func (h *MetricsHandler) GetDeploymentsMetrics(c fiber.Ctx) error {
 	deploymentInfos := make([]DeploymentMetrics, 0, len(deployments))
 	for _, deployment := range deployments {
 		info := DeploymentMetrics{
+			Kind:      deployment.Kind,
 			Name:      deployment.Name,
 			Namespace: deployment.Namespace,
+			Status:    deployment.Status,
 			Replicas:  deployment.Replicas,
 			Available: deployment.AvailableReplicas,
 			Ready:     deployment.ReadyReplicas,
@@ -312,15 +393,18 @@ func (h *MetricsHandler) GetDeploymentsMetrics(c fiber.Ctx) error {
 		deploymentInfos = append(deploymentInfos, info)
 	}
 
+	setCacheHeaders(c, kubeClient)
+	listquery.SetPageHeaders(c, page)
 	return c.Status(fiber.StatusOK).JSON(deploymentInfos)
 }
 
-// GetDeploymentStatus retrieves detailed status for a specific deployment
+// GetDeploymentStatus retrieves detailed status for a specific deployment, statefulset or daemonset
 func (h *MetricsHandler) GetDeploymentStatus(c fiber.Ctx) error {
 	op := "GetDeploymentStatus" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
-	if h.kubeClient == nil {
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
 		log.Error("Kubernetes client not available", "error", "kuber client is nil")
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"error": "Kubernetes client not available",
@@ -338,7 +422,15 @@ func (h *MetricsHandler) GetDeploymentStatus(c fiber.Ctx) error {
 		})
 	}
 
-	status, err := h.kubeClient.GetDeploymentStatus(ctx, namespace, name)
+	kind, err := kuberclient.ParseKind(c.Query("kind", ""))
+	if err != nil {
+		log.Error("Invalid kind", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	status, err := kubeClient.GetWorkloadStatus(ctx, namespace, name, kind)
 	if err != nil {
 		log.Error("Failed to get service status", "error", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -363,3 +455,17 @@ func convertMapToStringString(m map[string]interface{}) map[string]string {
 	}
 	return result
 }
+
+// setCacheHeaders reports whether a response was served from the informer cache and how stale it may be
+func setCacheHeaders(c fiber.Ctx, kubeClient *kuberclient.Client) {
+	status := kubeClient.CacheStatus()
+
+	source := "api"
+	if status.Synced {
+		source = "cache"
+	}
+	c.Set("X-Kube-Source", source)
+	if status.Synced {
+		c.Set("X-Kube-Cache-Stale-Seconds", strconv.FormatFloat(status.StaleSeconds, 'f', 0, 64))
+	}
+}
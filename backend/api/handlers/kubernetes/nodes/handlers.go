@@ -0,0 +1,219 @@
+package nodes
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/sse"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+type Handler struct {
+	log *slog.Logger
+}
+
+// NewHandler creates a new Kubernetes nodes handler
+func NewHandler(log *slog.Logger) *Handler {
+	return &Handler{
+		log: log,
+	}
+}
+
+type DrainRequest struct {
+	Force              bool   `json:"force,omitempty"`              // Also evict pods without a controller
+	TimeoutSeconds     int    `json:"timeoutSeconds,omitempty"`     // Defaults to 300
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"` // Overrides the pods' grace period
+}
+
+// GetNode returns a node with its pressure conditions, taints, allocated requests and limits and its pods
+func (h *Handler) GetNode(c fiber.Ctx) error {
+	op := "GetNode" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	name := c.Params("name")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	node, err := kubeClient.GetNodeDetail(ctx, name)
+	if err != nil {
+		log.Error("Failed to get node", "error", err, "node", name)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get node",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Node retrieved successfully", "node", name, "pods", len(node.Pods))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Node retrieved successfully",
+		"data":    node,
+	})
+}
+
+// CordonNode marks a node as unschedulable
+func (h *Handler) CordonNode(c fiber.Ctx) error {
+	return h.setSchedulable(c, "CordonNode", true)
+}
+
+// UncordonNode marks a node as schedulable
+func (h *Handler) UncordonNode(c fiber.Ctx) error {
+	return h.setSchedulable(c, "UncordonNode", false)
+}
+
+func (h *Handler) setSchedulable(c fiber.Ctx, opName string, cordon bool) error {
+	op := opName + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	name := c.Params("name")
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Node name is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var err error
+	action := "uncordoned"
+	if cordon {
+		action = "cordoned"
+		err = kubeClient.CordonNode(ctx, name)
+	} else {
+		err = kubeClient.UncordonNode(ctx, name)
+	}
+
+	if err != nil {
+		log.Error("Failed to update node", "error", err, "node", name, "cordon", cordon)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to update node",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Node "+action+" successfully", "node", name)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Node " + action + " successfully",
+		"data": fiber.Map{
+			"name":          name,
+			"unschedulable": cordon,
+		},
+	})
+}
+
+// DrainNode cordons a node and evicts its pods. With ?stream=true the per-pod progress
+// is sent as Server-Sent Events ("progress" events followed by "done" or "error").
+func (h *Handler) DrainNode(c fiber.Ctx) error {
+	op := "DrainNode" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	name := c.Params("name")
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Node name is required",
+		})
+	}
+
+	var req DrainRequest
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&req); err != nil {
+			log.Error("Failed to parse drain request", "error", err)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Invalid request format",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	options := kuberclient.DrainOptions{
+		Force:              req.Force,
+		Timeout:            time.Duration(req.TimeoutSeconds) * time.Second,
+		GracePeriodSeconds: req.GracePeriodSeconds,
+	}
+
+	if fiber.Query[bool](c, "stream") {
+		sse.SetHeaders(c)
+		return c.SendStreamWriter(func(w *bufio.Writer) {
+			// The drain is not cancelled when the client goes away, a half-drained node is worse
+			result, err := kubeClient.DrainNode(context.Background(), name, options, func(status kuberclient.DrainPodStatus) {
+				_ = sse.WriteEvent(w, "progress", status)
+			})
+			if err != nil {
+				log.Error("Failed to drain node", "error", err, "node", name)
+				_ = sse.WriteEvent(w, "error", fiber.Map{"error": err.Error()})
+				return
+			}
+
+			log.Info("Node drain finished", "node", name, "completed", result.Completed)
+			_ = sse.WriteEvent(w, "done", result)
+		})
+	}
+
+	result, err := kubeClient.DrainNode(context.Background(), name, options, nil)
+	if err != nil {
+		log.Error("Failed to drain node", "error", err, "node", name)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to drain node",
+			"error":   err.Error(),
+		})
+	}
+
+	if !result.Completed {
+		log.Warn("Node drain did not complete", "node", name)
+		return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Node cordoned but not all pods were evicted",
+			"data":    result,
+		})
+	}
+
+	log.Info("Node drained successfully", "node", name)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Node drained successfully",
+		"data":    result,
+	})
+}
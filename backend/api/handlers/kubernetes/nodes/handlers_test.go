@@ -0,0 +1,83 @@
+package nodes
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDrainNode(t *testing.T) {
+	controller := true
+	managed := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", UID: "web-uid",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web", UID: "rs-uid", Controller: &controller}},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-1"},
+	}
+	unmanaged := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default", UID: "debug-uid"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+
+	tests := []struct {
+		name       string
+		pods       []runtime.Object
+		body       string
+		blocked    bool // Every eviction is blocked by a PodDisruptionBudget
+		wantStatus int
+	}{
+		{"drained", []runtime.Object{managed}, "", false, fiber.StatusOK},
+		{"unmanaged pod without force", []runtime.Object{managed, unmanaged}, "", false, fiber.StatusInternalServerError},
+		{"timed out while blocked", []runtime.Object{managed}, `{"timeoutSeconds":1}`, true, fiber.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}, tt.pods...)
+			clientset := fake.NewSimpleClientset(objects...)
+			clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				if tt.blocked {
+					return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+				}
+				eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+				return true, nil, clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), action.GetNamespace(), eviction.Name)
+			})
+			client := kuberclient.NewClientFromInterface(clientset, nil)
+
+			app := fiber.New()
+			handler := NewHandler(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			app.Post("/:name/drain", handler.DrainNode, func(c fiber.Ctx) error {
+				middleware.SetCluster(c, "test", client)
+				return c.Next()
+			})
+
+			req := httptest.NewRequest("POST", "/node-1/drain", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, fiber.TestConfig{Timeout: 0})
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				body, _ := io.ReadAll(resp.Body)
+				t.Errorf("got status %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package pods
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/sse"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+const (
+	// Lines returned when neither tailLines nor sinceSeconds is set
+	defaultTailLines int64 = 100
+	// Bytes returned when limitBytes is not set, enough for a chat message attachment
+	defaultLimitBytes int64 = 64 * 1024
+	// Time a follow stream stays open when timeout is not set
+	defaultFollowTimeout = 10 * time.Minute
+)
+
+type Handler struct {
+	log *slog.Logger
+}
+
+// NewHandler creates a new Kubernetes pods handler
+func NewHandler(log *slog.Logger) *Handler {
+	return &Handler{
+		log: log,
+	}
+}
+
+// GetPod returns a pod with its container statuses, restart counts and owning workload
+func (h *Handler) GetPod(c fiber.Ctx) error {
+	op := "GetPod" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pod, err := kubeClient.GetPodDetail(ctx, namespace, name)
+	if err != nil {
+		log.Error("Failed to get pod", "error", err, "pod", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get pod",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Pod retrieved successfully", "pod", name, "namespace", namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Pod retrieved successfully",
+		"data":    pod,
+	})
+}
+
+// GetLogs returns the logs of a pod container, or streams them as Server-Sent Events with ?follow=true
+func (h *Handler) GetLogs(c fiber.Ctx) error {
+	op := "GetLogs" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	namespace := c.Params("namespace")
+	name := c.Params("name")
+
+	if name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Pod name is required",
+		})
+	}
+
+	options, err := parseLogOptions(c)
+	if err != nil {
+		log.Error("Invalid log options", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid log options",
+			"error":   err.Error(),
+		})
+	}
+
+	if options.Follow {
+		return h.followLogs(c, log, namespace, name, options)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	logs, container, err := kubeClient.GetPodLogs(ctx, namespace, name, options)
+	if err != nil {
+		log.Error("Failed to get pod logs", "error", err, "pod", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to get pod logs",
+			"error":   err.Error(),
+		})
+	}
+
+	log.Info("Pod logs retrieved successfully", "pod", name, "namespace", namespace, "container", container)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Pod logs retrieved successfully",
+		"data": fiber.Map{
+			"name":      name,
+			"namespace": namespace,
+			"container": container,
+			"previous":  options.Previous,
+			"logs":      logs,
+			"truncated": int64(len(logs)) >= *options.LimitBytes,
+		},
+	})
+}
+
+// followLogs streams log lines as "log" events until the container stops, the client leaves or the timeout expires
+func (h *Handler) followLogs(c fiber.Ctx, log *slog.Logger, namespace, name string, options kuberclient.LogOptions) error {
+	timeout := defaultFollowTimeout
+	if seconds := fiber.Query[int](c, "timeout"); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	// The byte limit is meant for one-off reads, a follow stream is bounded by its timeout
+	options.LimitBytes = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	// Open the stream before switching to SSE, so errors get a regular JSON response
+	stream, container, err := middleware.KubeClient(c).StreamPodLogs(ctx, namespace, name, options)
+	if err != nil {
+		cancel()
+		log.Error("Failed to stream pod logs", "error", err, "pod", name, "namespace", namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to stream pod logs",
+			"error":   err.Error(),
+		})
+	}
+
+	sse.SetHeaders(c)
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		defer stream.Close()
+
+		log.Info("Following pod logs", "pod", name, "namespace", namespace, "container", container)
+
+		scanner := bufio.NewScanner(stream)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if err := sse.WriteData(w, "log", scanner.Text()); err != nil {
+				// Client went away
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			_ = sse.WriteEvent(w, "error", fiber.Map{"error": err.Error()})
+			return
+		}
+		_ = sse.WriteEvent(w, "done", fiber.Map{"container": container})
+	})
+}
+
+// parseLogOptions reads log options from the query string
+func parseLogOptions(c fiber.Ctx) (kuberclient.LogOptions, error) {
+	options := kuberclient.LogOptions{
+		Container: c.Query("container", ""),
+		Previous:  fiber.Query[bool](c, "previous"),
+		Follow:    fiber.Query[bool](c, "follow"),
+	}
+
+	var err error
+	if options.TailLines, err = optionalInt64(c, "tailLines"); err != nil {
+		return options, err
+	}
+	if options.SinceSeconds, err = optionalInt64(c, "sinceSeconds"); err != nil {
+		return options, err
+	}
+	if options.LimitBytes, err = optionalInt64(c, "limitBytes"); err != nil {
+		return options, err
+	}
+
+	if options.TailLines == nil && options.SinceSeconds == nil {
+		tailLines := defaultTailLines
+		options.TailLines = &tailLines
+	}
+	if options.LimitBytes == nil {
+		limitBytes := defaultLimitBytes
+		options.LimitBytes = &limitBytes
+	}
+
+	return options, nil
+}
+
+// optionalInt64 parses a positive integer query parameter, returning nil if it is absent
+func optionalInt64(c fiber.Ctx, key string) (*int64, error) {
+	raw := c.Query(key, "")
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value <= 0 {
+		return nil, fmt.Errorf("%s must be a positive integer", key)
+	}
+
+	return &value, nil
+}
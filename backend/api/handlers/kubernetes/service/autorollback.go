@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/autorollback"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+type WatchRequest struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// prepareWatch decides whether an update is watched for firing alerts and records the revision a rollback
+// would return to. The returned function starts the watch once the update went through, it is nil when the
+// update is not watched.
+func (h *Handler) prepareWatch(c fiber.Ctx, req UpdateRequest, kind string) (func() *autorollback.Watch, error) {
+	if kind != kuberclient.KindDeployment || req.DryRun {
+		return nil, nil
+	}
+
+	window := h.watcher.Window(req.Namespace, req.AutoRollback, req.WatchSeconds)
+	if window <= 0 {
+		return nil, nil
+	}
+
+	kubeClient := middleware.KubeClient(c)
+	cluster := middleware.ClusterName(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	history, err := kubeClient.GetDeploymentHistory(ctx, req.Namespace, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	var previousRevision int64
+	for _, revision := range history {
+		if revision.Current {
+			previousRevision = revision.Revision
+			break
+		}
+	}
+	if previousRevision == 0 {
+		return nil, fmt.Errorf("deployment %s has no revision to roll back to", req.Name)
+	}
+
+	return func() *autorollback.Watch {
+		watch := h.watcher.Start(kubeClient, cluster, req.Namespace, req.Name, previousRevision, window)
+		return &watch
+	}, nil
+}
+
+// ListWatches lists the updates being watched for alerts and the outcome of recent watches
+func (h *Handler) ListWatches(c fiber.Ctx) error {
+	op := "ListWatches" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	watches := h.watcher.List(middleware.ClusterName(c))
+
+	log.Info("Auto rollback watches listed", "count", len(watches))
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Auto rollback watches retrieved successfully",
+		"data":    watches,
+	})
+}
+
+// CancelWatch stops watching an update without rolling it back
+func (h *Handler) CancelWatch(c fiber.Ctx) error {
+	op := "CancelWatch" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	var req WatchRequest
+	if err := c.Bind().Body(&req); err != nil {
+		log.Error("Failed to parse cancel watch request", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+	}
+
+	if req.Name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service name is required",
+		})
+	}
+
+	watch, ok := h.watcher.Cancel(middleware.ClusterName(c), req.Namespace, req.Name)
+	if !ok {
+		log.Error("No watch running", "service", req.Name, "namespace", req.Namespace)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service is not being watched",
+		})
+	}
+
+	log.Info("Auto rollback watch cancelled", "service", req.Name, "namespace", req.Namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Auto rollback watch cancelled successfully",
+		"data":    watch,
+	})
+}
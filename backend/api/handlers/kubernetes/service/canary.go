@@ -0,0 +1,116 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/handlers/sse"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/autorollback"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/canary"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// canaryUpdate updates a deployment through a canary analysed against Prometheus. With ?stream=true
+// every step is sent as Server-Sent Events ("step" events followed by "done" or "error"), otherwise
+// the run is started in the background and answered with 202 and the run to poll.
+// startWatch, if set, starts watching the deployment for alerts once the canary was promoted.
+func (h *Handler) canaryUpdate(c fiber.Ctx, log *slog.Logger, kubeClient *kuberclient.Client, config kuberclient.ServiceConfig, spec canary.Spec, startWatch func() *autorollback.Watch) error {
+	if config.Kind != kuberclient.KindDeployment {
+		log.Error("Invalid kind", "error", "canary update of "+config.Kind)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Only deployments can be updated through a canary",
+		})
+	}
+	if config.DryRun {
+		log.Error("Invalid canary update", "error", "dry run of a canary update")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "A canary update cannot be a dry run, preview the update without the canary",
+		})
+	}
+	if err := spec.Validate(); err != nil {
+		log.Error("Invalid canary spec", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid canary spec",
+			"error":   err.Error(),
+		})
+	}
+
+	runner := canary.NewRunner(kubeClient, h.promClient)
+	data := func(report *canary.Report) fiber.Map {
+		data := fiber.Map{
+			"kind":      config.Kind,
+			"name":      config.Name,
+			"namespace": config.Namespace,
+			"image":     config.Image,
+			"version":   config.Version,
+			"canary":    report,
+		}
+		if report != nil && report.Promoted && startWatch != nil {
+			data["autoRollback"] = startWatch()
+		}
+		return data
+	}
+
+	if fiber.Query[bool](c, "stream") {
+		sse.SetHeaders(c)
+		return c.SendStreamWriter(func(w *bufio.Writer) {
+			// The run is not cancelled when the client goes away, it always ends promoted or torn down
+			ctx, cancel := context.WithTimeout(context.Background(), spec.Timeout())
+			defer cancel()
+
+			report, err := runner.Run(ctx, config, spec, func(step canary.Step) {
+				_ = sse.WriteEvent(w, "step", step)
+			})
+			if err != nil {
+				log.Error("Canary update failed", "error", err, "service", config.Name, "namespace", config.Namespace)
+				_ = sse.WriteEvent(w, "error", fiber.Map{"error": err.Error(), "data": data(report)})
+				return
+			}
+
+			log.Info("Canary update finished", "service", config.Name, "namespace", config.Namespace, "promoted", report.Promoted)
+			_ = sse.WriteEvent(w, "done", data(report))
+		})
+	}
+
+	// A run takes longer than clients and proxies keep a request open, so it continues in the background
+	run := h.canaries.Start(runner, middleware.ClusterName(c), config, spec, startWatch)
+
+	log.Info("Canary update started", "service", config.Name, "namespace", config.Namespace, "run", run.ID)
+	c.Set(fiber.HeaderLocation, "/api/v1/kubernetes/service/canaries/"+run.ID)
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Canary update started, poll the run for its outcome",
+		"data":    run,
+	})
+}
+
+// GetCanaryRun returns a canary update started without ?stream=true, with its steps so far or its outcome
+func (h *Handler) GetCanaryRun(c fiber.Ctx) error {
+	op := "GetCanaryRun" + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	id := c.Params("id")
+
+	run, ok := h.canaries.Get(middleware.ClusterName(c), id)
+	if !ok {
+		log.Error("Unknown canary run", "run", id)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Canary run not found",
+		})
+	}
+
+	log.Info("Canary run retrieved", "run", id, "state", run.State)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Canary run retrieved successfully",
+		"data":    run,
+	})
+}
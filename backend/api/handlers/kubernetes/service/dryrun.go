@@ -0,0 +1,18 @@
+package service
+
+import (
+	"github.com/gofiber/fiber/v3"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// dryRunResponse reports the changes a mutation would make without persisting them
+func dryRunResponse(c fiber.Ctx, data fiber.Map, result *kuberclient.MutationResult) error {
+	data["dryRun"] = true
+	data["changes"] = result.Changes
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": "Dry run completed, no changes were persisted",
+		"data":    data,
+	})
+}
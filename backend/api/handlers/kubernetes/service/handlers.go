@@ -2,72 +2,97 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/autorollback"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/canary"
 	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/prometheus_client/query"
 )
 
 type Handler struct {
 	log        *slog.Logger
-	kubeClient *kuberclient.Client
+	promClient *query.PrometheusClient // Evaluates the criteria of canary updates
+	watcher    *autorollback.Watcher   // Rolls updates back when alerts fire
+	canaries   *canary.Tracker         // Canary updates running in the background
 }
 
-// NewHandler creates a new Kubernetes service handler
-// It accepts an existing kubeClient to avoid creating multiple instances
-func NewHandler(log *slog.Logger, kubeClient *kuberclient.Client) (*Handler, error) {
-	// If no client is provided, attempt to create one
-	var err error
-	if kubeClient == nil {
-		kubeClient, err = kuberclient.NewClient()
-		if err != nil {
-			return nil, err
-		}
-	}
-
+// NewHandler creates a new Kubernetes service handler. Updates in the namespaces of policy
+// are watched for alerts and rolled back automatically.
+func NewHandler(log *slog.Logger, promURL string, policy autorollback.Policy) *Handler {
+	promClient := query.NewPrometheusClient(promURL)
 	return &Handler{
 		log:        log,
-		kubeClient: kubeClient,
-	}, nil
+		promClient: promClient,
+		watcher:    autorollback.NewWatcher(log, promClient, policy),
+		canaries:   canary.NewTracker(log),
+	}
 }
 
 type ScaleRequest struct {
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
+	Kind      string `json:"kind,omitempty"`
 	Replicas  int32  `json:"replicas"`
+	DryRun    bool   `json:"dryRun,omitempty"`
+	AdjustHPA bool   `json:"adjustHpa,omitempty"` // Pin the bounds of an HPA managing the service instead of refusing
 }
 
 type RestartRequest struct {
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
+	Kind      string `json:"kind,omitempty"`
+	DryRun    bool   `json:"dryRun,omitempty"`
 }
 
 type RollbackRequest struct {
 	Namespace     string `json:"namespace"`
 	Name          string `json:"name"`
+	Kind          string `json:"kind,omitempty"`          // Deployment (default), StatefulSet or DaemonSet
 	RevisionID    string `json:"revisionId,omitempty"`    // Specific revision ID
 	RevisionImage string `json:"revisionImage,omitempty"` // Specific image
 	Version       string `json:"version,omitempty"`       // Specific version
+	Container     string `json:"container,omitempty"`     // Only match images of this container
+	DryRun        bool   `json:"dryRun,omitempty"`        // Preview the change without applying it
 }
 
 type UpdateRequest struct {
-	Namespace string `json:"namespace"`
-	Name      string `json:"name"`
-	Image     string `json:"image,omitempty"`
-	Version   string `json:"version,omitempty"`
+	Namespace    string       `json:"namespace"`
+	Name         string       `json:"name"`
+	Kind         string       `json:"kind,omitempty"`
+	Image        string       `json:"image,omitempty"`
+	Version      string       `json:"version,omitempty"`
+	Container    string       `json:"container,omitempty"`
+	DryRun       bool         `json:"dryRun,omitempty"`
+	Canary       *canary.Spec `json:"canary,omitempty"`       // Try the new version in a canary before updating the service
+	AutoRollback *bool        `json:"autoRollback,omitempty"` // Roll back when alerts fire after the update, defaults to the namespace policy
+	WatchSeconds int          `json:"watchSeconds,omitempty"` // How long to watch for alerts, defaults to the namespace policy
 }
 
 type StatusRequest struct {
 	Namespace string `json:"namespace"`
 	Name      string `json:"name"`
+	Kind      string `json:"kind,omitempty"`
 }
 
 func (h *Handler) ScaleService(c fiber.Ctx) error {
 	op := "ScaleService" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
 	var req ScaleRequest
 	if err := c.Bind().Body(&req); err != nil {
 		log.Error("Failed to parse scale request", "error", err)
@@ -94,15 +119,37 @@ func (h *Handler) ScaleService(c fiber.Ctx) error {
 		})
 	}
 
+	kind, err := parseKind(c, req.Kind)
+	if err != nil {
+		log.Error("Invalid kind", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid workload kind",
+			"error":   err.Error(),
+		})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	err := h.kubeClient.ScaleDeployment(ctx, kuberclient.ServiceConfig{
+	result, err := kubeClient.ScaleWorkload(ctx, kuberclient.ServiceConfig{
 		Namespace: req.Namespace,
 		Name:      req.Name,
+		Kind:      kind,
 		Replicas:  req.Replicas,
+		DryRun:    req.DryRun,
+		AdjustHPA: req.AdjustHPA,
 	})
 
+	if errors.Is(err, kuberclient.ErrManagedByHPA) {
+		log.Warn("Refused to scale HPA-managed service", "error", err, "service", req.Name, "namespace", req.Namespace)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service is managed by a HorizontalPodAutoscaler",
+			"error":   err.Error(),
+		})
+	}
+
 	if err != nil {
 		log.Error("Failed to scale service", "error", err, "service", req.Name, "namespace", req.Namespace)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -112,15 +159,26 @@ func (h *Handler) ScaleService(c fiber.Ctx) error {
 		})
 	}
 
+	data := fiber.Map{
+		"kind":      kind,
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"replicas":  req.Replicas,
+	}
+	if result.HPA != nil {
+		data["hpa"] = result.HPA
+	}
+
+	if req.DryRun {
+		log.Info("Service scale dry run completed", "service", req.Name, "namespace", req.Namespace, "replicas", req.Replicas)
+		return dryRunResponse(c, data, result)
+	}
+
 	log.Info("Service scaled successfully", "service", req.Name, "namespace", req.Namespace, "replicas", req.Replicas)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "Service scaled successfully",
-		"data": fiber.Map{
-			"name":      req.Name,
-			"namespace": req.Namespace,
-			"replicas":  req.Replicas,
-		},
+		"data":    data,
 	})
 }
 
@@ -128,6 +186,15 @@ func (h *Handler) RestartService(c fiber.Ctx) error {
 	op := "RestartService" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
 	var req RestartRequest
 	if err := c.Bind().Body(&req); err != nil {
 		log.Error("Failed to parse restart request", "error", err)
@@ -146,12 +213,24 @@ func (h *Handler) RestartService(c fiber.Ctx) error {
 		})
 	}
 
+	kind, err := parseKind(c, req.Kind)
+	if err != nil {
+		log.Error("Invalid kind", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid workload kind",
+			"error":   err.Error(),
+		})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	err := h.kubeClient.RestartDeployment(ctx, kuberclient.ServiceConfig{
+	result, err := kubeClient.RestartWorkload(ctx, kuberclient.ServiceConfig{
 		Namespace: req.Namespace,
 		Name:      req.Name,
+		Kind:      kind,
+		DryRun:    req.DryRun,
 	})
 
 	if err != nil {
@@ -163,14 +242,39 @@ func (h *Handler) RestartService(c fiber.Ctx) error {
 		})
 	}
 
+	data := fiber.Map{
+		"kind":      kind,
+		"name":      req.Name,
+		"namespace": req.Namespace,
+	}
+
+	if req.DryRun {
+		log.Info("Service restart dry run completed", "service", req.Name, "namespace", req.Namespace)
+		return dryRunResponse(c, data, result)
+	}
+
+	rollout, err := h.waitForRollout(c, req.Namespace, req.Name)
+	if err != nil {
+		log.Error("Rollout did not complete", "error", err, "service", req.Name, "namespace", req.Namespace)
+		return c.Status(rolloutFailureStatus(rollout)).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service restarted but rollout did not complete",
+			"error":   err.Error(),
+			"data": fiber.Map{
+				"rollout": rollout,
+			},
+		})
+	}
+
+	if rollout != nil {
+		data["rollout"] = rollout
+	}
+
 	log.Info("Service restarted successfully", "service", req.Name, "namespace", req.Namespace)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "Service restarted successfully",
-		"data": fiber.Map{
-			"name":      req.Name,
-			"namespace": req.Namespace,
-		},
+		"data":    data,
 	})
 }
 
@@ -178,6 +282,15 @@ func (h *Handler) RollbackService(c fiber.Ctx) error {
 	op := "RollbackService" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
 	var req RollbackRequest
 	if err := c.Bind().Body(&req); err != nil {
 		log.Error("Failed to parse rollback request", "error", err)
@@ -196,15 +309,28 @@ func (h *Handler) RollbackService(c fiber.Ctx) error {
 		})
 	}
 
+	kind, err := parseKind(c, req.Kind)
+	if err != nil {
+		log.Error("Invalid kind", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid workload kind",
+			"error":   err.Error(),
+		})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	err := h.kubeClient.RollbackDeployment(ctx, kuberclient.ServiceConfig{
+	result, err := kubeClient.RollbackWorkload(ctx, kuberclient.ServiceConfig{
 		Namespace:     req.Namespace,
 		Name:          req.Name,
+		Kind:          kind,
 		RevisionID:    req.RevisionID,
 		RevisionImage: req.RevisionImage,
 		Version:       req.Version,
+		Container:     req.Container,
+		DryRun:        req.DryRun,
 	})
 
 	if err != nil {
@@ -216,17 +342,57 @@ func (h *Handler) RollbackService(c fiber.Ctx) error {
 		})
 	}
 
-	log.Info("Service rolled back successfully", "service", req.Name, "namespace", req.Namespace)
+	data := fiber.Map{
+		"kind":               kind,
+		"name":               req.Name,
+		"namespace":          req.Namespace,
+		"revisionId":         req.RevisionID,
+		"revisionImage":      req.RevisionImage,
+		"version":            req.Version,
+		"revision":           result.Revision,
+		"replicaSet":         result.ReplicaSet,
+		"controllerRevision": result.ControllerRevision,
+		"changeCause":        result.ChangeCause,
+		"skipped":            result.Skipped,
+		"warnings":           result.Warnings,
+	}
+
+	for _, warning := range result.Warnings {
+		log.Warn("Rollback warning", "warning", warning, "service", req.Name, "namespace", req.Namespace)
+	}
+
+	if req.DryRun {
+		log.Info("Service rollback dry run completed", "service", req.Name, "namespace", req.Namespace, "revision", result.Revision)
+		return dryRunResponse(c, data, &result.MutationResult)
+	}
+
+	message := "Service rolled back successfully"
+	if result.Skipped {
+		message = "Service already runs the requested revision, rollback skipped"
+	}
+
+	rollout, err := h.waitForRollout(c, req.Namespace, req.Name)
+	if err != nil {
+		log.Error("Rollout did not complete", "error", err, "service", req.Name, "namespace", req.Namespace)
+		return c.Status(rolloutFailureStatus(rollout)).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service rolled back but rollout did not complete",
+			"error":   err.Error(),
+			"data": fiber.Map{
+				"rollout": rollout,
+			},
+		})
+	}
+
+	if rollout != nil {
+		data["rollout"] = rollout
+	}
+
+	log.Info(message, "service", req.Name, "namespace", req.Namespace, "revision", result.Revision, "changeCause", result.ChangeCause)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
-		"message": "Service rolled back successfully",
-		"data": fiber.Map{
-			"name":          req.Name,
-			"namespace":     req.Namespace,
-			"revisionId":    req.RevisionID,
-			"revisionImage": req.RevisionImage,
-			"version":       req.Version,
-		},
+		"message": message,
+		"data":    data,
 	})
 }
 
@@ -234,6 +400,15 @@ func (h *Handler) UpdateService(c fiber.Ctx) error {
 	op := "UpdateService" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
 	var req UpdateRequest
 	if err := c.Bind().Body(&req); err != nil {
 		log.Error("Failed to parse update request", "error", err)
@@ -260,15 +435,52 @@ func (h *Handler) UpdateService(c fiber.Ctx) error {
 		})
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	kind, err := parseKind(c, req.Kind)
+	if err != nil {
+		log.Error("Invalid kind", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid workload kind",
+			"error":   err.Error(),
+		})
+	}
+
+	if req.AutoRollback != nil && *req.AutoRollback && kind != kuberclient.KindDeployment {
+		log.Error("Invalid auto rollback", "error", "auto rollback of "+kind)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Only deployment updates can be rolled back automatically",
+		})
+	}
+
+	startWatch, err := h.prepareWatch(c, req, kind)
+	if err != nil {
+		log.Error("Failed to prepare auto rollback", "error", err, "service", req.Name, "namespace", req.Namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to prepare auto rollback",
+			"error":   err.Error(),
+		})
+	}
 
-	err := h.kubeClient.UpdateDeployment(ctx, kuberclient.ServiceConfig{
+	config := kuberclient.ServiceConfig{
 		Namespace: req.Namespace,
 		Name:      req.Name,
+		Kind:      kind,
 		Image:     req.Image,
 		Version:   req.Version,
-	})
+		Container: req.Container,
+		DryRun:    req.DryRun,
+	}
+
+	if req.Canary != nil {
+		return h.canaryUpdate(c, log, kubeClient, config, *req.Canary, startWatch)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := kubeClient.UpdateWorkload(ctx, config)
 
 	if err != nil {
 		log.Error("Failed to update service", "error", err, "service", req.Name, "namespace", req.Namespace)
@@ -279,16 +491,50 @@ func (h *Handler) UpdateService(c fiber.Ctx) error {
 		})
 	}
 
+	data := fiber.Map{
+		"kind":       kind,
+		"name":       req.Name,
+		"namespace":  req.Namespace,
+		"image":      req.Image,
+		"version":    req.Version,
+		"containers": result.Containers,
+	}
+
+	if req.DryRun {
+		log.Info("Service update dry run completed", "service", req.Name, "namespace", req.Namespace)
+		return dryRunResponse(c, data, result)
+	}
+
+	// The watch starts with the update, alerts caused by a failing rollout count as well
+	var watch *autorollback.Watch
+	if startWatch != nil {
+		watch = startWatch()
+		data["autoRollback"] = watch
+	}
+
+	rollout, err := h.waitForRollout(c, req.Namespace, req.Name)
+	if err != nil {
+		log.Error("Rollout did not complete", "error", err, "service", req.Name, "namespace", req.Namespace)
+		return c.Status(rolloutFailureStatus(rollout)).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service updated but rollout did not complete",
+			"error":   err.Error(),
+			"data": fiber.Map{
+				"rollout":      rollout,
+				"autoRollback": watch,
+			},
+		})
+	}
+
+	if rollout != nil {
+		data["rollout"] = rollout
+	}
+
 	log.Info("Service updated successfully", "service", req.Name, "namespace", req.Namespace)
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"status":  "success",
 		"message": "Service updated successfully",
-		"data": fiber.Map{
-			"name":      req.Name,
-			"namespace": req.Namespace,
-			"image":     req.Image,
-			"version":   req.Version,
-		},
+		"data":    data,
 	})
 }
 
@@ -296,6 +542,15 @@ func (h *Handler) GetServiceStatus(c fiber.Ctx) error {
 	op := "GetServiceStatus" + uuid.NewString()
 	log := h.log.With(slog.String("op", op))
 
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
 	var req StatusRequest
 	if err := c.Bind().Body(&req); err != nil {
 		log.Error("Failed to parse status request", "error", err)
@@ -314,10 +569,20 @@ func (h *Handler) GetServiceStatus(c fiber.Ctx) error {
 		})
 	}
 
+	kind, err := parseKind(c, req.Kind)
+	if err != nil {
+		log.Error("Invalid kind", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid workload kind",
+			"error":   err.Error(),
+		})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	status, err := h.kubeClient.GetDeploymentStatus(ctx, req.Namespace, req.Name)
+	status, err := kubeClient.GetWorkloadStatus(ctx, req.Namespace, req.Name, kind)
 	if err != nil {
 		log.Error("Failed to get service status", "error", err, "service", req.Name, "namespace", req.Namespace)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v3"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// parseKind validates the workload kind of a request.
+// Waiting for a rollout is only supported for deployments.
+func parseKind(c fiber.Ctx, kind string) (string, error) {
+	parsed, err := kuberclient.ParseKind(kind)
+	if err != nil {
+		return "", err
+	}
+
+	if parsed != kuberclient.KindDeployment && fiber.Query[bool](c, "wait") {
+		return "", fmt.Errorf("waiting for rollout is only supported for deployments")
+	}
+
+	return parsed, nil
+}
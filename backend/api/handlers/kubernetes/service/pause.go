@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+type PauseRequest struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind,omitempty"` // Only Deployment rollouts can be paused
+	DryRun    bool   `json:"dryRun,omitempty"`
+}
+
+// PauseService freezes a deployment rollout where it is, e.g. to investigate a bad update
+// before deciding between rollback and resume
+func (h *Handler) PauseService(c fiber.Ctx) error {
+	return h.setPaused(c, "PauseService", true)
+}
+
+// ResumeService continues a paused deployment rollout. With ?wait=true it waits for the rollout to finish.
+func (h *Handler) ResumeService(c fiber.Ctx) error {
+	return h.setPaused(c, "ResumeService", false)
+}
+
+// setPaused implements PauseService and ResumeService
+func (h *Handler) setPaused(c fiber.Ctx, name string, paused bool) error {
+	op := name + uuid.NewString()
+	log := h.log.With(slog.String("op", op))
+
+	action, done := "resume", "resumed"
+	if paused {
+		action, done = "pause", "paused"
+	}
+
+	kubeClient := middleware.KubeClient(c)
+	if kubeClient == nil {
+		log.Error("Kubernetes client not available", "error", "kuber client is nil")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	var req PauseRequest
+	if err := c.Bind().Body(&req); err != nil {
+		log.Error("Failed to parse "+action+" request", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid request format",
+			"error":   err.Error(),
+		})
+	}
+
+	if req.Name == "" {
+		log.Error("Invalid name", "error", "name is empty string")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Service name is required",
+		})
+	}
+
+	kind, err := parseKind(c, req.Kind)
+	if err != nil {
+		log.Error("Invalid kind", "error", err)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Invalid workload kind",
+			"error":   err.Error(),
+		})
+	}
+	if kind != kuberclient.KindDeployment {
+		log.Error("Invalid kind", "error", "rollout of "+kind+" cannot be paused")
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Only deployment rollouts can be paused and resumed",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	config := kuberclient.ServiceConfig{
+		Namespace: req.Namespace,
+		Name:      req.Name,
+		Kind:      kind,
+		DryRun:    req.DryRun,
+	}
+
+	var result *kuberclient.PauseResult
+	if paused {
+		result, err = kubeClient.PauseDeployment(ctx, config)
+	} else {
+		result, err = kubeClient.ResumeDeployment(ctx, config)
+	}
+
+	if err != nil {
+		log.Error("Failed to "+action+" service", "error", err, "service", req.Name, "namespace", req.Namespace)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Failed to " + action + " service",
+			"error":   err.Error(),
+		})
+	}
+
+	data := fiber.Map{
+		"kind":      kind,
+		"name":      req.Name,
+		"namespace": req.Namespace,
+		"paused":    paused,
+		"skipped":   result.Skipped,
+	}
+
+	if req.DryRun {
+		log.Info("Service "+action+" dry run completed", "service", req.Name, "namespace", req.Namespace)
+		return dryRunResponse(c, data, &result.MutationResult)
+	}
+
+	message := "Service rollout " + done + " successfully"
+	if result.Skipped {
+		message = "Service rollout already was " + done
+	}
+
+	// Resuming can wait for the rollout to finish, a paused rollout is reported as it was frozen
+	var rollout *kuberclient.RolloutStatus
+	if !paused {
+		rollout, err = h.waitForRollout(c, req.Namespace, req.Name)
+		if err != nil {
+			log.Error("Rollout did not complete", "error", err, "service", req.Name, "namespace", req.Namespace)
+			return c.Status(rolloutFailureStatus(rollout)).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Service resumed but rollout did not complete",
+				"error":   err.Error(),
+				"data": fiber.Map{
+					"rollout": rollout,
+				},
+			})
+		}
+	}
+	if rollout == nil {
+		rollout, err = kubeClient.GetRolloutStatus(ctx, req.Namespace, req.Name)
+		if err != nil {
+			log.Warn("Failed to get rollout status", "error", err, "service", req.Name, "namespace", req.Namespace)
+		}
+	}
+
+	if rollout != nil {
+		data["rollout"] = rollout
+	}
+
+	log.Info(message, "service", req.Name, "namespace", req.Namespace)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":  "success",
+		"message": message,
+		"data":    data,
+	})
+}
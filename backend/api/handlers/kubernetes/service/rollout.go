@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/api/middleware"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// Default time to wait for a rollout when ?wait=true is set without ?timeout
+const defaultRolloutTimeout = 5 * time.Minute
+
+// waitForRollout blocks until the deployment rollout finishes if the request has ?wait=true.
+// It returns a nil status when waiting was not requested.
+func (h *Handler) waitForRollout(c fiber.Ctx, namespace, name string) (*kuberclient.RolloutStatus, error) {
+	if !fiber.Query[bool](c, "wait") {
+		return nil, nil
+	}
+
+	timeout := defaultRolloutTimeout
+	if seconds := fiber.Query[int](c, "timeout"); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return middleware.KubeClient(c).WaitForRollout(ctx, namespace, name, nil)
+}
+
+// rolloutFailureStatus maps an unfinished rollout to an HTTP status code: a paused rollout is a conflict,
+// a rollout that was still progressing when the wait ended is reported as a timeout
+func rolloutFailureStatus(status *kuberclient.RolloutStatus) int {
+	if status != nil && status.Paused && !status.Done {
+		return fiber.StatusConflict
+	}
+	if status != nil && !status.Done && !status.Failed {
+		return fiber.StatusGatewayTimeout
+	}
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,38 @@
+package listquery
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// Parse reads the filter, sort and paging parameters shared by the list endpoints
+func Parse(c fiber.Ctx) (kuberclient.ListQuery, error) {
+	query := kuberclient.ListQuery{
+		LabelSelector: c.Query("labelSelector"),
+		FieldSelector: c.Query("fieldSelector"),
+		Status:        c.Query("status"),
+		Sort:          c.Query("sort"),
+		Continue:      c.Query("continue"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 0 {
+			return query, fmt.Errorf("limit must be a non-negative number, got %q", limit)
+		}
+		query.Limit = parsed
+	}
+
+	return query, nil
+}
+
+// SetPageHeaders reports the number of matching items and the token of the next page
+func SetPageHeaders(c fiber.Ctx, page *kuberclient.ListPage) {
+	c.Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.Continue != "" {
+		c.Set("X-Continue", page.Continue)
+	}
+}
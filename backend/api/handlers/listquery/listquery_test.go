@@ -0,0 +1,80 @@
+package listquery
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		want    kuberclient.ListQuery
+		wantErr bool
+	}{
+		{
+			name:   "no parameters",
+			target: "/",
+			want:   kuberclient.ListQuery{},
+		},
+		{
+			name:   "all parameters",
+			target: "/?labelSelector=app%3Dbackend%2Ctier%21%3Dcache&fieldSelector=spec.nodeName%3Dnode-1&status=Running&sort=-age&limit=5&continue=abc",
+			want: kuberclient.ListQuery{
+				LabelSelector: "app=backend,tier!=cache",
+				FieldSelector: "spec.nodeName=node-1",
+				Status:        "Running",
+				Sort:          "-age",
+				Limit:         5,
+				Continue:      "abc",
+			},
+		},
+		{
+			name:   "zero limit returns everything",
+			target: "/?limit=0",
+			want:   kuberclient.ListQuery{},
+		},
+		{
+			name:    "negative limit",
+			target:  "/?limit=-1",
+			wantErr: true,
+		},
+		{
+			name:    "limit is not a number",
+			target:  "/?limit=ten",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got kuberclient.ListQuery
+			var err error
+
+			app := fiber.New()
+			app.Get("/", func(c fiber.Ctx) error {
+				got, err = Parse(c)
+				return nil
+			})
+			if _, testErr := app.Test(httptest.NewRequest("GET", tt.target, nil)); testErr != nil {
+				t.Fatalf("request failed: %v", testErr)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q) = %+v, want an error", tt.target, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.target, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package sse
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// SetHeaders prepares the response for a Server-Sent Events stream
+func SetHeaders(c fiber.Ctx) {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+}
+
+// WriteEvent writes a single event with a JSON payload and flushes it.
+// A flush error means the client went away.
+func WriteEvent(w *bufio.Writer, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return WriteData(w, event, string(data))
+}
+
+// WriteData writes a single event with a plain text payload and flushes it
+func WriteData(w *bufio.Writer, event, data string) error {
+	if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+		return err
+	}
+	// Every line of a multi-line payload needs its own data field
+	for _, line := range strings.Split(data, "\n") {
+		if _, err := fmt.Fprintf(w, "data: %s\n", line); err != nil {
+			return err
+		}
+	}
+	if _, err := w.WriteString("\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// Keys of the request locals set by ClusterMiddleware
+const (
+	kubeClientKey  = "kubeClient"
+	clusterNameKey = "cluster"
+)
+
+type ClusterMiddleware struct {
+	registry *kuberclient.Registry
+}
+
+func NewClusterMiddleware(registry *kuberclient.Registry) *ClusterMiddleware {
+	return &ClusterMiddleware{
+		registry: registry,
+	}
+}
+
+// Resolve selects the Kubernetes cluster of a request from the "cluster" query parameter
+// or the X-Cluster header, falling back to the default cluster
+func (cm *ClusterMiddleware) Resolve(c fiber.Ctx) error {
+	if cm.registry == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+		})
+	}
+
+	name := c.Query("cluster", c.Get("X-Cluster"))
+
+	client, err := cm.registry.Get(name)
+	if errors.Is(err, kuberclient.ErrUnknownCluster) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Unknown cluster",
+			"error":   err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Kubernetes client not available",
+			"error":   err.Error(),
+		})
+	}
+
+	if name == "" {
+		name = cm.registry.Default()
+	}
+	SetCluster(c, name, client)
+
+	return c.Next()
+}
+
+// SetCluster selects the cluster of a request, it is read back with KubeClient and ClusterName
+func SetCluster(c fiber.Ctx, name string, client *kuberclient.Client) {
+	c.Locals(kubeClientKey, client)
+	c.Locals(clusterNameKey, name)
+}
+
+// KubeClient returns the client of the cluster selected by Resolve, or nil
+func KubeClient(c fiber.Ctx) *kuberclient.Client {
+	client, _ := c.Locals(kubeClientKey).(*kuberclient.Client)
+	return client
+}
+
+// ClusterName returns the name of the cluster selected by Resolve
+func ClusterName(c fiber.Ctx) string {
+	name, _ := c.Locals(clusterNameKey).(string)
+	return name
+}
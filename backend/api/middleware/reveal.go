@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v3"
+)
+
+// Key of the request local set by RevealMiddleware
+const revealKey = "reveal"
+
+// RevealMiddleware authorizes revealing secret values. Revealing needs a key of its own,
+// the API key of a request is not enough.
+type RevealMiddleware struct {
+	revealKeys map[string]bool
+}
+
+func NewRevealMiddleware(revealKeys map[string]bool) *RevealMiddleware {
+	return &RevealMiddleware{
+		revealKeys: revealKeys,
+	}
+}
+
+// Authorize lets requests with ?reveal=true through only if the X-Reveal-Key header holds a reveal key
+func (rm *RevealMiddleware) Authorize(c fiber.Ctx) error {
+	if !fiber.Query[bool](c, "reveal") {
+		return c.Next()
+	}
+
+	key := c.Get("X-Reveal-Key")
+	if key == "" || !rm.revealKeys[key] {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"status":  "error",
+			"message": "Revealing secret values requires a valid X-Reveal-Key header",
+		})
+	}
+
+	c.Locals(revealKey, true)
+	return c.Next()
+}
+
+// Revealed reports whether Authorize allowed the request to reveal secret values
+func Revealed(c fiber.Ctx) bool {
+	revealed, _ := c.Locals(revealKey).(bool)
+	return revealed
+}
@@ -0,0 +1,354 @@
+package autorollback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/prometheus_client/query"
+)
+
+// Defaults of a watch
+const (
+	DefaultWindow = 10 * time.Minute // Window of a watch requested without a length
+	maxFinished   = 100              // Finished watches kept for the list endpoint
+)
+
+// pollInterval is the time between two polls of the active alerts
+var pollInterval = 15 * time.Second
+
+// States of a watch
+const (
+	StateWatching   = "watching"    // Polling alerts
+	StatePassed     = "passed"      // The window ended without a matching alert
+	StateRolledBack = "rolled-back" // A matching alert fired and the deployment was rolled back
+	StateSkipped    = "skipped"     // A matching alert fired but the deployment was already back on the old revision
+	StateFailed     = "failed"      // A matching alert fired but the rollback failed
+	StateCancelled  = "cancelled"   // Cancelled by a request or replaced by a newer update
+)
+
+// ErrInvalidPolicy is returned when the namespace policy cannot be parsed
+var ErrInvalidPolicy = errors.New("invalid auto rollback policy")
+
+// Policy holds the watch windows of the namespaces that opted in to automatic rollback
+type Policy map[string]time.Duration
+
+// ParsePolicy parses a comma-separated list of namespace=window entries, e.g. "production=10m,staging=5m".
+// A namespace without a window uses DefaultWindow.
+func ParsePolicy(value string) (Policy, error) {
+	policy := make(Policy)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		namespace, window, found := strings.Cut(entry, "=")
+		namespace = strings.TrimSpace(namespace)
+		if namespace == "" {
+			return nil, fmt.Errorf("%w: entry %q has no namespace", ErrInvalidPolicy, entry)
+		}
+
+		policy[namespace] = DefaultWindow
+		if found {
+			duration, err := time.ParseDuration(strings.TrimSpace(window))
+			if err != nil || duration <= 0 {
+				return nil, fmt.Errorf("%w: window of namespace %s must be a positive duration like 10m", ErrInvalidPolicy, namespace)
+			}
+			policy[namespace] = duration
+		}
+	}
+	return policy, nil
+}
+
+// Window returns how long to watch an update. A request can opt in or out and set its own window
+// in seconds, otherwise the namespace policy applies. 0 means no watch.
+func (p Policy) Window(namespace string, optIn *bool, windowSeconds int) time.Duration {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	window, enabled := p[namespace]
+	if optIn != nil {
+		enabled = *optIn
+	}
+	if !enabled {
+		return 0
+	}
+
+	if windowSeconds > 0 {
+		return time.Duration(windowSeconds) * time.Second
+	}
+	if window > 0 {
+		return window
+	}
+	return DefaultWindow
+}
+
+// Watch is an update being watched for alerts, or the outcome of one
+type Watch struct {
+	ID               string           `json:"id"`
+	Cluster          string           `json:"cluster"`
+	Namespace        string           `json:"namespace"`
+	Name             string           `json:"name"`
+	PreviousRevision int64            `json:"previousRevision"` // Revision a rollback returns to
+	StartedAt        time.Time        `json:"startedAt"`
+	Until            time.Time        `json:"until"`
+	State            string           `json:"state"`
+	Reason           string           `json:"reason,omitempty"` // Why the watch ended the way it did
+	Alert            *query.Alert     `json:"alert,omitempty"`  // Alert that triggered the rollback
+	Rollback         *RollbackSummary `json:"rollback,omitempty"`
+	EndedAt          *time.Time       `json:"endedAt,omitempty"`
+
+	cancel context.CancelFunc
+	kube   *kuberclient.Client
+}
+
+// RollbackSummary describes a rollback done by a watch
+type RollbackSummary struct {
+	Revision   int64    `json:"revision"`
+	ReplicaSet string   `json:"replicaSet,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// Watcher watches updated deployments for firing alerts and rolls them back automatically.
+// Watches live in memory, a restart of the backend ends them.
+type Watcher struct {
+	log    *slog.Logger
+	prom   *query.PrometheusClient
+	policy Policy
+
+	mu       sync.Mutex
+	active   map[string]*Watch // By cluster/namespace/name
+	finished []*Watch          // Oldest first
+	nextID   int
+}
+
+// NewWatcher creates a watcher polling prom, with the namespace policy deciding which updates are watched
+func NewWatcher(log *slog.Logger, prom *query.PrometheusClient, policy Policy) *Watcher {
+	return &Watcher{
+		log:    log,
+		prom:   prom,
+		policy: policy,
+		active: make(map[string]*Watch),
+	}
+}
+
+// Window returns how long an update should be watched, see Policy.Window
+func (w *Watcher) Window(namespace string, optIn *bool, windowSeconds int) time.Duration {
+	return w.policy.Window(namespace, optIn, windowSeconds)
+}
+
+// Start watches a deployment that was just updated from previousRevision. A watch already running for
+// the same deployment is cancelled, its update was superseded.
+func (w *Watcher) Start(kube *kuberclient.Client, cluster, namespace, name string, previousRevision int64, window time.Duration) Watch {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	now := time.Now()
+
+	w.mu.Lock()
+	w.nextID++
+	watch := &Watch{
+		ID:               strconv.Itoa(w.nextID),
+		Cluster:          cluster,
+		Namespace:        namespace,
+		Name:             name,
+		PreviousRevision: previousRevision,
+		StartedAt:        now,
+		Until:            now.Add(window),
+		State:            StateWatching,
+		cancel:           cancel,
+		kube:             kube,
+	}
+	key := watchKey(cluster, namespace, name)
+	if previous, ok := w.active[key]; ok {
+		w.finishLocked(previous, StateCancelled, "superseded by a newer update", nil, nil)
+	}
+	w.active[key] = watch
+	snapshot := *watch
+	w.mu.Unlock()
+
+	w.log.Info("Watching update for alerts", "cluster", cluster, "namespace", namespace, "deployment", name, "window", window)
+	go w.run(ctx, watch)
+
+	return snapshot
+}
+
+// Cancel ends the watch of a deployment without rolling back. It reports whether a watch was running.
+func (w *Watcher) Cancel(cluster, namespace, name string) (Watch, bool) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watch, ok := w.active[watchKey(cluster, namespace, name)]
+	if !ok {
+		return Watch{}, false
+	}
+	w.finishLocked(watch, StateCancelled, "cancelled by request", nil, nil)
+	return *watch, true
+}
+
+// List returns the running and the recently finished watches of a cluster, newest first
+func (w *Watcher) List(cluster string) []Watch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	watches := make([]Watch, 0, len(w.active)+len(w.finished))
+	for _, watch := range w.active {
+		if watch.Cluster == cluster {
+			watches = append(watches, *watch)
+		}
+	}
+	for _, watch := range w.finished {
+		if watch.Cluster == cluster {
+			watches = append(watches, *watch)
+		}
+	}
+
+	sort.SliceStable(watches, func(i, j int) bool {
+		return watches[i].StartedAt.After(watches[j].StartedAt)
+	})
+	return watches
+}
+
+// run polls the active alerts until the window ends or a matching alert fires
+func (w *Watcher) run(ctx context.Context, watch *Watch) {
+	log := w.log.With(slog.String("cluster", watch.Cluster), slog.String("namespace", watch.Namespace), slog.String("deployment", watch.Name))
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			if watch.State == StateWatching {
+				w.finishLocked(watch, StatePassed, "no matching alert fired during the window", nil, nil)
+				log.Info("Update passed the alert watch")
+			}
+			w.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		alertCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		alerts, err := w.prom.GetActiveAlerts(alertCtx)
+		cancel()
+		if err != nil {
+			// Prometheus being unreachable is no reason to roll back, try again on the next tick
+			log.Warn("Failed to get active alerts", "error", err)
+			continue
+		}
+
+		alert := matchAlert(alerts, watch)
+		if alert == nil {
+			continue
+		}
+
+		w.rollback(watch, alert, log)
+		return
+	}
+}
+
+// rollback returns the deployment of a watch to its previous revision because alert fired
+func (w *Watcher) rollback(watch *Watch, alert *query.Alert, log *slog.Logger) {
+	reason := fmt.Sprintf("alert %s fired at %s", alert.Labels["alertname"], alert.ActiveAt.Format(time.RFC3339))
+
+	// The rollback must not be cut short by the end of the window
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	w.mu.Lock()
+	if watch.State != StateWatching {
+		w.mu.Unlock()
+		return
+	}
+	w.mu.Unlock()
+
+	result, err := watch.kube.RollbackDeployment(ctx, kuberclient.ServiceConfig{
+		Namespace:  watch.Namespace,
+		Name:       watch.Name,
+		Kind:       kuberclient.KindDeployment,
+		RevisionID: strconv.FormatInt(watch.PreviousRevision, 10),
+	})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err != nil {
+		log.Error("Automatic rollback failed", "error", err, "alert", alert.Labels["alertname"])
+		w.finishLocked(watch, StateFailed, fmt.Sprintf("%s, rollback failed: %v", reason, err), alert, nil)
+		return
+	}
+
+	summary := &RollbackSummary{Revision: result.Revision, ReplicaSet: result.ReplicaSet, Warnings: result.Warnings}
+	if result.Skipped {
+		log.Info("Alert fired but deployment already was on the previous revision", "alert", alert.Labels["alertname"])
+		w.finishLocked(watch, StateSkipped, reason+", deployment already was on the previous revision", alert, summary)
+		return
+	}
+
+	log.Warn("Deployment rolled back automatically", "alert", alert.Labels["alertname"], "revision", result.Revision)
+	w.finishLocked(watch, StateRolledBack, fmt.Sprintf("%s, rolled back to revision %d", reason, result.Revision), alert, summary)
+}
+
+// finishLocked ends a watch and moves it to the finished list, w.mu must be held
+func (w *Watcher) finishLocked(watch *Watch, state, reason string, alert *query.Alert, rollback *RollbackSummary) {
+	if watch.State != StateWatching {
+		return
+	}
+
+	now := time.Now()
+	watch.State = state
+	watch.Reason = reason
+	watch.Alert = alert
+	watch.Rollback = rollback
+	watch.EndedAt = &now
+	watch.cancel()
+
+	key := watchKey(watch.Cluster, watch.Namespace, watch.Name)
+	if w.active[key] == watch {
+		delete(w.active, key)
+	}
+	w.finished = append(w.finished, watch)
+	if len(w.finished) > maxFinished {
+		w.finished = w.finished[len(w.finished)-maxFinished:]
+	}
+}
+
+// matchAlert returns the first firing alert that belongs to the deployment of a watch: the namespace label
+// must match, and the deployment or service label must be the name. An alert that was already active
+// before the update counts as well, the update did not fix what it reports.
+func matchAlert(alerts []query.Alert, watch *Watch) *query.Alert {
+	for i := range alerts {
+		alert := &alerts[i]
+		if alert.State != "firing" {
+			continue
+		}
+		if alert.Labels["namespace"] != watch.Namespace {
+			continue
+		}
+		if alert.Labels["deployment"] == watch.Name || alert.Labels["service"] == watch.Name {
+			return alert
+		}
+	}
+	return nil
+}
+
+// watchKey identifies the deployment of a watch
+func watchKey(cluster, namespace, name string) string {
+	return cluster + "/" + namespace + "/" + name
+}
@@ -0,0 +1,190 @@
+package autorollback
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/prometheus_client/query"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Policy
+		wantErr bool
+	}{
+		{"empty", "", Policy{}, false},
+		{"namespaces with and without window", "production=15m, staging ,", Policy{"production": 15 * time.Minute, "staging": DefaultWindow}, false},
+		{"missing namespace", "=5m", nil, true},
+		{"invalid window", "production=soon", nil, true},
+		{"negative window", "production=-5m", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPolicy) {
+					t.Errorf("got policy %v and error %v, want ErrInvalidPolicy", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePolicy: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got policy %v, want %v", got, tt.want)
+			}
+			for namespace, window := range tt.want {
+				if got[namespace] != window {
+					t.Errorf("got window %v for %s, want %v", got[namespace], namespace, window)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyWindow(t *testing.T) {
+	policy := Policy{"production": 15 * time.Minute, "default": 5 * time.Minute}
+	yes, no := true, false
+
+	tests := []struct {
+		name          string
+		namespace     string
+		optIn         *bool
+		windowSeconds int
+		want          time.Duration
+	}{
+		{"namespace window", "production", nil, 0, 15 * time.Minute},
+		{"empty namespace is default", "", nil, 0, 5 * time.Minute},
+		{"namespace without policy", "staging", nil, 0, 0},
+		{"request window overrides the namespace window", "production", nil, 60, time.Minute},
+		{"opt out", "production", &no, 60, 0},
+		{"opt in without namespace policy", "staging", &yes, 0, DefaultWindow},
+		{"opt in with request window", "staging", &yes, 90, 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Window(tt.namespace, tt.optIn, tt.windowSeconds); got != tt.want {
+				t.Errorf("Window(%q) = %v, want %v", tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
+
+// testAlert returns a firing alert with labels, active since activeAt
+func testAlert(activeAt time.Time, labels ...string) query.Alert {
+	alert := query.Alert{Labels: map[string]string{"alertname": "HighErrorRate"}, State: "firing", ActiveAt: activeAt}
+	for i := 0; i+1 < len(labels); i += 2 {
+		alert.Labels[labels[i]] = labels[i+1]
+	}
+	return alert
+}
+
+func TestMatchAlert(t *testing.T) {
+	started := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	watch := &Watch{Namespace: "production", Name: "web", StartedAt: started}
+	after := started.Add(time.Minute)
+
+	pending := testAlert(after, "namespace", "production", "deployment", "web")
+	pending.State = "pending"
+
+	tests := []struct {
+		name   string
+		alerts []query.Alert
+		want   int // Index of the matching alert, -1 for none
+	}{
+		{"no alerts", nil, -1},
+		{"deployment label", []query.Alert{testAlert(after, "namespace", "production", "deployment", "web")}, 0},
+		{"service label", []query.Alert{testAlert(after, "namespace", "production", "service", "web")}, 0},
+		{"still firing since before the update", []query.Alert{testAlert(started.Add(-time.Hour), "namespace", "production", "deployment", "web")}, 0},
+		{"other namespace", []query.Alert{testAlert(after, "namespace", "staging", "deployment", "web")}, -1},
+		{"no namespace label", []query.Alert{testAlert(after, "deployment", "web")}, -1},
+		{"other deployment", []query.Alert{testAlert(after, "namespace", "production", "deployment", "web-api")}, -1},
+		{"pending alert", []query.Alert{pending}, -1},
+		{
+			name: "first matching alert",
+			alerts: []query.Alert{
+				testAlert(after, "namespace", "production", "deployment", "api"),
+				testAlert(after, "namespace", "production", "service", "web"),
+				testAlert(after, "namespace", "production", "deployment", "web"),
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchAlert(tt.alerts, watch)
+			switch {
+			case tt.want < 0 && got != nil:
+				t.Errorf("got alert %v, want none", got.Labels)
+			case tt.want >= 0 && got != &tt.alerts[tt.want]:
+				t.Errorf("got alert %v, want alert %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchPassesWhenWindowEnds(t *testing.T) {
+	interval := pollInterval
+	pollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pollInterval = interval })
+
+	// Alerts of other deployments keep firing during the whole window
+	var response query.AlertsResponse
+	response.Status = "success"
+	response.Data.Alerts = []query.Alert{
+		testAlert(time.Now(), "namespace", "production", "deployment", "web-api"),
+		testAlert(time.Now(), "namespace", "staging", "deployment", "web"),
+	}
+	polls := make(chan struct{}, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case polls <- struct{}{}:
+		default:
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	watcher := NewWatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), query.NewPrometheusClient(server.URL), Policy{})
+	started := watcher.Start(nil, "local", "production", "web", 3, 100*time.Millisecond)
+	if started.State != StateWatching || !started.Until.Equal(started.StartedAt.Add(100*time.Millisecond)) {
+		t.Fatalf("got started watch %+v, want watching for 100ms", started)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		watches := watcher.List("local")
+		if len(watches) != 1 {
+			t.Fatalf("got %d watches, want 1", len(watches))
+		}
+		if watch := watches[0]; watch.State != StateWatching {
+			if watch.State != StatePassed || watch.Alert != nil || watch.EndedAt == nil {
+				t.Errorf("got watch %+v, want passed without alert", watch)
+			}
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("watch did not end after its window")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if len(polls) == 0 {
+		t.Error("alerts were not polled during the window")
+	}
+	if _, ok := watcher.Cancel("local", "production", "web"); ok {
+		t.Error("cancelled a watch that already ended")
+	}
+}
@@ -0,0 +1,322 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/prometheus_client/query"
+)
+
+// Defaults for a Spec that leaves them out
+const (
+	defaultReplicas       = 1
+	defaultAnalysis       = 5 * time.Minute
+	defaultInterval       = 30 * time.Second
+	defaultRolloutTimeout = 5 * time.Minute
+)
+
+// Phases of a canary run, in the order they occur
+const (
+	PhaseCreated   = "created"   // Canary deployment created or updated
+	PhaseReady     = "ready"     // Canary pods are available
+	PhaseAnalysis  = "analysis"  // One evaluation of the success criteria
+	PhasePromoting = "promoting" // New image applied to the main deployment
+	PhasePromoted  = "promoted"  // Main deployment rolled out, canary removed
+	PhaseAborted   = "aborted"   // Canary failed, it is being removed
+	PhaseTornDown  = "torn-down" // Canary removed, main deployment untouched
+)
+
+// ErrInvalidSpec is returned when a canary spec cannot be run
+var ErrInvalidSpec = errors.New("invalid canary spec")
+
+// Criterion is a PromQL success criterion. The query must return a single sample, e.g. an error ratio
+// or a latency quantile, which has to stay within Min and Max. In the query $namespace, $deployment
+// and $canary are replaced by the namespace, the main deployment and the canary deployment name.
+type Criterion struct {
+	Name  string   `json:"name"`
+	Query string   `json:"query"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// Spec configures a canary run
+type Spec struct {
+	Replicas        int32       `json:"replicas,omitempty"`        // Canary pods, default 1
+	AnalysisSeconds int         `json:"analysisSeconds,omitempty"` // Length of the analysis window, default 300
+	IntervalSeconds int         `json:"intervalSeconds,omitempty"` // Time between evaluations, default 30
+	Criteria        []Criterion `json:"criteria"`
+}
+
+// Check is the result of evaluating one criterion
+type Check struct {
+	Name   string   `json:"name"`
+	Value  *float64 `json:"value,omitempty"`
+	Passed bool     `json:"passed"`
+	NoData bool     `json:"noData,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Step is reported for every stage of a canary run
+type Step struct {
+	Phase   string    `json:"phase"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+	Checks  []Check   `json:"checks,omitempty"`
+}
+
+// Report is the outcome of a canary run
+type Report struct {
+	Canary   string `json:"canary"`
+	Promoted bool   `json:"promoted"`
+	Reason   string `json:"reason,omitempty"` // Why the canary was not promoted
+	Steps    []Step `json:"steps"`
+}
+
+// Validate checks a spec and fills in defaults
+func (s *Spec) Validate() error {
+	if s.Replicas < 0 || s.AnalysisSeconds < 0 || s.IntervalSeconds < 0 {
+		return fmt.Errorf("%w: replicas, analysisSeconds and intervalSeconds must not be negative", ErrInvalidSpec)
+	}
+	if s.Replicas == 0 {
+		s.Replicas = defaultReplicas
+	}
+	if s.AnalysisSeconds == 0 {
+		s.AnalysisSeconds = int(defaultAnalysis.Seconds())
+	}
+	if s.IntervalSeconds == 0 {
+		s.IntervalSeconds = int(defaultInterval.Seconds())
+	}
+	if s.IntervalSeconds > s.AnalysisSeconds {
+		return fmt.Errorf("%w: intervalSeconds must not exceed analysisSeconds", ErrInvalidSpec)
+	}
+
+	if len(s.Criteria) == 0 {
+		return fmt.Errorf("%w: at least one criterion is required", ErrInvalidSpec)
+	}
+	for i, criterion := range s.Criteria {
+		if criterion.Query == "" {
+			return fmt.Errorf("%w: criterion %d has no query", ErrInvalidSpec, i+1)
+		}
+		if criterion.Min == nil && criterion.Max == nil {
+			return fmt.Errorf("%w: criterion %q needs min or max", ErrInvalidSpec, criterion.Query)
+		}
+		if criterion.Name == "" {
+			s.Criteria[i].Name = fmt.Sprintf("criterion %d", i+1)
+		}
+	}
+
+	return nil
+}
+
+// Timeout returns the longest a run of the spec can take
+func (s *Spec) Timeout() time.Duration {
+	return time.Duration(s.AnalysisSeconds)*time.Second + 2*defaultRolloutTimeout
+}
+
+// Runner runs canary updates of deployments
+type Runner struct {
+	kube *kuberclient.Client
+	prom *query.PrometheusClient
+}
+
+// NewRunner creates a runner for a cluster, evaluating criteria against prom
+func NewRunner(kube *kuberclient.Client, prom *query.PrometheusClient) *Runner {
+	return &Runner{
+		kube: kube,
+		prom: prom,
+	}
+}
+
+// Run updates a deployment through a canary: the new image first runs in a canary deployment next to
+// it, and is only applied to the deployment if every criterion held for the whole analysis window.
+// A failing criterion, a failing query or a criterion without data tears the canary down and leaves
+// the deployment untouched. onStep, if set, is called with every step as it happens. The returned
+// error is set when the run could not be completed, the report describes how far it got.
+func (r *Runner) Run(ctx context.Context, config kuberclient.ServiceConfig, spec Spec, onStep func(Step)) (*Report, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Namespace == "" {
+		config.Namespace = "default"
+	}
+
+	report := &Report{Canary: kuberclient.CanaryName(config.Name)}
+	step := func(phase, message string, checks []Check) {
+		s := Step{Phase: phase, Message: message, Time: time.Now(), Checks: checks}
+		report.Steps = append(report.Steps, s)
+		if onStep != nil {
+			onStep(s)
+		}
+	}
+
+	canary, err := r.kube.CreateCanary(ctx, config, spec.Replicas)
+	if err != nil {
+		return report, err
+	}
+	action := "created"
+	if canary.Updated {
+		action = "updated"
+	}
+	step(PhaseCreated, fmt.Sprintf("canary %s %s with %d replica(s), containers: %s",
+		canary.Name, action, canary.Replicas, strings.Join(canary.Containers, ", ")), nil)
+
+	// From here on the canary exists and has to be removed unless it gets promoted
+	abort := func(reason string) (*Report, error) {
+		report.Reason = reason
+		step(PhaseAborted, reason, nil)
+
+		// Tear down even when ctx is done, a forgotten canary keeps serving the new image
+		teardownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := r.kube.DeleteCanary(teardownCtx, config.Namespace, config.Name); err != nil {
+			return report, err
+		}
+		step(PhaseTornDown, fmt.Sprintf("canary %s deleted, %s was not changed", canary.Name, config.Name), nil)
+		return report, nil
+	}
+
+	rolloutCtx, cancel := context.WithTimeout(ctx, defaultRolloutTimeout)
+	_, err = r.kube.WaitForRollout(rolloutCtx, config.Namespace, canary.Name, nil)
+	cancel()
+	if err != nil {
+		return abort(fmt.Sprintf("canary did not become ready: %v", err))
+	}
+	step(PhaseReady, fmt.Sprintf("canary %s is available, analysing for %ds", canary.Name, spec.AnalysisSeconds), nil)
+
+	if reason := r.analyse(ctx, config, spec, step); reason != "" {
+		return abort(reason)
+	}
+
+	// Promote
+	if _, err := r.kube.UpdateWorkload(ctx, config); err != nil {
+		return abort(fmt.Sprintf("failed to promote: %v", err))
+	}
+	step(PhasePromoting, fmt.Sprintf("new image applied to %s, waiting for the rollout", config.Name), nil)
+
+	rolloutCtx, cancel = context.WithTimeout(ctx, defaultRolloutTimeout)
+	_, rolloutErr := r.kube.WaitForRollout(rolloutCtx, config.Namespace, config.Name, nil)
+	cancel()
+
+	// The canary has served its purpose either way
+	teardownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.kube.DeleteCanary(teardownCtx, config.Namespace, config.Name); err != nil {
+		return report, err
+	}
+	if rolloutErr != nil {
+		return report, fmt.Errorf("canary promoted and deleted, but the rollout of %s did not complete: %v", config.Name, rolloutErr)
+	}
+
+	report.Promoted = true
+	step(PhasePromoted, fmt.Sprintf("%s rolled out, canary %s deleted", config.Name, canary.Name), nil)
+	return report, nil
+}
+
+// analyse evaluates the criteria every interval until the analysis window ends.
+// It returns why the canary failed, or an empty string if it passed.
+func (r *Runner) analyse(ctx context.Context, config kuberclient.ServiceConfig, spec Spec, step func(string, string, []Check)) string {
+	replacer := strings.NewReplacer(
+		"$namespace", config.Namespace,
+		"$deployment", config.Name,
+		"$canary", kuberclient.CanaryName(config.Name),
+	)
+
+	interval := time.Duration(spec.IntervalSeconds) * time.Second
+	deadline := time.Now().Add(time.Duration(spec.AnalysisSeconds) * time.Second)
+	hadData := make([]bool, len(spec.Criteria))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for round := 1; ; round++ {
+		select {
+		case <-ctx.Done():
+			return fmt.Sprintf("analysis interrupted: %v", ctx.Err())
+		case <-ticker.C:
+		}
+
+		checks := make([]Check, 0, len(spec.Criteria))
+		var failed []string
+		for i, criterion := range spec.Criteria {
+			check := r.evaluate(ctx, criterion, replacer.Replace(criterion.Query))
+			checks = append(checks, check)
+			if !check.NoData {
+				hadData[i] = true
+			}
+			if !check.Passed && !check.NoData {
+				failed = append(failed, check.Name)
+			}
+		}
+
+		if len(failed) > 0 {
+			step(PhaseAnalysis, fmt.Sprintf("round %d: failed %s", round, strings.Join(failed, ", ")), checks)
+			return fmt.Sprintf("criteria failed: %s", strings.Join(failed, ", "))
+		}
+		step(PhaseAnalysis, fmt.Sprintf("round %d: all criteria passed", round), checks)
+
+		if !time.Now().Add(interval / 2).Before(deadline) {
+			break
+		}
+	}
+
+	// A criterion that never returned data proves nothing, e.g. a typo in a label
+	var missing []string
+	for i, criterion := range spec.Criteria {
+		if !hadData[i] {
+			missing = append(missing, criterion.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("no data during the whole analysis for: %s", strings.Join(missing, ", "))
+	}
+
+	return ""
+}
+
+// evaluate runs the query of a criterion and compares its value to the bounds.
+// An empty result or NaN, e.g. an error ratio without any requests, counts as no data.
+func (r *Runner) evaluate(ctx context.Context, criterion Criterion, promQL string) Check {
+	check := Check{Name: criterion.Name}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := r.prom.Query(queryCtx, promQL, time.Now())
+	if err != nil {
+		check.Error = fmt.Sprintf("query failed: %v", err)
+		return check
+	}
+	if result.Status != "success" {
+		check.Error = fmt.Sprintf("query returned status %s", result.Status)
+		return check
+	}
+
+	switch len(result.Data.Result) {
+	case 0:
+		check.NoData = true
+		return check
+	case 1:
+	default:
+		check.Error = fmt.Sprintf("query returned %d series, aggregate it to one", len(result.Data.Result))
+		return check
+	}
+
+	value, _, err := query.FormatValue(result.Data.Result[0].Value)
+	if err != nil {
+		check.Error = fmt.Sprintf("unexpected query result: %v", err)
+		return check
+	}
+	if math.IsNaN(value) {
+		check.NoData = true
+		return check
+	}
+
+	check.Value = &value
+	check.Passed = (criterion.Min == nil || value >= *criterion.Min) && (criterion.Max == nil || value <= *criterion.Max)
+	return check
+}
@@ -0,0 +1,223 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/prometheus_client/query"
+)
+
+// fakePrometheus answers instant queries with the sample values set for them. A query without values
+// returns no series, a query answering with an error status code fails.
+type fakePrometheus struct {
+	mu      sync.Mutex
+	values  map[string][]string
+	status  map[string]int
+	queries []string
+}
+
+func (f *fakePrometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	promQL := r.URL.Query().Get("query")
+
+	f.mu.Lock()
+	f.queries = append(f.queries, promQL)
+	values, status := f.values[promQL], f.status[promQL]
+	f.mu.Unlock()
+
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	series := make([]string, 0, len(values))
+	for _, value := range values {
+		series = append(series, fmt.Sprintf(`{"metric":{},"value":[1700000000,%q]}`, value))
+	}
+	fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(series, ","))
+}
+
+// newTestRunner returns a runner evaluating criteria against a fake Prometheus
+func newTestRunner(t *testing.T, prometheus *fakePrometheus) *Runner {
+	server := httptest.NewServer(prometheus)
+	t.Cleanup(server.Close)
+	return NewRunner(nil, query.NewPrometheusClient(server.URL))
+}
+
+func bound(value float64) *float64 {
+	return &value
+}
+
+func TestEvaluate(t *testing.T) {
+	prometheus := &fakePrometheus{
+		values: map[string][]string{
+			"error_ratio":     {"0.02"},
+			"latency_p99":     {"0.35"},
+			"success_ratio":   {"0.99"},
+			"no_requests":     {"NaN"},
+			"per_pod":         {"0.01", "0.02"},
+			"exact_maximum":   {"0.05"},
+			"not_a_number":    {"abc"},
+			"below_minimum":   {"0.9"},
+			"within_range":    {"120"},
+			"above_the_range": {"250"},
+		},
+		status: map[string]int{"broken": http.StatusBadRequest},
+	}
+	runner := newTestRunner(t, prometheus)
+
+	tests := []struct {
+		name       string
+		criterion  Criterion
+		wantPassed bool
+		wantNoData bool
+		wantError  bool
+		wantValue  float64
+	}{
+		{"below maximum", Criterion{Query: "error_ratio", Max: bound(0.05)}, true, false, false, 0.02},
+		{"at maximum", Criterion{Query: "exact_maximum", Max: bound(0.05)}, true, false, false, 0.05},
+		{"above maximum", Criterion{Query: "latency_p99", Max: bound(0.3)}, false, false, false, 0.35},
+		{"above minimum", Criterion{Query: "success_ratio", Min: bound(0.95)}, true, false, false, 0.99},
+		{"below minimum", Criterion{Query: "below_minimum", Min: bound(0.95)}, false, false, false, 0.9},
+		{"within range", Criterion{Query: "within_range", Min: bound(100), Max: bound(200)}, true, false, false, 120},
+		{"above range", Criterion{Query: "above_the_range", Min: bound(100), Max: bound(200)}, false, false, false, 250},
+		{"no series", Criterion{Query: "missing", Max: bound(0.05)}, false, true, false, 0},
+		{"NaN", Criterion{Query: "no_requests", Max: bound(0.05)}, false, true, false, 0},
+		{"several series", Criterion{Query: "per_pod", Max: bound(0.05)}, false, false, true, 0},
+		{"unexpected value", Criterion{Query: "not_a_number", Max: bound(0.05)}, false, false, true, 0},
+		{"failing query", Criterion{Query: "broken", Max: bound(0.05)}, false, false, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := runner.evaluate(context.Background(), tt.criterion, tt.criterion.Query)
+			if check.Passed != tt.wantPassed || check.NoData != tt.wantNoData || (check.Error != "") != tt.wantError {
+				t.Errorf("got check %+v, want passed %v, no data %v, error %v", check, tt.wantPassed, tt.wantNoData, tt.wantError)
+			}
+			if tt.wantPassed || (!tt.wantNoData && !tt.wantError) {
+				if check.Value == nil || *check.Value != tt.wantValue {
+					t.Errorf("got value %v, want %v", check.Value, tt.wantValue)
+				}
+			} else if check.Value != nil {
+				t.Errorf("got value %v, want none", *check.Value)
+			}
+		})
+	}
+}
+
+func TestAnalyse(t *testing.T) {
+	config := kuberclient.ServiceConfig{Namespace: "production", Name: "web"}
+	errorRatio := `sum(rate(http_requests_total{namespace="$namespace",pod=~"$canary-.*",code=~"5.."}[1m]))`
+	canaryErrorRatio := `sum(rate(http_requests_total{namespace="production",pod=~"web-canary-.*",code=~"5.."}[1m]))`
+
+	tests := []struct {
+		name       string
+		values     map[string][]string
+		criteria   []Criterion
+		wantReason string
+	}{
+		{
+			name:     "criteria hold",
+			values:   map[string][]string{canaryErrorRatio: {"0.01"}, "latency": {"0.2"}},
+			criteria: []Criterion{{Name: "error ratio", Query: errorRatio, Max: bound(0.05)}, {Name: "latency", Query: "latency", Max: bound(0.5)}},
+		},
+		{
+			name:       "criterion fails",
+			values:     map[string][]string{canaryErrorRatio: {"0.2"}, "latency": {"0.2"}},
+			criteria:   []Criterion{{Name: "error ratio", Query: errorRatio, Max: bound(0.05)}, {Name: "latency", Query: "latency", Max: bound(0.5)}},
+			wantReason: "criteria failed: error ratio",
+		},
+		{
+			name:       "criterion without data during the whole analysis",
+			values:     map[string][]string{canaryErrorRatio: {"0.01"}},
+			criteria:   []Criterion{{Name: "error ratio", Query: errorRatio, Max: bound(0.05)}, {Name: "latency", Query: "latency", Max: bound(0.5)}},
+			wantReason: "no data during the whole analysis for: latency",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			prometheus := &fakePrometheus{values: tt.values}
+			runner := newTestRunner(t, prometheus)
+			spec := Spec{AnalysisSeconds: 1, IntervalSeconds: 1, Criteria: tt.criteria}
+			if err := spec.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			var steps []Step
+			reason := runner.analyse(context.Background(), config, spec, func(phase, message string, checks []Check) {
+				steps = append(steps, Step{Phase: phase, Message: message, Checks: checks})
+			})
+			if reason != tt.wantReason {
+				t.Errorf("got reason %q, want %q", reason, tt.wantReason)
+			}
+			if len(steps) != 1 || steps[0].Phase != PhaseAnalysis || len(steps[0].Checks) != len(tt.criteria) {
+				t.Errorf("got steps %+v, want one analysis round checking every criterion", steps)
+			}
+
+			prometheus.mu.Lock()
+			defer prometheus.mu.Unlock()
+			if len(prometheus.queries) == 0 || prometheus.queries[0] != canaryErrorRatio {
+				t.Errorf("got queries %q, want the placeholders replaced", prometheus.queries)
+			}
+		})
+	}
+}
+
+func TestAnalyseInterrupted(t *testing.T) {
+	runner := newTestRunner(t, &fakePrometheus{})
+	spec := Spec{Criteria: []Criterion{{Query: "error_ratio", Max: bound(0.05)}}}
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reason := runner.analyse(ctx, kuberclient.ServiceConfig{Name: "web"}, spec, func(string, string, []Check) {
+		t.Error("got an analysis round after the context ended")
+	})
+	if !strings.HasPrefix(reason, "analysis interrupted") {
+		t.Errorf("got reason %q, want the analysis interrupted", reason)
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    Spec
+		wantErr bool
+	}{
+		{"defaults", Spec{Criteria: []Criterion{{Query: "up", Min: bound(1)}}}, false},
+		{"negative replicas", Spec{Replicas: -1, Criteria: []Criterion{{Query: "up", Min: bound(1)}}}, true},
+		{"interval longer than the analysis", Spec{AnalysisSeconds: 30, IntervalSeconds: 60, Criteria: []Criterion{{Query: "up", Min: bound(1)}}}, true},
+		{"no criteria", Spec{}, true},
+		{"criterion without query", Spec{Criteria: []Criterion{{Min: bound(1)}}}, true},
+		{"criterion without bounds", Spec{Criteria: []Criterion{{Query: "up"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec
+			err := spec.Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSpec) {
+					t.Errorf("got error %v, want ErrInvalidSpec", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if spec.Replicas != defaultReplicas || spec.AnalysisSeconds != 300 || spec.IntervalSeconds != 30 || spec.Criteria[0].Name != "criterion 1" {
+				t.Errorf("got spec %+v, want the defaults filled in", spec)
+			}
+		})
+	}
+}
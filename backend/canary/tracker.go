@@ -0,0 +1,157 @@
+package canary
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/autorollback"
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// Finished runs kept to be polled
+const maxFinishedRuns = 100
+
+// States of a background run
+const (
+	StateRunning     = "running"      // Canary created or being analysed
+	StatePromoted    = "promoted"     // Passed the analysis, the deployment was updated
+	StateNotPromoted = "not-promoted" // Failed the analysis, the canary was torn down
+	StateFailed      = "failed"       // The run could not be completed
+)
+
+// Run is a canary update running in the background, or the outcome of one
+type Run struct {
+	ID           string              `json:"id"`
+	Cluster      string              `json:"cluster"`
+	Namespace    string              `json:"namespace"`
+	Name         string              `json:"name"`
+	Image        string              `json:"image,omitempty"`
+	Version      string              `json:"version,omitempty"`
+	State        string              `json:"state"`
+	StartedAt    time.Time           `json:"startedAt"`
+	EndedAt      *time.Time          `json:"endedAt,omitempty"`
+	Steps        []Step              `json:"steps"` // Steps so far, the report holds them once the run ended
+	Report       *Report             `json:"report,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	AutoRollback *autorollback.Watch `json:"autoRollback,omitempty"` // Watch started after the promotion
+}
+
+// Tracker runs canary updates in the background, so a request does not have to stay open for the whole
+// analysis window, and keeps them to be polled. Runs live in memory, a restart of the backend loses them
+// and a run that was in progress leaves its canary deployment behind.
+type Tracker struct {
+	log *slog.Logger
+
+	mu       sync.Mutex
+	runs     map[string]*Run
+	finished []string // IDs of finished runs, oldest first
+	nextID   int
+}
+
+// NewTracker creates an empty tracker
+func NewTracker(log *slog.Logger) *Tracker {
+	return &Tracker{
+		log:  log,
+		runs: make(map[string]*Run),
+	}
+}
+
+// Start runs a canary update in the background and returns the run as started. The spec must be valid.
+// onPromoted, if set, is called once the canary was promoted, e.g. to start an auto rollback watch.
+func (t *Tracker) Start(runner *Runner, cluster string, config kuberclient.ServiceConfig, spec Spec, onPromoted func() *autorollback.Watch) Run {
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	t.mu.Lock()
+	t.nextID++
+	run := &Run{
+		ID:        strconv.Itoa(t.nextID),
+		Cluster:   cluster,
+		Namespace: namespace,
+		Name:      config.Name,
+		Image:     config.Image,
+		Version:   config.Version,
+		State:     StateRunning,
+		StartedAt: time.Now(),
+		Steps:     []Step{},
+	}
+	t.runs[run.ID] = run
+	snapshot := run.snapshot()
+	t.mu.Unlock()
+
+	go t.run(runner, run, config, spec, onPromoted)
+
+	return snapshot
+}
+
+// Get returns a run of a cluster by ID
+func (t *Tracker) Get(cluster, id string) (Run, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	run, ok := t.runs[id]
+	if !ok || run.Cluster != cluster {
+		return Run{}, false
+	}
+	return run.snapshot(), true
+}
+
+// run runs the canary and records its outcome
+func (t *Tracker) run(runner *Runner, run *Run, config kuberclient.ServiceConfig, spec Spec, onPromoted func() *autorollback.Watch) {
+	log := t.log.With(slog.String("canaryRun", run.ID), slog.String("namespace", run.Namespace), slog.String("deployment", run.Name))
+
+	// The run is not cancelled by anyone, it always ends promoted or torn down
+	ctx, cancel := context.WithTimeout(context.Background(), spec.Timeout())
+	defer cancel()
+
+	report, err := runner.Run(ctx, config, spec, func(step Step) {
+		t.mu.Lock()
+		run.Steps = append(run.Steps, step)
+		t.mu.Unlock()
+	})
+
+	var watch *autorollback.Watch
+	if err == nil && report.Promoted && onPromoted != nil {
+		watch = onPromoted()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	run.EndedAt = &now
+	run.Report = report
+	run.AutoRollback = watch
+	switch {
+	case err != nil:
+		run.State = StateFailed
+		run.Error = err.Error()
+		log.Error("Canary update failed", "error", err)
+	case report.Promoted:
+		run.State = StatePromoted
+		log.Info("Service updated through canary")
+	default:
+		run.State = StateNotPromoted
+		log.Warn("Canary was not promoted", "reason", report.Reason)
+	}
+
+	t.finished = append(t.finished, run.ID)
+	if len(t.finished) > maxFinishedRuns {
+		for _, id := range t.finished[:len(t.finished)-maxFinishedRuns] {
+			delete(t.runs, id)
+		}
+		t.finished = t.finished[len(t.finished)-maxFinishedRuns:]
+	}
+}
+
+// snapshot copies a run so it can be read without holding the lock
+func (r *Run) snapshot() Run {
+	snapshot := *r
+	snapshot.Steps = append([]Step{}, r.Steps...)
+	return snapshot
+}
@@ -4,15 +4,24 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/logger/handlers/slogpretty"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	ValidAPIKeys  map[string]bool
-	DebugLevel    string
-	PrometheusURL string
+	ValidAPIKeys       map[string]bool
+	RevealKeys         map[string]bool // Keys allowed to reveal secret values, from config/reveal_keys.json
+	DebugLevel         string
+	PrometheusURL      string
+	KubeClusters       string // e.g. "prod-eu=/etc/kube/prod.yaml,staging=@staging", empty for a single default cluster
+	KubeDefaultCluster string // Cluster used when a request does not name one, defaults to the first
+	KubeCache          bool   // Serve read endpoints from informer caches instead of listing on every request
+	AutoRollback       string // e.g. "production=10m,staging", namespaces whose updates are rolled back when alerts fire
+	HibernateSchedules string // JSON file with the schedules namespaces are put to sleep and woken up on
+	Sandbox            bool   // Serve an in-memory fake cluster instead of real ones
+	SandboxFixture     string // YAML file the sandbox cluster is seeded from
 }
 
 func NewConfig() *Config {
@@ -25,6 +34,11 @@ func NewConfig() *Config {
 		log.Fatal(err)
 	}
 
+	revealKeys, err := GetRevealKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	debugLevel := os.Getenv("DEBUG_LEVEL")
 	if debugLevel == "" {
 		debugLevel = slogpretty.EnvProd
@@ -35,10 +49,35 @@ func NewConfig() *Config {
 		prometheusURL = "http://localhost:9090"
 	}
 
+	kubeCache, err := strconv.ParseBool(os.Getenv("KUBE_CACHE"))
+	if err != nil {
+		kubeCache = true
+	}
+
+	hibernateSchedules := os.Getenv("HIBERNATE_SCHEDULES")
+	if hibernateSchedules == "" {
+		hibernateSchedules = filepath.Join("config", "hibernate.json")
+	}
+
+	sandbox, _ := strconv.ParseBool(os.Getenv("SANDBOX"))
+
+	sandboxFixture := os.Getenv("SANDBOX_FIXTURE")
+	if sandboxFixture == "" {
+		sandboxFixture = filepath.Join("config", "sandbox.yaml")
+	}
+
 	return &Config{
-		ValidAPIKeys:  keys,
-		DebugLevel:    debugLevel,
-		PrometheusURL: prometheusURL,
+		ValidAPIKeys:       keys,
+		RevealKeys:         revealKeys,
+		DebugLevel:         debugLevel,
+		PrometheusURL:      prometheusURL,
+		KubeClusters:       os.Getenv("KUBE_CLUSTERS"),
+		KubeDefaultCluster: os.Getenv("KUBE_DEFAULT_CLUSTER"),
+		KubeCache:          kubeCache,
+		AutoRollback:       os.Getenv("AUTO_ROLLBACK_NAMESPACES"),
+		HibernateSchedules: hibernateSchedules,
+		Sandbox:            sandbox,
+		SandboxFixture:     sandboxFixture,
 	}
 }
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 
 	"os"
 	"path/filepath"
@@ -34,3 +35,27 @@ func GetValidKeys() (map[string]bool, error) {
 
 	return keys, nil
 }
+
+// GetRevealKeys reads the optional reveal_keys.json file and returns the keys allowed to reveal secret values.
+// Without the file no key can reveal them.
+func GetRevealKeys() (map[string]bool, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config", "reveal_keys.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys map[string]bool
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
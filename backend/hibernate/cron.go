@@ -0,0 +1,174 @@
+package hibernate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearch bounds the search for the next activation of a cron expression
+const maxSearch = 366 * 24 * time.Hour
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month, month and day of week
+type Cron struct {
+	expr    string
+	minutes []bool // 0-59
+	hours   []bool // 0-23
+	days    []bool // 1-31
+	months  []bool // 1-12
+	weekday []bool // 0-6, Sunday is 0
+	anyDay  bool   // Day of month starts with *, e.g. * or */2
+	anyWeek bool   // Day of week starts with *
+}
+
+// cronField describes the allowed values of one cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses a cron expression such as "0 20 * * 1-5". Every field accepts *, numbers,
+// ranges (1-5), steps (*/15, 0-30/10) and comma-separated lists of those. Sunday is 0 or 7.
+// Like in cron, when both day of month and day of week are restricted either of them matches.
+// A field starting with * (*/2 too) does not count as restricted, both are then required to match.
+func ParseCron(expr string) (*Cron, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	values := make([][]bool, len(cronFields))
+	for i, part := range parts {
+		field, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %v", expr, err)
+		}
+		values[i] = field
+	}
+
+	// Sunday may be written as 7
+	weekday := values[4][:7]
+	weekday[0] = weekday[0] || values[4][7]
+
+	return &Cron{
+		expr:    strings.Join(parts, " "),
+		minutes: values[0],
+		hours:   values[1],
+		days:    values[2],
+		months:  values[3],
+		weekday: weekday,
+		anyDay:  strings.HasPrefix(parts[2], "*"),
+		anyWeek: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseCronField parses one field into a lookup table indexed by value
+func parseCronField(part string, field cronField) ([]bool, error) {
+	values := make([]bool, field.max+1)
+
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q in %s field", stepPart, field.name)
+			}
+		}
+
+		low, high := field.min, field.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			low, err = parseCronValue(lowPart, field)
+			if err != nil {
+				return nil, err
+			}
+			high = low
+			if isRange {
+				high, err = parseCronValue(highPart, field)
+				if err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				high = field.max
+			}
+			if high < low {
+				return nil, fmt.Errorf("invalid range %q in %s field", rangePart, field.name)
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+// parseCronValue parses a single number of a field and checks its bounds
+func parseCronValue(value string, field cronField) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < field.min || number > field.max {
+		return 0, fmt.Errorf("invalid value %q in %s field, expected %d-%d", value, field.name, field.min, field.max)
+	}
+	return number, nil
+}
+
+// String returns the expression the cron was parsed from
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Matches reports whether t, truncated to the minute, is an activation of the expression
+func (c *Cron) Matches(t time.Time) bool {
+	return c.minutes[t.Minute()] && c.hours[t.Hour()] && c.matchesDay(t)
+}
+
+// matchesDay reports whether the date of t matches the month, day of month and day of week fields
+func (c *Cron) matchesDay(t time.Time) bool {
+	if !c.months[int(t.Month())] {
+		return false
+	}
+
+	day := c.days[t.Day()]
+	weekday := c.weekday[int(t.Weekday())]
+	// Like Vixie cron, a day field starting with * is ANDed with the other one, with its step applied
+	if c.anyDay || c.anyWeek {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Next returns the first activation strictly after t, in the location of t.
+// The zero time is returned when there is none within a year, e.g. for "0 0 31 2 *".
+func (c *Cron) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(maxSearch)
+
+	for next.Before(end) {
+		if !c.matchesDay(next) {
+			// Jump to the start of the next day
+			year, month, day := next.Date()
+			next = time.Date(year, month, day+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if c.Matches(next) {
+			return next
+		}
+		next = next.Add(time.Minute)
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,168 @@
+package hibernate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "0 20 * *"},
+		{"too many fields", "0 20 * * * *"},
+		{"minute out of range", "60 20 * * *"},
+		{"hour out of range", "0 24 * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"reversed range", "0 0 * * 5-1"},
+		{"zero step", "*/0 * * * *"},
+		{"negative step", "*/-5 * * * *"},
+		{"not a number", "a * * * *"},
+		{"empty list item", "0, * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); err == nil {
+				t.Errorf("ParseCron(%q) succeeded, want an error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		time time.Time
+		want bool
+	}{
+		{"every minute", "* * * * *", at(3, 13, 37), true},
+		{"step matches", "*/15 * * * *", at(1, 10, 45), true},
+		{"step misses", "*/15 * * * *", at(1, 10, 50), false},
+		{"range step", "0-30/10 * * * *", at(1, 10, 30), true},
+		{"range step beyond range", "0-30/10 * * * *", at(1, 10, 40), false},
+		{"value step runs to max", "5/20 * * * *", at(1, 10, 45), true},
+		{"list", "0 8,20 * * *", at(1, 20, 0), true},
+		{"list misses", "0 8,20 * * *", at(1, 12, 0), false},
+		{"weekday range Monday", "0 20 * * 1-5", at(1, 20, 0), true},
+		{"weekday range Friday", "0 20 * * 1-5", at(5, 20, 0), true},
+		{"weekday range Saturday", "0 20 * * 1-5", at(6, 20, 0), false},
+		{"Sunday as 0", "0 0 * * 0", at(7, 0, 0), true},
+		{"Sunday as 7", "0 0 * * 7", at(7, 0, 0), true},
+		{"Sunday as 7 misses Monday", "0 0 * * 7", at(8, 0, 0), false},
+		{"range up to Sunday as 7", "0 0 * * 5-7", at(7, 0, 0), true},
+		{"day of month", "0 0 15 * *", at(15, 0, 0), true},
+		{"month", "0 0 * 2 *", at(15, 0, 0), false},
+		{"both days restricted, day of month matches", "0 0 15 * 1", at(15, 0, 0), true},
+		{"both days restricted, day of week matches", "0 0 15 * 1", at(8, 0, 0), true},
+		{"both days restricted, neither matches", "0 0 15 * 1", at(9, 0, 0), false},
+		{"starred day of month step keeps AND", "0 0 */1 * 1", at(9, 0, 0), false},
+		{"starred day of month step, day of week matches", "0 0 */1 * 1", at(8, 0, 0), true},
+		{"starred day of week step keeps AND", "0 0 15 * */1", at(9, 0, 0), false},
+		{"day of month step matches odd day", "0 0 */2 * *", at(3, 0, 0), true},
+		{"day of month step misses even day", "0 0 */2 * *", at(4, 0, 0), false},
+		{"day of month step and weekday both match", "0 0 */2 * 1-5", at(1, 0, 0), true},
+		{"day of month step matches, weekday misses", "0 0 */2 * 1-5", at(7, 0, 0), false},
+		{"day of week step matches Sunday", "0 0 * * */2", at(7, 0, 0), true},
+		{"day of week step matches Tuesday", "0 0 * * */2", at(2, 0, 0), true},
+		{"day of week step misses Monday", "0 0 * * */2", at(1, 0, 0), false},
+		{"day of month matches, day of week step misses", "0 0 15 * */2", at(15, 0, 0), false},
+		{"day of month and day of week step both match", "0 0 16 * */2", at(16, 0, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+			}
+			if got := cron.Matches(tt.time); got != tt.want {
+				t.Errorf("ParseCron(%q).Matches(%s) = %v, want %v", tt.expr, tt.time.Format(time.RFC1123), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "same day",
+			expr: "0 20 * * *",
+			from: time.Date(2024, time.January, 1, 9, 30, 0, 0, time.UTC),
+			want: time.Date(2024, time.January, 1, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "strictly after",
+			expr: "0 20 * * *",
+			from: time.Date(2024, time.January, 1, 20, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.January, 2, 20, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "seconds are dropped",
+			expr: "*/15 * * * *",
+			from: time.Date(2024, time.January, 1, 9, 14, 59, 0, time.UTC),
+			want: time.Date(2024, time.January, 1, 9, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "Friday evening to Monday morning",
+			expr: "0 8 * * 1-5",
+			from: time.Date(2024, time.January, 5, 20, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.January, 8, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next year",
+			expr: "0 0 1 1 *",
+			from: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "in the location of the time",
+			expr: "0 20 * * *",
+			from: time.Date(2024, time.January, 1, 12, 0, 0, 0, berlin),
+			want: time.Date(2024, time.January, 1, 20, 0, 0, 0, berlin),
+		},
+		{
+			name: "across daylight saving time",
+			expr: "0 8 * * *",
+			from: time.Date(2024, time.March, 30, 12, 0, 0, 0, berlin),
+			want: time.Date(2024, time.March, 31, 8, 0, 0, 0, berlin),
+		},
+		{
+			name: "never",
+			expr: "0 0 31 2 *",
+			from: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+			}
+			if got := cron.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("ParseCron(%q).Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+			}
+		})
+	}
+}
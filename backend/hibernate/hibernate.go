@@ -0,0 +1,390 @@
+package hibernate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+	_ "time/tzdata" // Schedule timezones must resolve in images without a zoneinfo database
+
+	kuberclient "github.com/ilyalinhnguyen/chatops-go-to-sleep/backend/kuber_client"
+)
+
+// Actions of a run
+const (
+	ActionSleep = "sleep"
+	ActionWake  = "wake"
+)
+
+// Triggers of a run
+const (
+	TriggerSchedule = "schedule" // The sleep or wake time was reached
+	TriggerManual   = "manual"   // Forced by a request
+)
+
+// States of a schedule, taken from its last run
+const (
+	StateUnknown = "unknown" // Nothing ran since the backend started
+	StateAsleep  = "asleep"
+	StateAwake   = "awake"
+)
+
+// runTimeout bounds a single sleep or wake run over all namespaces of a schedule
+const runTimeout = 2 * time.Minute
+
+var (
+	// ErrInvalidSchedule is returned when a schedule cannot be parsed
+	ErrInvalidSchedule = errors.New("invalid hibernation schedule")
+	// ErrUnknownSchedule is returned when a request names a schedule that is not configured
+	ErrUnknownSchedule = errors.New("unknown hibernation schedule")
+	// ErrRunning is returned when a schedule is asked to run while a run is in progress
+	ErrRunning = errors.New("hibernation schedule is already running")
+)
+
+// ScheduleConfig is a schedule as written in the schedules file
+type ScheduleConfig struct {
+	Name       string   `json:"name"`
+	Cluster    string   `json:"cluster,omitempty"` // Defaults to the default cluster
+	Namespaces []string `json:"namespaces"`
+	Exclude    []string `json:"exclude,omitempty"`  // Deployments that never sleep
+	Sleep      string   `json:"sleep"`              // Cron expression, e.g. "0 20 * * 1-5"
+	Wake       string   `json:"wake"`               // Cron expression, e.g. "0 8 * * 1-5"
+	Timezone   string   `json:"timezone,omitempty"` // IANA name, defaults to UTC
+}
+
+// LoadSchedules reads the schedules file, a JSON array of ScheduleConfig. A missing file means no schedules.
+func LoadSchedules(path string) ([]ScheduleConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []ScheduleConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSchedule, path, err)
+	}
+	return configs, nil
+}
+
+// NamespaceResult is the outcome of a run in one namespace
+type NamespaceResult struct {
+	Namespace   string                        `json:"namespace"`
+	Deployments []kuberclient.HibernateResult `json:"deployments"`
+	Error       string                        `json:"error,omitempty"`
+}
+
+// Run is one sleep or wake of a schedule, or a scheduled sleep that was skipped
+type Run struct {
+	Action     string            `json:"action"`
+	Trigger    string            `json:"trigger"`
+	Skipped    bool              `json:"skipped,omitempty"` // The sleep was skipped on request, nothing was scaled
+	StartedAt  time.Time         `json:"startedAt"`
+	EndedAt    time.Time         `json:"endedAt"`
+	Namespaces []NamespaceResult `json:"namespaces,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Schedule is the state of a configured schedule
+type Schedule struct {
+	ScheduleConfig
+	State     string     `json:"state"`
+	NextSleep *time.Time `json:"nextSleep,omitempty"`
+	NextWake  *time.Time `json:"nextWake,omitempty"`
+	SkipSleep *time.Time `json:"skipSleep,omitempty"` // Scheduled sleep that will be skipped
+	Running   bool       `json:"running"`
+	LastRun   *Run       `json:"lastRun,omitempty"`
+}
+
+// schedule is a parsed schedule with its runtime state
+type schedule struct {
+	config   ScheduleConfig
+	sleep    *Cron
+	wake     *Cron
+	location *time.Location
+
+	state     string
+	skipSleep time.Time
+	running   bool
+	lastRun   *Run
+}
+
+// Scheduler puts the deployments of the configured namespaces to sleep and wakes them up on their schedules.
+// The state lives in memory, but the replica counts to restore are kept in the deployments themselves,
+// so a restart of the backend does not lose them. Sleep and wake times passed while the backend was down are not caught up.
+type Scheduler struct {
+	log      *slog.Logger
+	registry *kuberclient.Registry
+
+	mu        sync.Mutex
+	schedules []*schedule
+	byName    map[string]*schedule
+}
+
+// NewScheduler validates the schedules and creates a scheduler running them against the clusters of registry
+func NewScheduler(log *slog.Logger, registry *kuberclient.Registry, configs []ScheduleConfig) (*Scheduler, error) {
+	scheduler := &Scheduler{
+		log:      log,
+		registry: registry,
+		byName:   make(map[string]*schedule),
+	}
+
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("%w: schedule without a name", ErrInvalidSchedule)
+		}
+		if _, ok := scheduler.byName[config.Name]; ok {
+			return nil, fmt.Errorf("%w: schedule %s is defined twice", ErrInvalidSchedule, config.Name)
+		}
+		if len(config.Namespaces) == 0 {
+			return nil, fmt.Errorf("%w: schedule %s has no namespaces", ErrInvalidSchedule, config.Name)
+		}
+
+		sleep, err := ParseCron(config.Sleep)
+		if err != nil {
+			return nil, fmt.Errorf("%w: sleep of schedule %s: %v", ErrInvalidSchedule, config.Name, err)
+		}
+		wake, err := ParseCron(config.Wake)
+		if err != nil {
+			return nil, fmt.Errorf("%w: wake of schedule %s: %v", ErrInvalidSchedule, config.Name, err)
+		}
+
+		location := time.UTC
+		if config.Timezone != "" {
+			location, err = time.LoadLocation(config.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("%w: timezone of schedule %s: %v", ErrInvalidSchedule, config.Name, err)
+			}
+		}
+
+		s := &schedule{config: config, sleep: sleep, wake: wake, location: location, state: StateUnknown}
+		scheduler.schedules = append(scheduler.schedules, s)
+		scheduler.byName[config.Name] = s
+	}
+
+	return scheduler, nil
+}
+
+// Start checks the schedules at the start of every minute until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	if len(s.schedules) == 0 {
+		return
+	}
+
+	go func() {
+		for {
+			now := time.Now()
+			timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case tick := <-timer.C:
+				s.tick(tick.Truncate(time.Minute))
+			}
+		}
+	}()
+}
+
+// tick runs every schedule whose sleep or wake time is now. Wake wins if both are.
+func (s *Scheduler) tick(now time.Time) {
+	for _, sched := range s.schedules {
+		local := now.In(sched.location)
+
+		switch {
+		case sched.wake.Matches(local):
+			go s.runScheduled(sched, ActionWake)
+		case sched.sleep.Matches(local):
+			s.mu.Lock()
+			skipped := !sched.skipSleep.IsZero() && !now.Before(sched.skipSleep)
+			if skipped {
+				sched.skipSleep = time.Time{}
+				sched.lastRun = &Run{Action: ActionSleep, Trigger: TriggerSchedule, Skipped: true, StartedAt: now, EndedAt: now}
+			}
+			s.mu.Unlock()
+
+			if skipped {
+				s.log.Info("Scheduled sleep skipped", "schedule", sched.config.Name)
+				continue
+			}
+			go s.runScheduled(sched, ActionSleep)
+		}
+	}
+}
+
+// runScheduled runs a schedule because its time was reached, logging instead of returning the outcome
+func (s *Scheduler) runScheduled(sched *schedule, action string) {
+	run, err := s.run(sched, action, TriggerSchedule)
+	if err != nil {
+		s.log.Warn("Scheduled hibernation run not started", "schedule", sched.config.Name, "action", action, "error", err)
+		return
+	}
+	if run.Error != "" {
+		s.log.Error("Scheduled hibernation run failed", "schedule", sched.config.Name, "action", action, "error", run.Error)
+	}
+}
+
+// List returns the state of all schedules in configuration order
+func (s *Scheduler) List() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	schedules := make([]Schedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		schedules = append(schedules, sched.snapshot(now))
+	}
+	return schedules
+}
+
+// Skip skips the next scheduled sleep of a schedule, e.g. to keep an environment up tonight.
+// With cancel a skip requested earlier is withdrawn.
+func (s *Scheduler) Skip(name string, cancel bool) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sched, ok := s.byName[name]
+	if !ok {
+		return Schedule{}, fmt.Errorf("%w %q", ErrUnknownSchedule, name)
+	}
+
+	now := time.Now()
+	if cancel {
+		sched.skipSleep = time.Time{}
+	} else {
+		sched.skipSleep = sched.sleep.Next(now.In(sched.location))
+	}
+
+	s.log.Info("Hibernation skip updated", "schedule", name, "cancel", cancel, "skipSleep", sched.skipSleep)
+	return sched.snapshot(now), nil
+}
+
+// Sleep puts the namespaces of a schedule to sleep right away
+func (s *Scheduler) Sleep(name string) (*Run, error) {
+	return s.runManual(name, ActionSleep)
+}
+
+// Wake wakes the namespaces of a schedule up right away
+func (s *Scheduler) Wake(name string) (*Run, error) {
+	return s.runManual(name, ActionWake)
+}
+
+// runManual runs a schedule on request
+func (s *Scheduler) runManual(name, action string) (*Run, error) {
+	s.mu.Lock()
+	sched, ok := s.byName[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSchedule, name)
+	}
+
+	return s.run(sched, action, TriggerManual)
+}
+
+// run puts the namespaces of a schedule to sleep or wakes them up, one namespace after the other.
+// Only one run of a schedule happens at a time.
+func (s *Scheduler) run(sched *schedule, action, trigger string) (*Run, error) {
+	s.mu.Lock()
+	if sched.running {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrRunning, sched.config.Name)
+	}
+	sched.running = true
+	s.mu.Unlock()
+
+	log := s.log.With(slog.String("schedule", sched.config.Name), slog.String("action", action), slog.String("trigger", trigger))
+	log.Info("Hibernation run started")
+
+	run := &Run{Action: action, Trigger: trigger, StartedAt: time.Now()}
+	s.runNamespaces(sched, run, log)
+	run.EndedAt = time.Now()
+
+	s.mu.Lock()
+	sched.running = false
+	sched.lastRun = run
+	if len(run.Namespaces) > 0 {
+		// A partly failed run still changed the state, the failures are in the run
+		sched.state = StateAwake
+		if action == ActionSleep {
+			sched.state = StateAsleep
+		}
+	}
+	s.mu.Unlock()
+
+	log.Info("Hibernation run finished", "namespaces", len(run.Namespaces), "error", run.Error)
+	return run, nil
+}
+
+// runNamespaces applies the action of run to every namespace of a schedule
+func (s *Scheduler) runNamespaces(sched *schedule, run *Run, log *slog.Logger) {
+	if s.registry == nil {
+		run.Error = "Kubernetes clients not available"
+		return
+	}
+	kubeClient, err := s.registry.Get(sched.config.Cluster)
+	if err != nil {
+		run.Error = err.Error()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+
+	failed := 0
+	for _, namespace := range sched.config.Namespaces {
+		result := NamespaceResult{Namespace: namespace}
+
+		var deployments []kuberclient.HibernateResult
+		if run.Action == ActionSleep {
+			deployments, err = kubeClient.SleepNamespace(ctx, namespace, sched.config.Exclude)
+		} else {
+			deployments, err = kubeClient.WakeNamespace(ctx, namespace)
+		}
+		if err != nil {
+			log.Error("Hibernation of namespace failed", "namespace", namespace, "error", err)
+			result.Error = err.Error()
+			failed++
+		}
+		for _, deployment := range deployments {
+			if deployment.Status == kuberclient.HibernateFailed {
+				log.Error("Hibernation of deployment failed", "namespace", namespace, "deployment", deployment.Name, "error", deployment.Reason)
+				failed++
+			}
+		}
+
+		result.Deployments = deployments
+		run.Namespaces = append(run.Namespaces, result)
+	}
+
+	if failed > 0 {
+		run.Error = fmt.Sprintf("%d namespaces or deployments failed", failed)
+	}
+}
+
+// snapshot returns the state of a schedule for the API, s.mu must be held
+func (sched *schedule) snapshot(now time.Time) Schedule {
+	local := now.In(sched.location)
+	return Schedule{
+		ScheduleConfig: sched.config,
+		State:          sched.state,
+		NextSleep:      timeOrNil(sched.sleep.Next(local)),
+		NextWake:       timeOrNil(sched.wake.Next(local)),
+		SkipSleep:      timeOrNil(sched.skipSleep),
+		Running:        sched.running,
+		LastRun:        sched.lastRun,
+	}
+}
+
+// timeOrNil returns nil for the zero time
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
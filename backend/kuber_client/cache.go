@@ -0,0 +1,308 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Cache keeps nodes, pods and workloads in memory through shared informers, so the read endpoints
+// no longer list them from the API server on every request. Writes always go to the API server.
+type Cache struct {
+	factory      informers.SharedInformerFactory
+	nodes        corelisters.NodeLister
+	pods         corelisters.PodLister
+	deployments  appslisters.DeploymentLister
+	statefulSets appslisters.StatefulSetLister
+	daemonSets   appslisters.DaemonSetLister
+	informers    map[string]cache.SharedIndexInformer
+
+	mu        sync.RWMutex
+	startedAt time.Time
+	syncedAt  time.Time
+	resources map[string]*resourceState
+}
+
+// resourceState tracks the watch of one cached resource
+type resourceState struct {
+	lastEventAt time.Time
+	watchErr    error
+	watchErrAt  time.Time
+}
+
+// CacheStatus reports whether reads are served from the cache and how far behind it may be.
+// StaleSeconds is the time since a watch broke without having recovered, or since the start while
+// the initial sync is still running; it is 0 for a healthy cache.
+type CacheStatus struct {
+	Enabled      bool       `json:"enabled"`
+	Synced       bool       `json:"synced"`
+	SyncedAt     *time.Time `json:"syncedAt,omitempty"`
+	LastEventAt  *time.Time `json:"lastEventAt,omitempty"`
+	StaleSeconds float64    `json:"staleSeconds"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// newCache registers the informers of the cached resources without starting them
+func newCache(c *Client) *Cache {
+	factory := informers.NewSharedInformerFactory(c.clientset, 0)
+
+	cc := &Cache{
+		factory:      factory,
+		nodes:        factory.Core().V1().Nodes().Lister(),
+		pods:         factory.Core().V1().Pods().Lister(),
+		deployments:  factory.Apps().V1().Deployments().Lister(),
+		statefulSets: factory.Apps().V1().StatefulSets().Lister(),
+		daemonSets:   factory.Apps().V1().DaemonSets().Lister(),
+		informers: map[string]cache.SharedIndexInformer{
+			"nodes":        factory.Core().V1().Nodes().Informer(),
+			"pods":         factory.Core().V1().Pods().Informer(),
+			"deployments":  factory.Apps().V1().Deployments().Informer(),
+			"statefulsets": factory.Apps().V1().StatefulSets().Informer(),
+			"daemonsets":   factory.Apps().V1().DaemonSets().Informer(),
+		},
+		resources: make(map[string]*resourceState),
+	}
+
+	for resource, informer := range cc.informers {
+		state := &resourceState{}
+		cc.resources[resource] = state
+
+		// Managed fields are never read and make up a large part of every object
+		informer.SetTransform(stripManagedFields)
+		informer.SetWatchErrorHandler(func(r *cache.Reflector, err error) {
+			cache.DefaultWatchErrorHandler(r, err)
+			cc.mu.Lock()
+			defer cc.mu.Unlock()
+			state.watchErr = err
+			state.watchErrAt = time.Now()
+		})
+
+		// Any event, including the relist after a broken watch, proves the watch is healthy again
+		observe := func() {
+			cc.mu.Lock()
+			defer cc.mu.Unlock()
+			state.lastEventAt = time.Now()
+			state.watchErr = nil
+		}
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { observe() },
+			UpdateFunc: func(interface{}, interface{}) { observe() },
+			DeleteFunc: func(interface{}) { observe() },
+		})
+	}
+
+	return cc
+}
+
+// stripManagedFields drops metadata.managedFields from cached objects
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// start runs the informers until ctx is done and records when they have synced
+func (cc *Cache) start(ctx context.Context) {
+	cc.mu.Lock()
+	cc.startedAt = time.Now()
+	cc.mu.Unlock()
+
+	cc.factory.Start(ctx.Done())
+
+	go func() {
+		for resource, informer := range cc.informers {
+			if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+				cc.mu.Lock()
+				if cc.resources[resource].watchErr == nil {
+					cc.resources[resource].watchErr = fmt.Errorf("cache of %s did not sync", resource)
+				}
+				cc.mu.Unlock()
+				return
+			}
+		}
+
+		cc.mu.Lock()
+		cc.syncedAt = time.Now()
+		cc.mu.Unlock()
+	}()
+}
+
+// hasSynced reports whether reads can be served from the cache. A nil cache never has.
+func (cc *Cache) hasSynced() bool {
+	if cc == nil {
+		return false
+	}
+
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+	return !cc.syncedAt.IsZero()
+}
+
+// status summarizes the state of all cached resources
+func (cc *Cache) status() CacheStatus {
+	if cc == nil {
+		return CacheStatus{}
+	}
+
+	cc.mu.RLock()
+	defer cc.mu.RUnlock()
+
+	status := CacheStatus{Enabled: true, Synced: !cc.syncedAt.IsZero()}
+	if status.Synced {
+		syncedAt := cc.syncedAt
+		status.SyncedAt = &syncedAt
+	} else if !cc.startedAt.IsZero() {
+		status.StaleSeconds = time.Since(cc.startedAt).Seconds()
+	}
+
+	var lastEventAt time.Time
+	for resource, state := range cc.resources {
+		if state.lastEventAt.After(lastEventAt) {
+			lastEventAt = state.lastEventAt
+		}
+		if state.watchErr == nil {
+			continue
+		}
+		if stale := time.Since(state.watchErrAt).Seconds(); stale > status.StaleSeconds {
+			status.StaleSeconds = stale
+		}
+		status.Error = fmt.Sprintf("watch of %s failed: %v", resource, state.watchErr)
+	}
+	if !lastEventAt.IsZero() {
+		status.LastEventAt = &lastEventAt
+	}
+
+	return status
+}
+
+// StartCache starts the informer cache of the client. Reads fall back to the API server until it has synced.
+func (c *Client) StartCache(ctx context.Context) {
+	if c.cache != nil {
+		return
+	}
+
+	c.cache = newCache(c)
+	c.cache.start(ctx)
+}
+
+// CacheStatus reports the state of the client's informer cache
+func (c *Client) CacheStatus() CacheStatus {
+	return c.cache.status()
+}
+
+// listNodes returns all nodes, from the cache once it has synced
+func (c *Client) listNodes(ctx context.Context) ([]corev1.Node, error) {
+	if c.cache.hasSynced() {
+		cached, err := c.cache.nodes.List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nodes from cache: %v", err)
+		}
+		nodes := make([]corev1.Node, 0, len(cached))
+		for _, node := range cached {
+			nodes = append(nodes, *node)
+		}
+		return nodes, nil
+	}
+
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %v", err)
+	}
+	return nodes.Items, nil
+}
+
+// listPods returns the pods of a namespace ("" for all namespaces), from the cache once it has synced
+func (c *Client) listPods(ctx context.Context, namespace string) ([]corev1.Pod, error) {
+	if c.cache.hasSynced() {
+		cached, err := c.cache.pods.Pods(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pods from cache: %v", err)
+		}
+		pods := make([]corev1.Pod, 0, len(cached))
+		for _, pod := range cached {
+			pods = append(pods, *pod)
+		}
+		return pods, nil
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods: %v", err)
+	}
+	return pods.Items, nil
+}
+
+// listDeployments returns the deployments of a namespace ("" for all namespaces), from the cache once it has synced
+func (c *Client) listDeployments(ctx context.Context, namespace string) ([]appsv1.Deployment, error) {
+	if c.cache.hasSynced() {
+		cached, err := c.cache.deployments.Deployments(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments from cache: %v", err)
+		}
+		deployments := make([]appsv1.Deployment, 0, len(cached))
+		for _, deployment := range cached {
+			deployments = append(deployments, *deployment)
+		}
+		return deployments, nil
+	}
+
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+	return deployments.Items, nil
+}
+
+// listStatefulSets returns the statefulsets of a namespace ("" for all namespaces), from the cache once it has synced
+func (c *Client) listStatefulSets(ctx context.Context, namespace string) ([]appsv1.StatefulSet, error) {
+	if c.cache.hasSynced() {
+		cached, err := c.cache.statefulSets.StatefulSets(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list statefulsets from cache: %v", err)
+		}
+		statefulSets := make([]appsv1.StatefulSet, 0, len(cached))
+		for _, statefulSet := range cached {
+			statefulSets = append(statefulSets, *statefulSet)
+		}
+		return statefulSets, nil
+	}
+
+	statefulSets, err := c.clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %v", err)
+	}
+	return statefulSets.Items, nil
+}
+
+// listDaemonSets returns the daemonsets of a namespace ("" for all namespaces), from the cache once it has synced
+func (c *Client) listDaemonSets(ctx context.Context, namespace string) ([]appsv1.DaemonSet, error) {
+	if c.cache.hasSynced() {
+		cached, err := c.cache.daemonSets.DaemonSets(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list daemonsets from cache: %v", err)
+		}
+		daemonSets := make([]appsv1.DaemonSet, 0, len(cached))
+		for _, daemonSet := range cached {
+			daemonSets = append(daemonSets, *daemonSet)
+		}
+		return daemonSets, nil
+	}
+
+	daemonSets, err := c.clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	return daemonSets.Items, nil
+}
@@ -0,0 +1,159 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// CanaryLabel marks a canary deployment and its pods, the value is the name of the main deployment
+const CanaryLabel = "chatops/canary"
+
+// CanaryInfo describes a canary deployment created for an update
+type CanaryInfo struct {
+	Name       string   `json:"name"`
+	Namespace  string   `json:"namespace"`
+	Replicas   int32    `json:"replicas"`
+	Containers []string `json:"containers"` // Containers running the new image
+	Updated    bool     `json:"updated"`    // An existing canary was reused instead of created
+}
+
+// CanaryName returns the name of the canary deployment of a deployment
+func CanaryName(name string) string {
+	return name + "-canary"
+}
+
+// CreateCanary creates a copy of a deployment running the image from config next to it, or updates
+// the canary left by a previous run. The canary carries the labels of the main deployment, so its
+// pods receive a share of the service's traffic, plus CanaryLabel to tell them apart.
+func (c *Client) CreateCanary(ctx context.Context, config ServiceConfig, replicas int32) (*CanaryInfo, error) {
+	if config.Namespace == "" {
+		config.Namespace = "default"
+	}
+	if config.Image == "" && config.Version == "" {
+		return nil, fmt.Errorf("either image or version must be specified")
+	}
+
+	deployments := c.clientset.AppsV1().Deployments(config.Namespace)
+	deployment, err := deployments.Get(ctx, config.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s in namespace %s: %v", config.Name, config.Namespace, err)
+	}
+	if deployment.Labels[CanaryLabel] != "" {
+		return nil, fmt.Errorf("deployment %s is a canary itself", config.Name)
+	}
+
+	canary := newCanaryDeployment(deployment, replicas)
+	containers, err := setTemplateImage(&canary.Spec.Template, config)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CanaryInfo{
+		Name:       canary.Name,
+		Namespace:  canary.Namespace,
+		Replicas:   replicas,
+		Containers: containers,
+	}
+
+	_, err = deployments.Create(ctx, canary, metav1.CreateOptions{})
+	if err == nil {
+		return info, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create canary deployment %s: %v", canary.Name, err)
+	}
+
+	// Reuse the canary of an earlier, interrupted run
+	info.Updated = true
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := deployments.Get(ctx, canary.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if existing.Labels[CanaryLabel] != deployment.Name {
+			return fmt.Errorf("deployment %s exists and is not a canary of %s", canary.Name, deployment.Name)
+		}
+		existing.Spec.Replicas = canary.Spec.Replicas
+		existing.Spec.Template = canary.Spec.Template
+		existing.Spec.Paused = false
+		_, err = deployments.Update(ctx, existing, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update canary deployment %s: %v", canary.Name, err)
+	}
+
+	return info, nil
+}
+
+// DeleteCanary deletes the canary deployment of a deployment together with its pods.
+// A canary that does not exist is not an error.
+func (c *Client) DeleteCanary(ctx context.Context, namespace, name string) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	canaryName := CanaryName(name)
+	deployments := c.clientset.AppsV1().Deployments(namespace)
+
+	canary, err := deployments.Get(ctx, canaryName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get canary deployment %s in namespace %s: %v", canaryName, namespace, err)
+	}
+	if canary.Labels[CanaryLabel] != name {
+		return fmt.Errorf("deployment %s is not a canary of %s, refusing to delete it", canaryName, name)
+	}
+
+	propagation := metav1.DeletePropagationForeground
+	err = deployments.Delete(ctx, canaryName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete canary deployment %s in namespace %s: %v", canaryName, namespace, err)
+	}
+
+	return nil
+}
+
+// newCanaryDeployment copies a deployment under the canary name. Selector and pod labels get
+// CanaryLabel, so the canary's ReplicaSets never adopt pods of the main deployment.
+func newCanaryDeployment(deployment *appsv1.Deployment, replicas int32) *appsv1.Deployment {
+	labels := make(map[string]string, len(deployment.Labels)+1)
+	for key, value := range deployment.Labels {
+		labels[key] = value
+	}
+	labels[CanaryLabel] = deployment.Name
+
+	spec := *deployment.Spec.DeepCopy()
+	spec.Replicas = &replicas
+	spec.Paused = false
+
+	if spec.Selector == nil {
+		spec.Selector = &metav1.LabelSelector{}
+	}
+	if spec.Selector.MatchLabels == nil {
+		spec.Selector.MatchLabels = make(map[string]string)
+	}
+	spec.Selector.MatchLabels[CanaryLabel] = deployment.Name
+
+	if spec.Template.Labels == nil {
+		spec.Template.Labels = make(map[string]string)
+	}
+	spec.Template.Labels[CanaryLabel] = deployment.Name
+	delete(spec.Template.Labels, podTemplateHashLabel)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CanaryName(deployment.Name),
+			Namespace: deployment.Namespace,
+			Labels:    labels,
+		},
+		Spec: spec,
+	}
+}
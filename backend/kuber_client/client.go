@@ -4,24 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
-	"sync"
+	"sort"
+	"strconv"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
-	"k8s.io/client-go/util/retry"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 )
+
 //TODO: add retry counter to user
 
 // Client provides methods to interact with a Kubernetes cluster
 type Client struct {
-	clientset *kubernetes.Clientset
+	clientset   kubernetes.Interface
+	metrics     metricsclient.Interface // metrics.k8s.io, only served when metrics-server is installed
+	localDryRun bool                    // Evaluate dry runs client-side, the fake clientset would persist them
+	cache       *Cache                  // Informer cache serving the read paths, nil until StartCache
 }
 
 // ServiceConfig defines the configuration for Kubernetes service operations
@@ -33,251 +33,189 @@ type ServiceConfig struct {
 	Version       string `json:"version,omitempty"`
 	RevisionID    string `json:"revisionId,omitempty"`    // For specific revision rollback
 	RevisionImage string `json:"revisionImage,omitempty"` // For specific image rollback
+	Container     string `json:"container,omitempty"`     // Container or init container to update, optional for single-container pods
+	DryRun        bool   `json:"dryRun,omitempty"`        // Evaluate the change on the server without persisting it
+	Kind          string `json:"kind,omitempty"`          // Deployment (default), StatefulSet or DaemonSet
+	AdjustHPA     bool   `json:"adjustHpa,omitempty"`     // When scaling an HPA-managed workload, pin the HPA bounds instead of refusing
 }
 
-// Singleton pattern for client
-var (
-	instance *Client
-	once     sync.Once
-	initErr  error
-)
-
-// NewClient creates a new Kubernetes client or returns the existing singleton instance
+// NewClient creates a client from the in-cluster config or, outside a cluster, the default kubeconfig
 func NewClient() (*Client, error) {
-	once.Do(func() {
-		var config *rest.Config
-
-		// Try in-cluster config first (for running inside a pod)
-		config, initErr = rest.InClusterConfig()
-		if initErr != nil {
-			// Fall back to kubeconfig file
-			kubeconfig := os.Getenv("KUBECONFIG")
-			if kubeconfig == "" {
-				if home := homedir.HomeDir(); home != "" {
-					kubeconfig = filepath.Join(home, ".kube", "config")
-				} else {
-					initErr = fmt.Errorf("could not locate kubeconfig file")
-					return
-				}
-			}
+	return NewClientForCluster(ClusterConfig{})
+}
 
-			config, initErr = clientcmd.BuildConfigFromFlags("", kubeconfig)
-			if initErr != nil {
-				initErr = fmt.Errorf("failed to build config from kubeconfig: %v", initErr)
-				return
-			}
-		}
+// NewClientForCluster creates a client for the given cluster
+func NewClientForCluster(cluster ClusterConfig) (*Client, error) {
+	config, err := cluster.restConfig()
+	if err != nil {
+		return nil, err
+	}
 
-		var clientset *kubernetes.Clientset
-		clientset, initErr = kubernetes.NewForConfig(config)
-		if initErr != nil {
-			initErr = fmt.Errorf("failed to create Kubernetes client: %v", initErr)
-			return
-		}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
 
-		instance = &Client{
-			clientset: clientset,
-		}
-	})
+	metrics, err := metricsclient.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics client: %v", err)
+	}
+
+	return NewClientFromInterface(clientset, metrics), nil
+}
+
+// NewClientFromInterface creates a client on top of existing clientsets, e.g. fakes.
+// metrics may be nil, usage is then reported as unavailable.
+func NewClientFromInterface(clientset kubernetes.Interface, metrics metricsclient.Interface) *Client {
+	return &Client{
+		clientset: clientset,
+		metrics:   metrics,
+	}
+}
 
-	return instance, initErr
+// updateDeployment fetches a deployment, applies mutate to it and writes it back, retrying on conflicts.
+// With dryRun the update is only evaluated by the API server and the resulting spec diff is returned.
+func (c *Client) updateDeployment(ctx context.Context, namespace, name string, dryRun bool, mutate func(*appsv1.Deployment) error) (*MutationResult, error) {
+	return updateWorkload(ctx, dryRunAPI[*appsv1.Deployment](c, c.clientset.AppsV1().Deployments(namespace)), KindDeployment, namespace, name, dryRun,
+		func(deployment *appsv1.Deployment) interface{} { return deployment.Spec }, mutate)
 }
 
 // ScaleDeployment scales a deployment to the specified number of replicas
-func (c *Client) ScaleDeployment(ctx context.Context, config ServiceConfig) error {
+func (c *Client) ScaleDeployment(ctx context.Context, config ServiceConfig) (*MutationResult, error) {
 	if config.Namespace == "" {
 		config.Namespace = "default"
 	}
 
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		// Get the deployment
-		deployment, err := c.clientset.AppsV1().Deployments(config.Namespace).Get(ctx, config.Name, metav1.GetOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to get deployment %s in namespace %s: %v", config.Name, config.Namespace, err)
-		}
-
+	return c.updateDeployment(ctx, config.Namespace, config.Name, config.DryRun, func(deployment *appsv1.Deployment) error {
 		// Update replicas
 		deployment.Spec.Replicas = &config.Replicas
-
-		// Update the deployment
-		_, err = c.clientset.AppsV1().Deployments(config.Namespace).Update(ctx, deployment, metav1.UpdateOptions{})
-		return err
+		return nil
 	})
 }
 
 // RestartDeployment restarts a deployment by adding a timestamp annotation
-func (c *Client) RestartDeployment(ctx context.Context, config ServiceConfig) error {
+func (c *Client) RestartDeployment(ctx context.Context, config ServiceConfig) (*MutationResult, error) {
 	if config.Namespace == "" {
 		config.Namespace = "default"
 	}
 
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		// Get the deployment
-		deployment, err := c.clientset.AppsV1().Deployments(config.Namespace).Get(ctx, config.Name, metav1.GetOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to get deployment %s in namespace %s: %v", config.Name, config.Namespace, err)
-		}
+	return c.updateDeployment(ctx, config.Namespace, config.Name, config.DryRun, func(deployment *appsv1.Deployment) error {
+		restartTemplate(&deployment.Spec.Template)
+		return nil
+	})
+}
 
-		// Add/update restart annotation
-		if deployment.Spec.Template.Annotations == nil {
-			deployment.Spec.Template.Annotations = make(map[string]string)
-		}
-		// Add a timestamp annotation to force a restart
-		deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = metav1.Now().Format(metav1.RFC3339Micro)
+// PauseResult is the outcome of pausing or resuming a deployment rollout
+type PauseResult struct {
+	MutationResult
+	Skipped bool `json:"skipped"` // The deployment already was paused or resumed
+}
 
-		// Update the deployment
-		_, err = c.clientset.AppsV1().Deployments(config.Namespace).Update(ctx, deployment, metav1.UpdateOptions{})
-		return err
-	})
+// PauseDeployment pauses the rollout of a deployment. Pod template changes, including ones already
+// being rolled out, are not acted upon until the deployment is resumed. Scaling still works.
+func (c *Client) PauseDeployment(ctx context.Context, config ServiceConfig) (*PauseResult, error) {
+	return c.setDeploymentPaused(ctx, config, true)
 }
 
-// RollbackDeployment rolls back a deployment to a specified revision or previous revision
-func (c *Client) RollbackDeployment(ctx context.Context, config ServiceConfig) error {
+// ResumeDeployment resumes a paused deployment rollout
+func (c *Client) ResumeDeployment(ctx context.Context, config ServiceConfig) (*PauseResult, error) {
+	return c.setDeploymentPaused(ctx, config, false)
+}
+
+// setDeploymentPaused sets spec.paused of a deployment, skipping the update when it is already set
+func (c *Client) setDeploymentPaused(ctx context.Context, config ServiceConfig, paused bool) (*PauseResult, error) {
 	if config.Namespace == "" {
 		config.Namespace = "default"
 	}
 
-	// Get the deployment's ReplicaSets (revision history)
-	deploymentHistory, err := c.clientset.AppsV1().ReplicaSets(config.Namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("app=%s", config.Name),
+	result := &PauseResult{}
+	mutation, err := c.updateDeployment(ctx, config.Namespace, config.Name, config.DryRun, func(deployment *appsv1.Deployment) error {
+		if deployment.Spec.Paused == paused {
+			result.Skipped = true
+			return errNoChange
+		}
+		deployment.Spec.Paused = paused
+		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get deployment history: %v", err)
+		return nil, err
 	}
 
-	if len(deploymentHistory.Items) == 0 {
-		return fmt.Errorf("no revisions found for deployment %s", config.Name)
-	}
+	result.MutationResult = *mutation
+	return result, nil
+}
 
-	// Find the target revision to roll back to
-	var targetRevision *appsv1.ReplicaSet
+// RollbackDeployment rolls back a deployment to a specified revision or previous revision.
+// History is taken from the ReplicaSets owned by the deployment and the whole pod template is restored.
+func (c *Client) RollbackDeployment(ctx context.Context, config ServiceConfig) (*RollbackResult, error) {
+	if config.Namespace == "" {
+		config.Namespace = "default"
+	}
 
-	// Case 1: Specific revision ID requested
-	if config.RevisionID != "" {
-		for i := range deploymentHistory.Items {
-			// Check if the ReplicaSet name or revision annotation matches the requested revision
-			if deploymentHistory.Items[i].Name == config.RevisionID ||
-				deploymentHistory.Items[i].Annotations["deployment.kubernetes.io/revision"] == config.RevisionID {
-				targetRevision = &deploymentHistory.Items[i]
-				break
-			}
-		}
-		if targetRevision == nil {
-			return fmt.Errorf("revision %s not found for deployment %s", config.RevisionID, config.Name)
-		}
-		// Case 2: Specific image requested
-	} else if config.RevisionImage != "" || config.Version != "" {
-		targetImage := config.RevisionImage
-		if targetImage == "" && config.Version != "" {
-			// If only version is specified, need to construct the full image name
-			// This would require knowing the image name format
-			// For simplicity, we'll search for any image ending with the specified version
-			for i := range deploymentHistory.Items {
-				for _, container := range deploymentHistory.Items[i].Spec.Template.Spec.Containers {
-					if container.Image == config.Version || filepath.Ext(container.Image) == "."+config.Version {
-						targetRevision = &deploymentHistory.Items[i]
-						break
-					}
-				}
-				if targetRevision != nil {
-					break
-				}
-			}
-		} else {
-			// Search for the specific image
-			for i := range deploymentHistory.Items {
-				for _, container := range deploymentHistory.Items[i].Spec.Template.Spec.Containers {
-					if container.Image == targetImage {
-						targetRevision = &deploymentHistory.Items[i]
-						break
-					}
-				}
-				if targetRevision != nil {
-					break
-				}
-			}
-		}
-		if targetRevision == nil {
-			return fmt.Errorf("no revision found with image %s for deployment %s",
-				config.RevisionImage != "")
+	var result *RollbackResult
+	mutation, err := c.updateDeployment(ctx, config.Namespace, config.Name, config.DryRun, func(deployment *appsv1.Deployment) error {
+		// Get the deployment's ReplicaSets (revision history), newest first
+		replicaSets, foreign, err := c.getDeploymentReplicaSets(ctx, deployment)
+		if err != nil {
+			return err
 		}
-		// Case 3: Default to previous revision
-	} else if len(deploymentHistory.Items) > 1 {
-		// Sort revisions by creation timestamp (newest first)
-		// For simplicity, we're just getting the previous revision
-		for i := range deploymentHistory.Items {
-			if i == 1 { // Second newest (current is index 0)
-				targetRevision = &deploymentHistory.Items[i]
-				break
-			}
+
+		history := make([]templateRevision, 0, len(replicaSets))
+		for i := range replicaSets {
+			history = append(history, replicaSetTemplateRevision(&replicaSets[i]))
 		}
-	} else {
-		return fmt.Errorf("only one revision found, cannot rollback deployment %s", config.Name)
-	}
 
-	if targetRevision == nil {
-		return fmt.Errorf("could not find a valid revision to roll back to")
-	}
+		// Default to the newest revision older than the current one
+		currentRevision, err := strconv.ParseInt(deployment.Annotations[revisionAnnotation], 10, 64)
+		if err != nil && len(history) > 0 {
+			currentRevision = history[0].Number
+		}
 
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		// Get the deployment
-		deployment, err := c.clientset.AppsV1().Deployments(config.Namespace).Get(ctx, config.Name, metav1.GetOptions{})
+		var target *templateRevision
+		result, target, err = prepareRollback(KindDeployment, config, history, currentRevision, foreign, deployment.Spec.Template)
 		if err != nil {
-			return fmt.Errorf("failed to get deployment %s in namespace %s: %v", config.Name, config.Namespace, err)
+			return err
 		}
 
-		// Extract container specs from the target revision
-		if len(targetRevision.Spec.Template.Spec.Containers) > 0 {
-			deployment.Spec.Template.Spec.Containers = targetRevision.Spec.Template.Spec.Containers
-		}
+		applyRevision(deployment, target)
 
-		// Add an annotation to indicate this was a rollback
-		if deployment.Annotations == nil {
-			deployment.Annotations = make(map[string]string)
+		// A paused deployment would not roll out the restored template
+		if deployment.Spec.Paused {
+			deployment.Spec.Paused = false
+			result.Warnings = append(result.Warnings, "deployment was paused, the rollback resumed it")
 		}
-		deployment.Annotations["kubernetes.io/change-cause"] = fmt.Sprintf("Rollback to revision %s",
-			targetRevision.Annotations["deployment.kubernetes.io/revision"])
-
-		// Update the deployment
-		_, err = c.clientset.AppsV1().Deployments(config.Namespace).Update(ctx, deployment, metav1.UpdateOptions{})
-		return err
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.MutationResult = *mutation
+	return result, nil
 }
 
 // UpdateDeployment updates a deployment with a new image or version
-func (c *Client) UpdateDeployment(ctx context.Context, config ServiceConfig) error {
+func (c *Client) UpdateDeployment(ctx context.Context, config ServiceConfig) (*MutationResult, error) {
 	if config.Namespace == "" {
 		config.Namespace = "default"
 	}
 
 	if config.Image == "" && config.Version == "" {
-		return fmt.Errorf("either image or version must be specified")
+		return nil, fmt.Errorf("either image or version must be specified")
 	}
 
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		// Get the deployment
-		deployment, err := c.clientset.AppsV1().Deployments(config.Namespace).Get(ctx, config.Name, metav1.GetOptions{})
-		if err != nil {
-			return fmt.Errorf("failed to get deployment %s in namespace %s: %v", config.Name, config.Namespace, err)
-		}
-
-		// Update container image
-		for i := range deployment.Spec.Template.Spec.Containers {
-			if config.Image != "" {
-				deployment.Spec.Template.Spec.Containers[i].Image = config.Image
-			} else if config.Version != "" {
-				// Extract the image name and repository, update the tag
-				image := deployment.Spec.Template.Spec.Containers[i].Image
-				// Simple image tag replacement - assumes format like "image:tag"
-				// For more complex image references, you might need a more sophisticated parser
-				deployment.Spec.Template.Spec.Containers[i].Image = fmt.Sprintf("%s:%s", image[:len(image)-len(filepath.Ext(image))], config.Version)
-			}
-		}
-
-		// Update the deployment
-		_, err = c.clientset.AppsV1().Deployments(config.Namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	var containers []string
+	result, err := c.updateDeployment(ctx, config.Namespace, config.Name, config.DryRun, func(deployment *appsv1.Deployment) error {
+		var err error
+		containers, err = setTemplateImage(&deployment.Spec.Template, config)
 		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Containers = containers
+	return result, nil
 }
 
 // GetDeploymentStatus gets the status of a deployment
@@ -306,7 +244,11 @@ func (c *Client) GetDeploymentStatus(ctx context.Context, namespace, name string
 		"creationTimestamp":  deployment.CreationTimestamp,
 	}
 
-	// Convert to JSON and back to ensure it's serializable
+	return toJSONMap(status)
+}
+
+// toJSONMap converts a status map to JSON and back to ensure it's serializable
+func toJSONMap(status map[string]interface{}) (map[string]interface{}, error) {
 	jsonData, err := json.Marshal(status)
 	if err != nil {
 		return nil, fmt.Errorf("error serializing status: %v", err)
@@ -323,14 +265,14 @@ func (c *Client) GetDeploymentStatus(ctx context.Context, namespace, name string
 // GetClusterMetrics retrieves overall cluster metrics
 func (c *Client) GetClusterMetrics(ctx context.Context) (map[string]interface{}, error) {
 	// Get nodes to calculate total cluster capacity
-	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	nodes, err := c.listNodes(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get nodes: %v", err)
+		return nil, err
 	}
 
 	// Calculate total cluster capacity and allocatable resources
 	clusterMetrics := map[string]interface{}{
-		"nodes_total":    len(nodes.Items),
+		"nodes_total":    len(nodes),
 		"nodes_ready":    0,
 		"pods_total":     0,
 		"pods_running":   0,
@@ -340,7 +282,7 @@ func (c *Client) GetClusterMetrics(ctx context.Context) (map[string]interface{},
 	}
 
 	// Count ready nodes
-	for _, node := range nodes.Items {
+	for _, node := range nodes {
 		for _, condition := range node.Status.Conditions {
 			if condition.Type == "Ready" && condition.Status == "True" {
 				clusterMetrics["nodes_ready"] = clusterMetrics["nodes_ready"].(int) + 1
@@ -350,15 +292,15 @@ func (c *Client) GetClusterMetrics(ctx context.Context) (map[string]interface{},
 	}
 
 	// Get pod information
-	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	pods, err := c.listPods(ctx, "")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pods: %v", err)
+		return nil, err
 	}
 
-	clusterMetrics["pods_total"] = len(pods.Items)
+	clusterMetrics["pods_total"] = len(pods)
 
 	// Count pods by phase
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
 		switch pod.Status.Phase {
 		case "Running":
 			clusterMetrics["pods_running"] = clusterMetrics["pods_running"].(int) + 1
@@ -374,36 +316,66 @@ func (c *Client) GetClusterMetrics(ctx context.Context) (map[string]interface{},
 	return clusterMetrics, nil
 }
 
-// GetNodeMetrics retrieves metrics for all nodes or a specific node
-func (c *Client) GetNodeMetrics(ctx context.Context, nodeName string) ([]map[string]interface{}, error) {
-	// Set up list options
-	listOptions := metav1.ListOptions{}
+// GetNodeMetrics retrieves metrics for all nodes or a specific node, filtered, sorted and paged by query
+func (c *Client) GetNodeMetrics(ctx context.Context, nodeName string, query ListQuery) ([]map[string]interface{}, *ListPage, error) {
+	// Get nodes
+	nodes, err := c.listNodes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if nodeName != "" {
-		listOptions.FieldSelector = fmt.Sprintf("metadata.name=%s", nodeName)
+		named := nodes[:0]
+		for _, node := range nodes {
+			if node.Name == nodeName {
+				named = append(named, node)
+			}
+		}
+		nodes = named
 	}
 
-	// Get nodes
-	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, listOptions)
+	nodes, page, err := applyListQuery(nodes, query, nodeListSchema, "", nodeListEntry)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get nodes: %v", err)
+		return nil, nil, err
 	}
 
+	// Requests and limits are summed from the pods scheduled on each node
+	pods, err := c.listPods(ctx, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	scheduled := nodePods(pods)
+
+	// Live usage is optional, the nodes are still reported without metrics-server
+	usage, usageErr := c.listNodeUsage(ctx)
+
 	var nodeMetrics []map[string]interface{}
 
-	for _, node := range nodes.Items {
+	for i, node := range nodes {
 		nodeInfo := map[string]interface{}{
-			"name":        node.Name,
-			"status":      getNodeStatus(node),
-			"allocatable": node.Status.Allocatable,
-			"capacity":    node.Status.Capacity,
-			"labels":      node.Labels,
-			"conditions":  node.Status.Conditions,
+			"name":           node.Name,
+			"status":         getNodeStatus(node),
+			"unschedulable":  node.Spec.Unschedulable,
+			"kubeletVersion": node.Status.NodeInfo.KubeletVersion,
+			"pressure":       nodePressure(&nodes[i]),
+			"taints":         nodeTaints(&nodes[i]),
+			"allocated":      nodeAllocated(&nodes[i], scheduled[node.Name]),
+			"allocatable":    resourceListToMap(node.Status.Allocatable),
+			"capacity":       resourceListToMap(node.Status.Capacity),
+			"labels":         node.Labels,
+			"conditions":     node.Status.Conditions,
+		}
+
+		if usageErr != nil {
+			nodeInfo["usageUnavailable"] = usageErr.Error()
+		} else if used, ok := usage[node.Name]; ok {
+			nodeInfo["usage"] = nodeUsage(used, node.Status.Allocatable)
 		}
 
 		nodeMetrics = append(nodeMetrics, nodeInfo)
 	}
 
-	return nodeMetrics, nil
+	return nodeMetrics, page, nil
 }
 
 // Helper function to determine node status
@@ -420,25 +392,34 @@ func getNodeStatus(node corev1.Node) string {
 	return "Unknown"
 }
 
-// GetPodMetrics retrieves metrics for all pods or pods in a specific namespace
-func (c *Client) GetPodMetrics(ctx context.Context, namespace string) ([]map[string]interface{}, error) {
+// GetPodMetrics retrieves metrics for all pods or pods in a specific namespace, filtered, sorted and paged by query
+func (c *Client) GetPodMetrics(ctx context.Context, namespace string, query ListQuery, detailed bool) ([]map[string]interface{}, *ListPage, error) {
 	// Get pods
-	var pods *corev1.PodList
-	var err error
-
-	if namespace != "" {
-		pods, err = c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
-	} else {
-		pods, err = c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	pods, err := c.listPods(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	pods, page, err := applyListQuery(pods, query, podListSchema, namespace, podListEntry)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pods: %v", err)
+		return nil, nil, err
+	}
+
+	// Container statuses and owners are only looked up for the returned page
+	var details []PodDetail
+	if detailed {
+		details, err = c.podDetails(ctx, pods)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
+	// Live usage is optional, the pods are still reported without metrics-server
+	usage, usageErr := c.listPodUsage(ctx, namespace)
+
 	var podMetrics []map[string]interface{}
 
-	for _, pod := range pods.Items {
+	for i, pod := range pods {
 		podInfo := map[string]interface{}{
 			"name":       pod.Name,
 			"namespace":  pod.Namespace,
@@ -447,53 +428,111 @@ func (c *Client) GetPodMetrics(ctx context.Context, namespace string) ([]map[str
 			"podIP":      pod.Status.PodIP,
 			"startTime":  pod.Status.StartTime,
 			"containers": len(pod.Spec.Containers),
+			"restarts":   podRestarts(&pods[i]),
+		}
+
+		if usageErr != nil {
+			podInfo["usageUnavailable"] = usageErr.Error()
+		} else if used, ok := usage[pod.Namespace+"/"+pod.Name]; ok {
+			podInfo["usage"] = podUsage(used, &pods[i])
+		}
+
+		if detailed {
+			podInfo["detail"] = &details[i]
 		}
 
 		podMetrics = append(podMetrics, podInfo)
 	}
 
-	return podMetrics, nil
+	return podMetrics, page, nil
 }
 
-// DeploymentInfo holds basic deployment information
+// DeploymentInfo holds basic deployment, statefulset or daemonset information
 type DeploymentInfo struct {
-	Name               string
-	Namespace          string
-	Replicas           int32
-	AvailableReplicas  int32
-	ReadyReplicas      int32
-	UpdatedReplicas    int32
-	CreationTimestamp  metav1.Time
+	Kind              string
+	Name              string
+	Namespace         string
+	Labels            map[string]string
+	Status            string // Ready, Degraded, Unavailable or ScaledDown, see workloadStatus
+	Replicas          int32
+	AvailableReplicas int32
+	ReadyReplicas     int32
+	UpdatedReplicas   int32
+	CreationTimestamp metav1.Time
 }
 
 // ListDeployments retrieves all deployments or deployments in a specific namespace
 func (c *Client) ListDeployments(ctx context.Context, namespace string) ([]DeploymentInfo, error) {
-	var deployments *appsv1.DeploymentList
-	var err error
-
-	if namespace != "" {
-		deployments, err = c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
-	} else {
-		deployments, err = c.clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
-	}
-
+	deployments, err := c.listDeployments(ctx, namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list deployments: %v", err)
+		return nil, err
 	}
 
-	deploymentInfos := make([]DeploymentInfo, 0, len(deployments.Items))
-	for _, deployment := range deployments.Items {
+	deploymentInfos := make([]DeploymentInfo, 0, len(deployments))
+	for _, deployment := range deployments {
 		info := DeploymentInfo{
+			Kind:              KindDeployment,
 			Name:              deployment.Name,
 			Namespace:         deployment.Namespace,
-			Replicas:          *deployment.Spec.Replicas,
+			Labels:            deployment.Labels,
+			Replicas:          derefReplicas(deployment.Spec.Replicas),
 			AvailableReplicas: deployment.Status.AvailableReplicas,
 			ReadyReplicas:     deployment.Status.ReadyReplicas,
 			UpdatedReplicas:   deployment.Status.UpdatedReplicas,
 			CreationTimestamp: deployment.CreationTimestamp,
 		}
+		info.Status = workloadStatus(info.Replicas, info.ReadyReplicas)
 		deploymentInfos = append(deploymentInfos, info)
 	}
 
 	return deploymentInfos, nil
 }
+
+// NamespaceMetrics counts the pods of a namespace by phase
+type NamespaceMetrics struct {
+	Name      string `json:"name"`
+	PodCount  int    `json:"podCount"`
+	Running   int    `json:"running"`
+	Pending   int    `json:"pending"`
+	Failed    int    `json:"failed"`
+	Succeeded int    `json:"succeeded"`
+}
+
+// GetNamespaceMetrics groups all pods by namespace and counts them by phase, sorted by namespace name
+func (c *Client) GetNamespaceMetrics(ctx context.Context) ([]NamespaceMetrics, error) {
+	pods, err := c.listPods(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceMap := make(map[string]*NamespaceMetrics)
+	for _, pod := range pods {
+		metrics, exists := namespaceMap[pod.Namespace]
+		if !exists {
+			metrics = &NamespaceMetrics{Name: pod.Namespace}
+			namespaceMap[pod.Namespace] = metrics
+		}
+
+		metrics.PodCount++
+		switch pod.Status.Phase {
+		case corev1.PodRunning:
+			metrics.Running++
+		case corev1.PodPending:
+			metrics.Pending++
+		case corev1.PodFailed:
+			metrics.Failed++
+		case corev1.PodSucceeded:
+			metrics.Succeeded++
+		}
+	}
+
+	namespaceMetrics := make([]NamespaceMetrics, 0, len(namespaceMap))
+	for _, metrics := range namespaceMap {
+		namespaceMetrics = append(namespaceMetrics, *metrics)
+	}
+	sort.Slice(namespaceMetrics, func(i, j int) bool {
+		return namespaceMetrics[i].Name < namespaceMetrics[j].Name
+	})
+
+	return namespaceMetrics, nil
+}
@@ -0,0 +1,435 @@
+package kuberclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Kinds of configuration objects
+const (
+	KindConfigMap = "ConfigMap"
+	KindSecret    = "Secret"
+)
+
+var (
+	// ErrInvalidConfigPatch is returned when a patch sets an invalid key or names no key at all
+	ErrInvalidConfigPatch = errors.New("invalid config patch")
+	// ErrImmutableConfig is returned when patching a ConfigMap or Secret marked immutable
+	ErrImmutableConfig = errors.New("config object is immutable")
+)
+
+var (
+	configMapListSchema = listSchema{resource: "configmaps", fields: []string{"metadata.name", "metadata.namespace"}}
+	secretListSchema    = listSchema{resource: "secrets", fields: []string{"metadata.name", "metadata.namespace", "type"}}
+)
+
+// ConfigObject describes a ConfigMap or Secret. Data is only set for a single object, and the
+// values of a Secret are redacted unless they were explicitly revealed.
+type ConfigObject struct {
+	Kind              string            `json:"kind"`
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	Type              string            `json:"type,omitempty"` // Secret type, e.g. Opaque
+	Immutable         bool              `json:"immutable"`
+	Keys              []string          `json:"keys"`
+	Data              map[string]string `json:"data,omitempty"`
+	Redacted          bool              `json:"redacted,omitempty"`
+	UsedBy            []string          `json:"usedBy,omitempty"` // Deployments mounting or referencing the object
+	Labels            map[string]string `json:"labels,omitempty"`
+	CreationTimestamp metav1.Time       `json:"creationTimestamp"`
+}
+
+// ConfigPatch sets and removes keys of a ConfigMap or Secret
+type ConfigPatch struct {
+	Namespace string
+	Name      string
+	Kind      string // ConfigMap or Secret
+	Set       map[string]string
+	Remove    []string
+	DryRun    bool
+}
+
+// ConfigPatchResult is the outcome of a ConfigPatch. Changes of a Secret are redacted.
+type ConfigPatchResult struct {
+	MutationResult
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Removed []string `json:"removed"`
+}
+
+// Changed reports whether the patch changed any key
+func (r *ConfigPatchResult) Changed() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Removed) > 0
+}
+
+// ListConfigObjects lists the ConfigMaps or Secrets of a namespace ("" for all namespaces) without their values
+func (c *Client) ListConfigObjects(ctx context.Context, namespace, kind string, query ListQuery) ([]ConfigObject, *ListPage, error) {
+	var objects []ConfigObject
+	schema := configMapListSchema
+
+	switch kind {
+	case KindConfigMap:
+		configMaps, err := c.clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list configmaps: %v", err)
+		}
+		for i := range configMaps.Items {
+			objects = append(objects, configMapObject(&configMaps.Items[i]))
+		}
+	case KindSecret:
+		schema = secretListSchema
+		secrets, err := c.clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list secrets: %v", err)
+		}
+		for i := range secrets.Items {
+			objects = append(objects, secretObject(&secrets.Items[i], false))
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported config kind %q", kind)
+	}
+
+	for i := range objects {
+		objects[i].Data = nil
+		objects[i].Redacted = false
+	}
+
+	return applyListQuery(objects, query, schema, namespace, configListEntry)
+}
+
+// GetConfigObject returns a ConfigMap or Secret with its values and the deployments using it.
+// Secret values are redacted unless reveal is set.
+func (c *Client) GetConfigObject(ctx context.Context, namespace, kind, name string, reveal bool) (*ConfigObject, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	var object ConfigObject
+	switch kind {
+	case KindConfigMap:
+		configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configmap %s in namespace %s: %v", name, namespace, err)
+		}
+		object = configMapObject(configMap)
+	case KindSecret:
+		secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get secret %s in namespace %s: %v", name, namespace, err)
+		}
+		object = secretObject(secret, reveal)
+	default:
+		return nil, fmt.Errorf("unsupported config kind %q", kind)
+	}
+
+	usedBy, err := c.ConfigConsumers(ctx, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	object.UsedBy = usedBy
+
+	return &object, nil
+}
+
+// PatchConfigObject sets and removes keys of a ConfigMap or Secret, retrying on conflicts
+func (c *Client) PatchConfigObject(ctx context.Context, patch ConfigPatch) (*ConfigPatchResult, error) {
+	if patch.Namespace == "" {
+		patch.Namespace = "default"
+	}
+	if len(patch.Set) == 0 && len(patch.Remove) == 0 {
+		return nil, fmt.Errorf("%w: set or remove at least one key", ErrInvalidConfigPatch)
+	}
+	for key := range patch.Set {
+		if problems := validation.IsConfigMapKey(key); len(problems) > 0 {
+			return nil, fmt.Errorf("%w: key %q: %s", ErrInvalidConfigPatch, key, strings.Join(problems, ", "))
+		}
+	}
+	for _, key := range patch.Remove {
+		if _, ok := patch.Set[key]; ok {
+			return nil, fmt.Errorf("%w: key %q is both set and removed", ErrInvalidConfigPatch, key)
+		}
+	}
+
+	result := &ConfigPatchResult{}
+	var mutation *MutationResult
+	var err error
+
+	switch patch.Kind {
+	case KindConfigMap:
+		api := dryRunAPI[*corev1.ConfigMap](c, c.clientset.CoreV1().ConfigMaps(patch.Namespace))
+		mutation, err = updateWorkload(ctx, api, KindConfigMap, patch.Namespace, patch.Name, patch.DryRun,
+			func(configMap *corev1.ConfigMap) interface{} { return configMapObject(configMap).Data },
+			func(configMap *corev1.ConfigMap) error {
+				if configMap.Immutable != nil && *configMap.Immutable {
+					return fmt.Errorf("%w: configmap %s", ErrImmutableConfig, patch.Name)
+				}
+				// A key is either text or binary, binary values can only be removed
+				for key := range patch.Set {
+					if _, ok := configMap.BinaryData[key]; ok {
+						return fmt.Errorf("%w: key %q of configmap %s holds binary data", ErrInvalidConfigPatch, key, patch.Name)
+					}
+				}
+				if configMap.Data == nil {
+					configMap.Data = make(map[string]string)
+				}
+				values := make(map[string][]byte, len(configMap.Data))
+				for key, value := range configMap.Data {
+					values[key] = []byte(value)
+				}
+				applyConfigPatch(values, patch, result)
+				configMap.Data = make(map[string]string, len(values))
+				for key, value := range values {
+					configMap.Data[key] = string(value)
+				}
+				for _, key := range patch.Remove {
+					if _, ok := configMap.BinaryData[key]; ok {
+						delete(configMap.BinaryData, key)
+						result.Removed = append(result.Removed, key)
+					}
+				}
+				sort.Strings(result.Removed)
+				return configPatchChanged(result)
+			})
+	case KindSecret:
+		api := dryRunAPI[*corev1.Secret](c, c.clientset.CoreV1().Secrets(patch.Namespace))
+		mutation, err = updateWorkload(ctx, api, KindSecret, patch.Namespace, patch.Name, patch.DryRun,
+			func(secret *corev1.Secret) interface{} { return redactValues(secret.Data) },
+			func(secret *corev1.Secret) error {
+				if secret.Immutable != nil && *secret.Immutable {
+					return fmt.Errorf("%w: secret %s", ErrImmutableConfig, patch.Name)
+				}
+				if secret.Data == nil {
+					secret.Data = make(map[string][]byte)
+				}
+				applyConfigPatch(secret.Data, patch, result)
+				return configPatchChanged(result)
+			})
+	default:
+		return nil, fmt.Errorf("unsupported config kind %q", patch.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.MutationResult = *mutation
+	return result, nil
+}
+
+// applyConfigPatch applies a patch to the values of a ConfigMap or Secret and records the changed keys
+func applyConfigPatch(values map[string][]byte, patch ConfigPatch, result *ConfigPatchResult) {
+	// A retry after a conflict starts over
+	result.Added, result.Updated, result.Removed = []string{}, []string{}, []string{}
+
+	for key, value := range patch.Set {
+		current, exists := values[key]
+		switch {
+		case !exists:
+			result.Added = append(result.Added, key)
+		case string(current) != value:
+			result.Updated = append(result.Updated, key)
+		default:
+			continue
+		}
+		values[key] = []byte(value)
+	}
+	for _, key := range patch.Remove {
+		if _, exists := values[key]; exists {
+			delete(values, key)
+			result.Removed = append(result.Removed, key)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Removed)
+}
+
+// configPatchChanged skips the update of a patch that changes nothing
+func configPatchChanged(result *ConfigPatchResult) error {
+	if !result.Changed() {
+		return errNoChange
+	}
+	return nil
+}
+
+// ConfigConsumers returns the deployments of a namespace whose pods mount or reference a ConfigMap or Secret
+func (c *Client) ConfigConsumers(ctx context.Context, namespace, kind, name string) ([]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	deployments, err := c.listDeployments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var consumers []string
+	for _, deployment := range deployments {
+		if templateReferences(deployment.Spec.Template.Spec, kind, name) {
+			consumers = append(consumers, deployment.Name)
+		}
+	}
+	sort.Strings(consumers)
+	return consumers, nil
+}
+
+// templateReferences reports whether a pod spec uses a ConfigMap or Secret as a volume, a projected volume
+// source, in env or envFrom of any container, or, for a Secret, as an image pull secret
+func templateReferences(spec corev1.PodSpec, kind, name string) bool {
+	isConfigMap := kind == KindConfigMap
+
+	for _, volume := range spec.Volumes {
+		if isConfigMap && volume.ConfigMap != nil && volume.ConfigMap.Name == name {
+			return true
+		}
+		if !isConfigMap && volume.Secret != nil && volume.Secret.SecretName == name {
+			return true
+		}
+		if volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if isConfigMap && source.ConfigMap != nil && source.ConfigMap.Name == name {
+				return true
+			}
+			if !isConfigMap && source.Secret != nil && source.Secret.Name == name {
+				return true
+			}
+		}
+	}
+
+	if !isConfigMap {
+		for _, pullSecret := range spec.ImagePullSecrets {
+			if pullSecret.Name == name {
+				return true
+			}
+		}
+	}
+
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, container := range containers {
+			for _, envFrom := range container.EnvFrom {
+				if isConfigMap && envFrom.ConfigMapRef != nil && envFrom.ConfigMapRef.Name == name {
+					return true
+				}
+				if !isConfigMap && envFrom.SecretRef != nil && envFrom.SecretRef.Name == name {
+					return true
+				}
+			}
+			for _, env := range container.Env {
+				if env.ValueFrom == nil {
+					continue
+				}
+				if isConfigMap && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == name {
+					return true
+				}
+				if !isConfigMap && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// configMapObject describes a ConfigMap, binary values are summarized
+func configMapObject(configMap *corev1.ConfigMap) ConfigObject {
+	data := make(map[string]string, len(configMap.Data)+len(configMap.BinaryData))
+	for key, value := range configMap.Data {
+		data[key] = value
+	}
+	for key, value := range configMap.BinaryData {
+		data[key] = fmt.Sprintf("<binary, %d bytes>", len(value))
+	}
+
+	return ConfigObject{
+		Kind:              KindConfigMap,
+		Name:              configMap.Name,
+		Namespace:         configMap.Namespace,
+		Immutable:         configMap.Immutable != nil && *configMap.Immutable,
+		Keys:              sortedKeys(data),
+		Data:              data,
+		Labels:            configMap.Labels,
+		CreationTimestamp: configMap.CreationTimestamp,
+	}
+}
+
+// secretObject describes a Secret. Unless reveal is set its values are replaced by their size,
+// revealed values that are not text are base64 encoded.
+func secretObject(secret *corev1.Secret, reveal bool) ConfigObject {
+	data := redactValues(secret.Data)
+	if reveal {
+		for key, value := range secret.Data {
+			if utf8.Valid(value) {
+				data[key] = string(value)
+			} else {
+				data[key] = "base64:" + base64.StdEncoding.EncodeToString(value)
+			}
+		}
+	}
+
+	return ConfigObject{
+		Kind:              KindSecret,
+		Name:              secret.Name,
+		Namespace:         secret.Namespace,
+		Type:              string(secret.Type),
+		Immutable:         secret.Immutable != nil && *secret.Immutable,
+		Keys:              sortedKeys(data),
+		Data:              data,
+		Redacted:          !reveal,
+		Labels:            secret.Labels,
+		CreationTimestamp: secret.CreationTimestamp,
+	}
+}
+
+// redactValues replaces secret values by their size
+func redactValues(values map[string][]byte) map[string]string {
+	redacted := make(map[string]string, len(values))
+	for key, value := range values {
+		redacted[key] = fmt.Sprintf("<redacted, %d bytes>", len(value))
+	}
+	return redacted
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// configListEntry describes a ConfigMap or Secret for a ListQuery, a Secret has its type as status
+func configListEntry(object ConfigObject) listEntry {
+	entryFields := fields.Set{
+		"metadata.name":      object.Name,
+		"metadata.namespace": object.Namespace,
+	}
+	var statuses []string
+	if object.Kind == KindSecret {
+		entryFields["type"] = object.Type
+		statuses = []string{object.Type}
+	}
+
+	return listEntry{
+		name:      object.Name,
+		namespace: object.Namespace,
+		labels:    object.Labels,
+		fields:    entryFields,
+		statuses:  statuses,
+		created:   object.CreationTimestamp.Time,
+	}
+}
@@ -0,0 +1,235 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyConfigPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		patch       ConfigPatch
+		wantValues  map[string]string
+		wantAdded   []string
+		wantUpdated []string
+		wantRemoved []string
+	}{
+		{
+			name:        "add, update and remove",
+			patch:       ConfigPatch{Set: map[string]string{"LOG_LEVEL": "debug", "TIMEOUT": "30s"}, Remove: []string{"PORT"}},
+			wantValues:  map[string]string{"LOG_LEVEL": "debug", "TIMEOUT": "30s"},
+			wantAdded:   []string{"TIMEOUT"},
+			wantUpdated: []string{"LOG_LEVEL"},
+			wantRemoved: []string{"PORT"},
+		},
+		{
+			name:        "unchanged value and missing key are no change",
+			patch:       ConfigPatch{Set: map[string]string{"LOG_LEVEL": "info"}, Remove: []string{"TIMEOUT"}},
+			wantValues:  map[string]string{"LOG_LEVEL": "info", "PORT": "8080"},
+			wantAdded:   []string{},
+			wantUpdated: []string{},
+			wantRemoved: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string][]byte{"LOG_LEVEL": []byte("info"), "PORT": []byte("8080")}
+			// A retry after a conflict reuses the result
+			result := &ConfigPatchResult{Added: []string{"stale"}}
+			applyConfigPatch(values, tt.patch, result)
+
+			got := make(map[string]string, len(values))
+			for key, value := range values {
+				got[key] = string(value)
+			}
+			if !reflect.DeepEqual(got, tt.wantValues) {
+				t.Errorf("got values %v, want %v", got, tt.wantValues)
+			}
+			if !reflect.DeepEqual(result.Added, tt.wantAdded) || !reflect.DeepEqual(result.Updated, tt.wantUpdated) || !reflect.DeepEqual(result.Removed, tt.wantRemoved) {
+				t.Errorf("got added %v, updated %v, removed %v, want %v, %v, %v",
+					result.Added, result.Updated, result.Removed, tt.wantAdded, tt.wantUpdated, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestRedactValues(t *testing.T) {
+	got := redactValues(map[string][]byte{"password": []byte("hunter22"), "empty": {}, "key.der": {0x30, 0x82, 0x01}})
+	want := map[string]string{"password": "<redacted, 8 bytes>", "empty": "<redacted, 0 bytes>", "key.der": "<redacted, 3 bytes>"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := redactValues(nil); got == nil || len(got) != 0 {
+		t.Errorf("got %v for no values, want an empty map", got)
+	}
+}
+
+func TestPatchConfigObject(t *testing.T) {
+	immutable := true
+	configMap := func() *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "default"},
+			Data:       map[string]string{"LOG_LEVEL": "info", "PORT": "8080"},
+			BinaryData: map[string][]byte{"ca.der": {0x30, 0x82}},
+		}
+	}
+	frozen := configMap()
+	frozen.Name = "frozen"
+	frozen.Immutable = &immutable
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-secrets", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("hunter22")},
+	}
+
+	tests := []struct {
+		name           string
+		patch          ConfigPatch
+		wantErr        error
+		wantAdded      []string
+		wantUpdated    []string
+		wantRemoved    []string
+		wantChanges    []SpecChange
+		wantData       map[string]string // Stored ConfigMap data, or Secret values as text
+		wantBinaryKeys []string
+	}{
+		{
+			name:           "configmap keys are set and removed",
+			patch:          ConfigPatch{Kind: KindConfigMap, Name: "web-config", Set: map[string]string{"LOG_LEVEL": "debug", "TIMEOUT": "30s"}, Remove: []string{"PORT"}},
+			wantAdded:      []string{"TIMEOUT"},
+			wantUpdated:    []string{"LOG_LEVEL"},
+			wantRemoved:    []string{"PORT"},
+			wantData:       map[string]string{"LOG_LEVEL": "debug", "TIMEOUT": "30s"},
+			wantBinaryKeys: []string{"ca.der"},
+		},
+		{
+			name:           "binary configmap key cannot be set",
+			patch:          ConfigPatch{Kind: KindConfigMap, Name: "web-config", Set: map[string]string{"ca.der": "text"}},
+			wantErr:        ErrInvalidConfigPatch,
+			wantData:       map[string]string{"LOG_LEVEL": "info", "PORT": "8080"},
+			wantBinaryKeys: []string{"ca.der"},
+		},
+		{
+			name:        "binary configmap key is removed",
+			patch:       ConfigPatch{Kind: KindConfigMap, Name: "web-config", Remove: []string{"ca.der", "PORT"}},
+			wantAdded:   []string{},
+			wantUpdated: []string{},
+			wantRemoved: []string{"PORT", "ca.der"},
+			wantData:    map[string]string{"LOG_LEVEL": "info"},
+		},
+		{
+			name:           "dry run shows the changes and stores nothing",
+			patch:          ConfigPatch{Kind: KindConfigMap, Name: "web-config", Set: map[string]string{"PORT": "9090"}, Remove: []string{"ca.der"}, DryRun: true},
+			wantAdded:      []string{},
+			wantUpdated:    []string{"PORT"},
+			wantRemoved:    []string{"ca.der"},
+			wantChanges:    []SpecChange{{Path: "PORT", Before: "8080", After: "9090"}, {Path: "ca.der", Before: "<binary, 2 bytes>"}},
+			wantData:       map[string]string{"LOG_LEVEL": "info", "PORT": "8080"},
+			wantBinaryKeys: []string{"ca.der"},
+		},
+		{
+			name:           "patch without change",
+			patch:          ConfigPatch{Kind: KindConfigMap, Name: "web-config", Set: map[string]string{"PORT": "8080"}},
+			wantAdded:      []string{},
+			wantUpdated:    []string{},
+			wantRemoved:    []string{},
+			wantData:       map[string]string{"LOG_LEVEL": "info", "PORT": "8080"},
+			wantBinaryKeys: []string{"ca.der"},
+		},
+		{
+			name:    "immutable configmap",
+			patch:   ConfigPatch{Kind: KindConfigMap, Name: "frozen", Set: map[string]string{"PORT": "9090"}},
+			wantErr: ErrImmutableConfig,
+		},
+		{
+			name:    "invalid key",
+			patch:   ConfigPatch{Kind: KindConfigMap, Name: "web-config", Set: map[string]string{"log level": "debug"}},
+			wantErr: ErrInvalidConfigPatch,
+		},
+		{
+			name:    "key both set and removed",
+			patch:   ConfigPatch{Kind: KindConfigMap, Name: "web-config", Set: map[string]string{"PORT": "9090"}, Remove: []string{"PORT"}},
+			wantErr: ErrInvalidConfigPatch,
+		},
+		{
+			name:        "secret dry run changes are redacted",
+			patch:       ConfigPatch{Kind: KindSecret, Name: "web-secrets", Set: map[string]string{"password": "correct horse"}, DryRun: true},
+			wantAdded:   []string{},
+			wantUpdated: []string{"password"},
+			wantRemoved: []string{},
+			wantChanges: []SpecChange{{Path: "password", Before: "<redacted, 8 bytes>", After: "<redacted, 13 bytes>"}},
+			wantData:    map[string]string{"password": "hunter22"},
+		},
+		{
+			name:        "secret value is set",
+			patch:       ConfigPatch{Kind: KindSecret, Name: "web-secrets", Set: map[string]string{"password": "correct horse"}},
+			wantAdded:   []string{},
+			wantUpdated: []string{"password"},
+			wantRemoved: []string{},
+			wantData:    map[string]string{"password": "correct horse"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, clientset := newTestClient(configMap(), frozen, secret.DeepCopy())
+
+			patch := tt.patch
+			patch.Namespace = "default"
+			result, err := client.PatchConfigObject(context.Background(), patch)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got result %+v and error %v, want %v", result, err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("PatchConfigObject: %v", err)
+			} else {
+				if !reflect.DeepEqual(result.Added, tt.wantAdded) || !reflect.DeepEqual(result.Updated, tt.wantUpdated) || !reflect.DeepEqual(result.Removed, tt.wantRemoved) {
+					t.Errorf("got added %v, updated %v, removed %v, want %v, %v, %v",
+						result.Added, result.Updated, result.Removed, tt.wantAdded, tt.wantUpdated, tt.wantRemoved)
+				}
+				if !reflect.DeepEqual(result.Changes, tt.wantChanges) {
+					t.Errorf("got changes %+v, want %+v", result.Changes, tt.wantChanges)
+				}
+			}
+			if tt.wantData == nil {
+				return
+			}
+
+			if patch.Kind == KindSecret {
+				stored, err := clientset.CoreV1().Secrets("default").Get(context.Background(), patch.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := make(map[string]string, len(stored.Data))
+				for key, value := range stored.Data {
+					got[key] = string(value)
+				}
+				if !reflect.DeepEqual(got, tt.wantData) {
+					t.Errorf("stored values %v, want %v", got, tt.wantData)
+				}
+				return
+			}
+
+			stored, err := clientset.CoreV1().ConfigMaps("default").Get(context.Background(), patch.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(stored.Data, tt.wantData) {
+				t.Errorf("stored data %v, want %v", stored.Data, tt.wantData)
+			}
+			var binaryKeys []string
+			for key := range stored.BinaryData {
+				binaryKeys = append(binaryKeys, key)
+			}
+			if !reflect.DeepEqual(binaryKeys, tt.wantBinaryKeys) {
+				t.Errorf("stored binary keys %v, want %v", binaryKeys, tt.wantBinaryKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Severities of a finding
+const (
+	SeverityCritical = "critical" // Pods are down or cannot start
+	SeverityWarning  = "warning"  // Pods run but are degraded or restarting
+	SeverityInfo     = "info"     // Worth knowing, not a fault
+)
+
+// Types of findings
+const (
+	FindingCrashLoop        = "CrashLoopBackOff"
+	FindingOOMKilled        = "OOMKilled"
+	FindingImagePull        = "ImagePullBackOff"
+	FindingContainerConfig  = "CreateContainerConfigError"
+	FindingUnschedulable    = "Unschedulable"
+	FindingReadinessProbe   = "ReadinessProbeFailed"
+	FindingLivenessProbe    = "LivenessProbeFailed"
+	FindingRestarts         = "Restarts"
+	FindingProgressDeadline = "ProgressDeadlineExceeded"
+	FindingReplicaFailure   = "ReplicaFailure"
+	FindingPaused           = "Paused"
+	FindingScaledToZero     = "ScaledToZero"
+	FindingUnavailable      = "Unavailable"
+)
+
+const (
+	maxDiagnosisEvents     = 10
+	restartsWorthReporting = 3 // Restarts of a container that are reported without other symptoms
+)
+
+// Finding is one problem found by a diagnosis. Findings of the same type, container and reason or exit code
+// are merged across pods, their message describes the first pod and the counts cover all of them.
+type Finding struct {
+	Rank          int      `json:"rank"`
+	Severity      string   `json:"severity"`
+	Type          string   `json:"type"`
+	Container     string   `json:"container,omitempty"`
+	Pods          []string `json:"pods,omitempty"`
+	Message       string   `json:"message"`
+	ExitCode      *int32   `json:"exitCode,omitempty"`
+	Restarts      int32    `json:"restarts,omitempty"`      // Highest restart count of the container among the pods
+	ProbeFailures int32    `json:"probeFailures,omitempty"` // Probe failure events summed over the pods
+	Suggestion    string   `json:"suggestion"`
+	Command       string   `json:"command,omitempty"` // Next command to run
+	score         int
+	reason        string // Waiting reason or exit code the finding is merged on besides type and container
+}
+
+// Diagnosis is the result of inspecting a deployment, its pods and their recent events
+type Diagnosis struct {
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace"`
+	Healthy   bool          `json:"healthy"` // No critical or warning finding
+	Rollout   RolloutStatus `json:"rollout"`
+	Pods      int           `json:"pods"`
+	Findings  []Finding     `json:"findings"`
+	Events    []EventInfo   `json:"events"` // Most recent warning events of the deployment and its pods
+}
+
+// diagnosis collects findings, merging repeated ones
+type diagnosis struct {
+	namespace string
+	name      string
+	findings  []*Finding
+}
+
+// add records a finding for a pod, or adds the pod to the same finding found earlier
+func (d *diagnosis) add(finding Finding, pod string) {
+	for _, existing := range d.findings {
+		if existing.Type == finding.Type && existing.Container == finding.Container && existing.reason == finding.reason {
+			if pod != "" {
+				existing.Pods = append(existing.Pods, pod)
+			}
+			existing.Restarts = max(existing.Restarts, finding.Restarts)
+			existing.ProbeFailures += finding.ProbeFailures
+			return
+		}
+	}
+	if pod != "" {
+		finding.Pods = []string{pod}
+	}
+	d.findings = append(d.findings, &finding)
+}
+
+// kubectl formats a kubectl command against the namespace of the diagnosis
+func (d *diagnosis) kubectl(format string, args ...interface{}) string {
+	return fmt.Sprintf("kubectl -n %s ", d.namespace) + fmt.Sprintf(format, args...)
+}
+
+// DiagnoseDeployment inspects a deployment, the container states of its pods, their last terminations and
+// the recent warning events, and returns the problems found ranked by severity, each with a suggested next command
+func (c *Client) DiagnoseDeployment(ctx context.Context, namespace, name string) (*Diagnosis, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s in namespace %s: %v", name, namespace, err)
+	}
+
+	replicaSets, _, err := c.getDeploymentReplicaSets(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[types.UID]bool, len(replicaSets))
+	for i := range replicaSets {
+		owners[replicaSets[i].UID] = true
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on deployment %s: %v", name, err)
+	}
+	podList, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods of deployment %s: %v", name, err)
+	}
+	var pods []corev1.Pod
+	for i := range podList.Items {
+		if owner := metav1.GetControllerOf(&podList.Items[i]); owner != nil && owners[owner.UID] {
+			pods = append(pods, podList.Items[i])
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	eventList, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events in namespace %s: %v", namespace, err)
+	}
+	// Events of pods that are gone are kept, they often hold the cause
+	events := summarizeEvents(eventList.Items, EventFilter{Types: []string{corev1.EventTypeWarning}}, deploymentEventScope(deployment, replicaSets))
+
+	d := &diagnosis{namespace: namespace, name: name}
+	for i := range pods {
+		d.diagnosePod(&pods[i], events)
+	}
+	d.diagnoseDeployment(deployment, len(pods))
+
+	result := &Diagnosis{
+		Name:      name,
+		Namespace: namespace,
+		Healthy:   true,
+		Rollout:   getRolloutStatus(deployment),
+		Pods:      len(pods),
+		Findings:  rankFindings(d.findings),
+		Events:    events,
+	}
+	if len(result.Events) > maxDiagnosisEvents {
+		result.Events = result.Events[:maxDiagnosisEvents]
+	}
+	for _, finding := range result.Findings {
+		if finding.Severity != SeverityInfo {
+			result.Healthy = false
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// diagnosePod checks the scheduling of a pod and the state of its containers
+func (d *diagnosis) diagnosePod(pod *corev1.Pod, events []EventInfo) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+			d.addUnschedulable(pod, condition.Message)
+		}
+	}
+
+	containers := make(map[string]corev1.Container, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	for _, container := range append(append([]corev1.Container(nil), pod.Spec.InitContainers...), pod.Spec.Containers...) {
+		containers[container.Name] = container
+	}
+
+	statuses := append(append([]corev1.ContainerStatus(nil), pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		d.diagnoseContainer(pod, containers[status.Name], status, events)
+	}
+}
+
+// diagnoseContainer checks the current and last state of a container
+func (d *diagnosis) diagnoseContainer(pod *corev1.Pod, container corev1.Container, status corev1.ContainerStatus, events []EventInfo) {
+	last := status.LastTerminationState.Terminated
+	oomKilled := last != nil && last.Reason == FindingOOMKilled ||
+		status.State.Terminated != nil && status.State.Terminated.Reason == FindingOOMKilled
+
+	if waiting := status.State.Waiting; waiting != nil {
+		switch waiting.Reason {
+		case "CrashLoopBackOff":
+			if oomKilled {
+				d.addOOMKilled(pod, container, status)
+				return
+			}
+			finding := Finding{
+				Severity:   SeverityCritical,
+				Type:       FindingCrashLoop,
+				Container:  status.Name,
+				Message:    fmt.Sprintf("container %s keeps crashing", status.Name),
+				Restarts:   status.RestartCount,
+				Suggestion: "Read the logs of the crashed container. If the crashes started with the last update, roll back.",
+				score:      90,
+			}
+			if last != nil {
+				exitCode := last.ExitCode
+				finding.ExitCode = &exitCode
+				finding.Message = fmt.Sprintf("container %s keeps crashing, last exit code %d%s", status.Name, exitCode, exitCodeHint(exitCode))
+				finding.reason = fmt.Sprint(exitCode)
+			}
+			finding.Command = d.kubectl("logs %s -c %s --previous", pod.Name, status.Name)
+			d.add(finding, pod.Name)
+			return
+		case "ImagePullBackOff", "ErrImagePull", "InvalidImageName", "ErrImageNeverPull":
+			d.add(Finding{
+				Severity:   SeverityCritical,
+				Type:       FindingImagePull,
+				Container:  status.Name,
+				Message:    fmt.Sprintf("image %s of container %s cannot be pulled (%s)", container.Image, status.Name, waiting.Reason),
+				Suggestion: "Check that the image tag exists and the registry credentials (imagePullSecrets) are valid, or roll back to the previous image.",
+				Command:    d.kubectl("rollout undo deployment/%s", d.name),
+				score:      85,
+				reason:     waiting.Reason,
+			}, pod.Name)
+			return
+		case "CreateContainerConfigError", "CreateContainerError":
+			d.add(Finding{
+				Severity:   SeverityCritical,
+				Type:       FindingContainerConfig,
+				Container:  status.Name,
+				Message:    fmt.Sprintf("container %s cannot be created: %s", status.Name, waiting.Message),
+				Suggestion: "A referenced ConfigMap, Secret or key is usually missing. Create it or fix the reference.",
+				Command:    d.kubectl("describe pod %s", pod.Name),
+				score:      80,
+				reason:     waiting.Reason,
+			}, pod.Name)
+			return
+		}
+	}
+
+	if oomKilled {
+		d.addOOMKilled(pod, container, status)
+		return
+	}
+
+	if status.State.Running != nil && !status.Ready && container.ReadinessProbe != nil {
+		message, failures := probeMessage(events, pod.Name, "Readiness", status.Name)
+		d.add(Finding{
+			Severity:      SeverityWarning,
+			Type:          FindingReadinessProbe,
+			Container:     status.Name,
+			Message:       message,
+			ProbeFailures: failures,
+			Suggestion:    "The pod gets no traffic while the probe fails. Check the probe endpoint and whether the app is still starting up.",
+			Command:       d.kubectl("describe pod %s", pod.Name),
+			score:         60,
+		}, pod.Name)
+	}
+
+	if container.LivenessProbe != nil && hasProbeEvent(events, pod.Name, "Liveness") {
+		message, failures := probeMessage(events, pod.Name, "Liveness", status.Name)
+		d.add(Finding{
+			Severity:      SeverityWarning,
+			Type:          FindingLivenessProbe,
+			Container:     status.Name,
+			Message:       message,
+			Restarts:      status.RestartCount,
+			ProbeFailures: failures,
+			Suggestion:    "The kubelet restarts the container when the liveness probe fails. Check the probe and its timeouts.",
+			Command:       d.kubectl("logs %s -c %s --previous", pod.Name, status.Name),
+			score:         55,
+		}, pod.Name)
+		return
+	}
+
+	if last != nil && last.ExitCode != 0 && status.RestartCount >= restartsWorthReporting {
+		exitCode := last.ExitCode
+		d.add(Finding{
+			Severity:   SeverityWarning,
+			Type:       FindingRestarts,
+			Container:  status.Name,
+			Message:    fmt.Sprintf("container %s keeps restarting, last exit code %d%s", status.Name, exitCode, exitCodeHint(exitCode)),
+			ExitCode:   &exitCode,
+			Restarts:   status.RestartCount,
+			Suggestion: "Read the logs of the previous run to see why it exited.",
+			Command:    d.kubectl("logs %s -c %s --previous", pod.Name, status.Name),
+			score:      50,
+			reason:     fmt.Sprint(exitCode),
+		}, pod.Name)
+	}
+}
+
+// addOOMKilled reports a container killed for exceeding its memory limit
+func (d *diagnosis) addOOMKilled(pod *corev1.Pod, container corev1.Container, status corev1.ContainerStatus) {
+	finding := Finding{
+		Severity:   SeverityCritical,
+		Type:       FindingOOMKilled,
+		Container:  status.Name,
+		Restarts:   status.RestartCount,
+		Suggestion: "The container needs more memory than its limit, or leaks memory. Raise the limit or look into the memory usage.",
+		score:      95,
+	}
+	exitCode := int32(137)
+	finding.ExitCode = &exitCode
+
+	limit, hasLimit := container.Resources.Limits[corev1.ResourceMemory]
+	if hasLimit {
+		finding.Message = fmt.Sprintf("container %s was OOMKilled at its memory limit of %s", status.Name, limit.String())
+		doubled := resource.NewQuantity(limit.Value()*2, resource.BinarySI)
+		finding.Command = d.kubectl("set resources deployment/%s -c %s --limits=memory=%s", d.name, status.Name, doubled.String())
+	} else {
+		finding.Message = fmt.Sprintf("container %s was OOMKilled without a memory limit, the node ran out of memory", status.Name)
+		finding.Command = d.kubectl("describe node %s", pod.Spec.NodeName)
+	}
+	if status.State.Waiting == nil {
+		// Restarted and running again, not down right now
+		finding.Severity = SeverityWarning
+		finding.score = 70
+	}
+
+	d.add(finding, pod.Name)
+}
+
+// addUnschedulable reports a pod the scheduler found no node for
+func (d *diagnosis) addUnschedulable(pod *corev1.Pod, message string) {
+	finding := Finding{
+		Severity: SeverityCritical,
+		Type:     FindingUnschedulable,
+		score:    88,
+	}
+
+	cause := "constraints"
+	switch {
+	case strings.Contains(message, "Insufficient"):
+		cause = "resources"
+		finding.Suggestion = "No node has enough free CPU or memory for the pod's requests. Lower the requests, scale down other workloads or add nodes."
+		finding.Command = "kubectl describe nodes | grep -A 8 'Allocated resources'"
+	case strings.Contains(message, "taint"):
+		cause = "taints"
+		finding.Suggestion = "The nodes carry taints the pod does not tolerate. Add a toleration or untaint a node."
+		finding.Command = "kubectl get nodes -o custom-columns=NAME:.metadata.name,TAINTS:.spec.taints"
+	default:
+		finding.Suggestion = "The pod's node selector, affinity or volume constraints match no node."
+		finding.Command = d.kubectl("describe pod %s", pod.Name)
+	}
+	finding.Message = fmt.Sprintf("pods cannot be scheduled due to %s: %s", cause, message)
+	finding.reason = cause
+
+	d.add(finding, pod.Name)
+}
+
+// diagnoseDeployment checks the deployment conditions and replica counts
+func (d *diagnosis) diagnoseDeployment(deployment *appsv1.Deployment, pods int) {
+	for _, condition := range deployment.Status.Conditions {
+		switch {
+		case condition.Type == appsv1.DeploymentProgressing && condition.Reason == progressDeadlineExceededReason:
+			d.add(Finding{
+				Severity:   SeverityCritical,
+				Type:       FindingProgressDeadline,
+				Message:    fmt.Sprintf("rollout exceeded its progress deadline: %s", condition.Message),
+				Suggestion: "The new pods never became available. Fix the cause found on the pods or roll back.",
+				Command:    d.kubectl("rollout undo deployment/%s", d.name),
+				score:      75,
+			}, "")
+		case condition.Type == appsv1.DeploymentReplicaFailure && condition.Status == corev1.ConditionTrue:
+			d.add(Finding{
+				Severity:   SeverityCritical,
+				Type:       FindingReplicaFailure,
+				Message:    fmt.Sprintf("pods cannot be created (%s): %s", condition.Reason, condition.Message),
+				Suggestion: "Pod creation is usually refused by a ResourceQuota, a LimitRange or an admission webhook.",
+				Command:    d.kubectl("describe resourcequota"),
+				score:      82,
+			}, "")
+		}
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	if deployment.Spec.Paused {
+		d.add(Finding{
+			Severity:   SeverityInfo,
+			Type:       FindingPaused,
+			Message:    "the rollout is paused, template changes are not rolled out",
+			Suggestion: "Resume the rollout once the investigation is done.",
+			Command:    d.kubectl("rollout resume deployment/%s", d.name),
+			score:      20,
+		}, "")
+	}
+
+	if desired == 0 {
+		finding := Finding{
+			Severity:   SeverityInfo,
+			Type:       FindingScaledToZero,
+			Message:    "the deployment is scaled to zero replicas",
+			Suggestion: "Scale it up if it should be running.",
+			Command:    d.kubectl("scale deployment/%s --replicas=1", d.name),
+			score:      10,
+		}
+		if replicas, asleep := deployment.Annotations[HibernateAnnotation]; asleep {
+			finding.Message = fmt.Sprintf("the deployment is asleep, it had %s replicas", replicas)
+			finding.Suggestion = "Its namespace was put to sleep by a hibernation schedule, wake the schedule up to restore it."
+			finding.Command = ""
+		}
+		d.add(finding, "")
+		return
+	}
+
+	// Unavailable pods without a more specific finding
+	if len(d.findings) == 0 && deployment.Status.AvailableReplicas < desired {
+		d.add(Finding{
+			Severity:   SeverityWarning,
+			Type:       FindingUnavailable,
+			Message:    fmt.Sprintf("%d of %d replicas are available, %d pods exist", deployment.Status.AvailableReplicas, desired, pods),
+			Suggestion: "The pods show no known failure yet, they may still be starting. Check the rollout and the events.",
+			Command:    d.kubectl("rollout status deployment/%s", d.name),
+			score:      40,
+		}, "")
+	}
+}
+
+// rankFindings sorts findings by severity, most severe first, and numbers them
+func rankFindings(findings []*Finding) []Finding {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].score != findings[j].score {
+			return findings[i].score > findings[j].score
+		}
+		return len(findings[i].Pods) > len(findings[j].Pods)
+	})
+
+	ranked := make([]Finding, 0, len(findings))
+	for i, finding := range findings {
+		finding.Rank = i + 1
+		ranked = append(ranked, *finding)
+	}
+	return ranked
+}
+
+// probeMessage returns the latest probe failure event of a pod with the number of failures it counts,
+// or a generic message without one
+func probeMessage(events []EventInfo, pod, probe, container string) (string, int32) {
+	// events are sorted newest first
+	for _, event := range events {
+		if event.Kind == "Pod" && event.Name == pod && event.Reason == "Unhealthy" && strings.HasPrefix(event.Message, probe+" probe failed") {
+			return fmt.Sprintf("container %s: %s", container, event.Message), event.Count
+		}
+	}
+	return fmt.Sprintf("%s probe of container %s is failing", strings.ToLower(probe), container), 0
+}
+
+// hasProbeEvent reports whether a pod has a probe failure event of the given probe
+func hasProbeEvent(events []EventInfo, pod, probe string) bool {
+	for _, event := range events {
+		if event.Kind == "Pod" && event.Name == pod && event.Reason == "Unhealthy" && strings.HasPrefix(event.Message, probe+" probe failed") {
+			return true
+		}
+	}
+	return false
+}
+
+// exitCodeHint explains well-known exit codes
+func exitCodeHint(exitCode int32) string {
+	switch exitCode {
+	case 1:
+		return " (application error)"
+	case 126:
+		return " (command not executable)"
+	case 127:
+		return " (command not found)"
+	case 137:
+		return " (killed, SIGKILL)"
+	case 139:
+		return " (segmentation fault)"
+	case 143:
+		return " (terminated, SIGTERM)"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,169 @@
+package kuberclient
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// testDiagnosisPod returns a pod of the web-6d4f9 ReplicaSet with an app and a sidecar container
+func testDiagnosisPod(name string, app, sidecar corev1.ContainerStatus) *corev1.Pod {
+	controller := true
+	app.Name, sidecar.Name = "app", "sidecar"
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app": "web"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: kindReplicaSet, Name: "web-6d4f9", UID: "web-6d4f9-uid", Controller: &controller},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+			Containers: []corev1.Container{
+				{Name: "app", Image: "web:v2", ReadinessProbe: &corev1.Probe{}},
+				{
+					Name:  "sidecar",
+					Image: "proxy:v1",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+					},
+				},
+			},
+		},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{app, sidecar}},
+	}
+}
+
+func running(ready bool, restarts int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Ready:        ready,
+		RestartCount: restarts,
+		State:        corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+}
+
+func crashLooping(restarts, exitCode int32, reason string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		RestartCount: restarts,
+		State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		LastTerminationState: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Reason: reason},
+		},
+	}
+}
+
+func TestDiagnoseDeployment(t *testing.T) {
+	unschedulable := testDiagnosisPod("web-6d4f9-g7h8i", corev1.ContainerStatus{}, corev1.ContainerStatus{})
+	unschedulable.Status = corev1.PodStatus{Conditions: []corev1.PodCondition{{
+		Type:    corev1.PodScheduled,
+		Status:  corev1.ConditionFalse,
+		Reason:  corev1.PodReasonUnschedulable,
+		Message: "0/3 nodes are available: 3 Insufficient memory.",
+	}}}
+
+	// A pod of another deployment matching the selector is not diagnosed
+	foreign := testDiagnosisPod("web-api-5b7c8-q9z4r", crashLooping(9, 1, "Error"), running(true, 0))
+	foreign.OwnerReferences[0].UID = types.UID("other-uid")
+
+	readinessEvent := func(pod string, count int32) *corev1.Event {
+		event := testEvent(podRef(pod), corev1.EventTypeWarning, "Unhealthy", "Readiness probe failed: HTTP probe failed with statuscode: 503", count, 0, 1)
+		return &event
+	}
+
+	objects := []runtime.Object{
+		testDeployment("web:v2", 2, ""),
+		testReplicaSet("web-6d4f9", "web-uid", 2, "web:v2", ""),
+		testDiagnosisPod("web-6d4f9-a1b2c", crashLooping(5, 1, "Error"), running(true, 0)),
+		testDiagnosisPod("web-6d4f9-d3e4f", crashLooping(8, 1, "Error"), running(true, 0)),
+		testDiagnosisPod("web-6d4f9-j1k2l", crashLooping(2, 2, "Error"), running(true, 0)),
+		testDiagnosisPod("web-6d4f9-m3n4o", running(true, 0), crashLooping(4, 137, FindingOOMKilled)),
+		testDiagnosisPod("web-6d4f9-p5q6r", running(false, 0), running(true, 0)),
+		testDiagnosisPod("web-6d4f9-s7t8u", running(false, 0), running(true, 0)),
+		unschedulable,
+		foreign,
+		readinessEvent("web-6d4f9-p5q6r", 3),
+		readinessEvent("web-6d4f9-s7t8u", 4),
+	}
+	client, _ := newTestClient(objects...)
+
+	diagnosis, err := client.DiagnoseDeployment(context.Background(), "default", "web")
+	if err != nil {
+		t.Fatalf("DiagnoseDeployment: %v", err)
+	}
+
+	type summary struct {
+		Rank          int
+		Type          string
+		Container     string
+		Pods          []string
+		Restarts      int32
+		ProbeFailures int32
+	}
+	want := []summary{
+		{1, FindingOOMKilled, "sidecar", []string{"web-6d4f9-m3n4o"}, 4, 0},
+		// The same exit code is merged across pods and keeps the highest restart count
+		{2, FindingCrashLoop, "app", []string{"web-6d4f9-a1b2c", "web-6d4f9-d3e4f"}, 8, 0},
+		// Another exit code is a finding of its own, ranked below the one affecting more pods
+		{3, FindingCrashLoop, "app", []string{"web-6d4f9-j1k2l"}, 2, 0},
+		{4, FindingUnschedulable, "", []string{"web-6d4f9-g7h8i"}, 0, 0},
+		// Probe failures are summed over the pods
+		{5, FindingReadinessProbe, "app", []string{"web-6d4f9-p5q6r", "web-6d4f9-s7t8u"}, 0, 7},
+	}
+
+	got := make([]summary, 0, len(diagnosis.Findings))
+	for _, finding := range diagnosis.Findings {
+		got = append(got, summary{finding.Rank, finding.Type, finding.Container, finding.Pods, finding.Restarts, finding.ProbeFailures})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got findings\n%+v\nwant\n%+v", got, want)
+	}
+
+	if diagnosis.Healthy {
+		t.Error("diagnosis is healthy, want unhealthy")
+	}
+	if diagnosis.Pods != 7 {
+		t.Errorf("got %d pods, want 7", diagnosis.Pods)
+	}
+	if len(diagnosis.Findings) > 0 {
+		oom := diagnosis.Findings[0]
+		if want := "kubectl -n default set resources deployment/web -c sidecar --limits=memory=512Mi"; oom.Command != want {
+			t.Errorf("got command %q, want %q", oom.Command, want)
+		}
+	}
+}
+
+func TestDiagnosisAdd(t *testing.T) {
+	d := &diagnosis{namespace: "default", name: "web"}
+	d.add(Finding{Type: FindingRestarts, Container: "app", Message: "first pod", Restarts: 3, reason: "1"}, "web-1")
+	d.add(Finding{Type: FindingRestarts, Container: "app", Message: "second pod", Restarts: 7, reason: "1"}, "web-2")
+	d.add(Finding{Type: FindingRestarts, Container: "app", Message: "third pod", Restarts: 5, reason: "1"}, "web-3")
+	d.add(Finding{Type: FindingRestarts, Container: "app", Restarts: 3, reason: "143"}, "web-4")
+	d.add(Finding{Type: FindingRestarts, Container: "sidecar", Restarts: 3, reason: "1"}, "web-1")
+	d.add(Finding{Type: FindingReadinessProbe, Container: "app", ProbeFailures: 2}, "web-1")
+	d.add(Finding{Type: FindingReadinessProbe, Container: "app", ProbeFailures: 5}, "web-2")
+	d.add(Finding{Type: FindingPaused}, "")
+	d.add(Finding{Type: FindingPaused}, "")
+
+	if len(d.findings) != 5 {
+		t.Fatalf("got %d findings, want 5", len(d.findings))
+	}
+
+	restarts := d.findings[0]
+	if restarts.Message != "first pod" || restarts.Restarts != 7 || !reflect.DeepEqual(restarts.Pods, []string{"web-1", "web-2", "web-3"}) {
+		t.Errorf("got merged finding %+v, want the first message, 7 restarts and three pods", restarts)
+	}
+	if probe := d.findings[3]; probe.ProbeFailures != 7 || len(probe.Pods) != 2 {
+		t.Errorf("got merged finding %+v, want 7 probe failures of two pods", probe)
+	}
+	if paused := d.findings[4]; len(paused.Pods) != 0 {
+		t.Errorf("got pods %v for a deployment finding, want none", paused.Pods)
+	}
+}
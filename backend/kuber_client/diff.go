@@ -0,0 +1,105 @@
+package kuberclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// SpecChange is a single field that differs between two versions of a spec
+type SpecChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// MutationResult describes the outcome of a workload mutation.
+// Changes are only computed for dry runs, Containers is only set by image updates
+// and HPA only by scaling a workload whose HPA bounds had to be adjusted.
+type MutationResult struct {
+	DryRun     bool           `json:"dryRun"`
+	Changes    []SpecChange   `json:"changes,omitempty"`
+	Containers []string       `json:"containers,omitempty"`
+	HPA        *HPAAdjustment `json:"hpa,omitempty"`
+}
+
+// diffSpecs returns the fields that differ between two specs, sorted by path
+func diffSpecs(before, after interface{}) ([]SpecChange, error) {
+	beforeValue, err := toGeneric(before)
+	if err != nil {
+		return nil, err
+	}
+	afterValue, err := toGeneric(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []SpecChange
+	collectChanges("", beforeValue, afterValue, &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+// toGeneric converts a typed object into maps, slices and scalars via its JSON form
+func toGeneric(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing spec: %v", err)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("error deserializing spec: %v", err)
+	}
+
+	return result, nil
+}
+
+// collectChanges walks both values and records every differing leaf
+func collectChanges(path string, before, after interface{}, changes *[]SpecChange) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		keys := make(map[string]bool)
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		for key := range keys {
+			collectChanges(joinPath(path, key), beforeMap[key], afterMap[key], changes)
+		}
+		return
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList && len(beforeList) == len(afterList) {
+		for i := range beforeList {
+			collectChanges(fmt.Sprintf("%s[%d]", path, i), beforeList[i], afterList[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, SpecChange{
+			Path:   path,
+			Before: before,
+			After:  after,
+		})
+	}
+}
+
+// joinPath appends a field name to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
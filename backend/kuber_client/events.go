@@ -0,0 +1,239 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// EventFilter restricts the returned events, empty fields match everything
+type EventFilter struct {
+	Types   []string // Event types, e.g. "Warning"
+	Reasons []string // Event reasons, e.g. "BackOff" or "FailedScheduling"
+}
+
+// EventInfo is a deduplicated Kubernetes event
+type EventInfo struct {
+	Type           string    `json:"type"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Kind           string    `json:"kind"`
+	Name           string    `json:"name"`
+	Namespace      string    `json:"namespace"`
+	Count          int32     `json:"count"`
+	FirstTimestamp time.Time `json:"firstTimestamp"`
+	LastTimestamp  time.Time `json:"lastTimestamp"`
+	Source         string    `json:"source,omitempty"`
+}
+
+// matches reports whether an event passes the filter
+func (f EventFilter) matches(event *corev1.Event) bool {
+	return matchesAny(f.Types, event.Type) && matchesAny(f.Reasons, event.Reason)
+}
+
+// matchesAny reports whether value equals one of the values, ignoring case. No values match everything.
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNamespaceEvents returns the events of a namespace, newest first
+func (c *Client) GetNamespaceEvents(ctx context.Context, namespace string, filter EventFilter) ([]EventInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	events, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events in namespace %s: %v", namespace, err)
+	}
+
+	return summarizeEvents(events.Items, filter, nil), nil
+}
+
+// GetDeploymentEvents returns the events of a deployment, its ReplicaSets and their pods, newest first
+func (c *Client) GetDeploymentEvents(ctx context.Context, namespace, name string, filter EventFilter) ([]EventInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s in namespace %s: %v", name, namespace, err)
+	}
+
+	replicaSets, _, err := c.getDeploymentReplicaSets(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events in namespace %s: %v", namespace, err)
+	}
+
+	return summarizeEvents(events.Items, filter, deploymentEventScope(deployment, replicaSets)), nil
+}
+
+// eventScope selects the events of a deployment. The deployment and its ReplicaSets are matched by UID.
+// Pods are matched by name, as the pods behind the interesting events have often crashed, been evicted
+// or been replaced since and no longer exist.
+type eventScope struct {
+	uids        map[types.UID]bool
+	podPrefixes []string // "<replicaset>-" of each ReplicaSet, pod names add a random suffix
+}
+
+// deploymentEventScope selects the events of a deployment, its ReplicaSets and every pod they created
+func deploymentEventScope(deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) *eventScope {
+	scope := &eventScope{uids: map[types.UID]bool{deployment.UID: true}}
+	for i := range replicaSets {
+		scope.uids[replicaSets[i].UID] = true
+		scope.podPrefixes = append(scope.podPrefixes, replicaSets[i].Name+"-")
+	}
+	return scope
+}
+
+// includes reports whether an event about the object is in scope
+func (s *eventScope) includes(object corev1.ObjectReference) bool {
+	if s.uids[object.UID] {
+		return true
+	}
+	if object.Kind != "Pod" {
+		return false
+	}
+	for _, prefix := range s.podPrefixes {
+		// The suffix has no dash, so pods of a ReplicaSet whose name starts the same way are not matched
+		if suffix, ok := strings.CutPrefix(object.Name, prefix); ok && suffix != "" && !strings.Contains(suffix, "-") {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeEvents filters events, merges repeated ones and sorts them newest first.
+// If scope is not nil, only events for objects in scope are kept.
+func summarizeEvents(events []corev1.Event, filter EventFilter, scope *eventScope) []EventInfo {
+	type eventKey struct {
+		uid       types.UID
+		kind      string
+		name      string
+		eventType string
+		reason    string
+		message   string
+	}
+
+	merged := make(map[eventKey]*EventInfo)
+	for i := range events {
+		event := &events[i]
+		if scope != nil && !scope.includes(event.InvolvedObject) {
+			continue
+		}
+		if !filter.matches(event) {
+			continue
+		}
+
+		first, last := eventTimestamps(event)
+		count := eventCount(event)
+
+		key := eventKey{
+			uid:       event.InvolvedObject.UID,
+			kind:      event.InvolvedObject.Kind,
+			name:      event.InvolvedObject.Name,
+			eventType: event.Type,
+			reason:    event.Reason,
+			message:   event.Message,
+		}
+		if existing, ok := merged[key]; ok {
+			existing.Count += count
+			if first.Before(existing.FirstTimestamp) {
+				existing.FirstTimestamp = first
+			}
+			if last.After(existing.LastTimestamp) {
+				existing.LastTimestamp = last
+			}
+			continue
+		}
+
+		merged[key] = &EventInfo{
+			Type:           event.Type,
+			Reason:         event.Reason,
+			Message:        event.Message,
+			Kind:           event.InvolvedObject.Kind,
+			Name:           event.InvolvedObject.Name,
+			Namespace:      event.InvolvedObject.Namespace,
+			Count:          count,
+			FirstTimestamp: first,
+			LastTimestamp:  last,
+			Source:         eventSource(event),
+		}
+	}
+
+	result := make([]EventInfo, 0, len(merged))
+	for _, info := range merged {
+		result = append(result, *info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].LastTimestamp.Equal(result[j].LastTimestamp) {
+			return result[i].LastTimestamp.After(result[j].LastTimestamp)
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// eventTimestamps returns when an event was first and last seen.
+// Events created through events.k8s.io leave the legacy timestamps empty and use eventTime and series instead.
+func eventTimestamps(event *corev1.Event) (time.Time, time.Time) {
+	first := event.FirstTimestamp.Time
+	if first.IsZero() {
+		first = event.EventTime.Time
+	}
+	if first.IsZero() {
+		first = event.CreationTimestamp.Time
+	}
+
+	last := event.LastTimestamp.Time
+	if last.IsZero() && event.Series != nil {
+		last = event.Series.LastObservedTime.Time
+	}
+	if last.IsZero() {
+		last = first
+	}
+
+	return first, last
+}
+
+// eventCount returns how often an event occurred
+func eventCount(event *corev1.Event) int32 {
+	if event.Series != nil && event.Series.Count > 0 {
+		return event.Series.Count
+	}
+	if event.Count > 0 {
+		return event.Count
+	}
+	return 1
+}
+
+// eventSource returns the component that reported an event
+func eventSource(event *corev1.Event) string {
+	if event.Source.Component != "" {
+		return event.Source.Component
+	}
+	return event.ReportingController
+}
@@ -0,0 +1,188 @@
+package kuberclient
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// eventsStart is the time of the first test event
+var eventsStart = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// testEvent returns an event about object seen count times, first and last the given minutes after eventsStart
+func testEvent(object corev1.ObjectReference, eventType, reason, message string, count int32, first, last int) corev1.Event {
+	return corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: object.Name + "." + reason, Namespace: "default"},
+		InvolvedObject: object,
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		Count:          count,
+		FirstTimestamp: metav1.NewTime(eventsStart.Add(time.Duration(first) * time.Minute)),
+		LastTimestamp:  metav1.NewTime(eventsStart.Add(time.Duration(last) * time.Minute)),
+	}
+}
+
+func podRef(name string) corev1.ObjectReference {
+	return corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: name, UID: types.UID(name + "-uid")}
+}
+
+func TestEventScopeIncludes(t *testing.T) {
+	deployment := testDeployment("web:v2", 2, "")
+	scope := deploymentEventScope(deployment, []appsv1.ReplicaSet{
+		*testReplicaSet("web-6d4f9", "web-uid", 2, "web:v2", ""),
+		*testReplicaSet("web-58b7c", "web-uid", 1, "web:v1", ""),
+	})
+
+	tests := []struct {
+		name   string
+		object corev1.ObjectReference
+		want   bool
+	}{
+		{"deployment by UID", corev1.ObjectReference{Kind: KindDeployment, Name: "web", UID: "web-uid"}, true},
+		{"ReplicaSet by UID", corev1.ObjectReference{Kind: "ReplicaSet", Name: "web-6d4f9", UID: "web-6d4f9-uid"}, true},
+		{"pod of the current ReplicaSet", podRef("web-6d4f9-x7k2p"), true},
+		{"deleted pod of an old ReplicaSet", podRef("web-58b7c-q2w9d"), true},
+		{"pod of another deployment starting the same way", podRef("web-api-5b7c8-q9z4r"), false},
+		{"pod of a ReplicaSet starting with the ReplicaSet name", podRef("web-6d4f9-canary-x7k2p"), false},
+		{"object named like the ReplicaSet prefix", podRef("web-6d4f9-"), false},
+		{"ReplicaSet named like a pod", corev1.ObjectReference{Kind: "ReplicaSet", Name: "web-6d4f9-x7k2p"}, false},
+		{"deployment of the same name with another UID", corev1.ObjectReference{Kind: KindDeployment, Name: "web", UID: "old-uid"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scope.includes(tt.object); got != tt.want {
+				t.Errorf("includes(%s %s) = %v, want %v", tt.object.Kind, tt.object.Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeEvents(t *testing.T) {
+	crashing := podRef("web-6d4f9-x7k2p")
+	replaced := podRef("web-6d4f9-h8j3k")
+
+	events := []corev1.Event{
+		testEvent(crashing, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app", 3, 0, 5),
+		// The same event recorded again, e.g. after the event was garbage collected and re-created
+		testEvent(crashing, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app", 4, 6, 9),
+		testEvent(crashing, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container sidecar", 1, 7, 7),
+		testEvent(crashing, corev1.EventTypeNormal, "Pulled", "Container image \"web:v2\" already present on machine", 1, 1, 1),
+		testEvent(replaced, corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app", 2, 2, 8),
+		testEvent(podRef("other-5b7c8-q9z4r"), corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app", 1, 3, 3),
+	}
+	// An events.k8s.io event without legacy timestamps and count
+	series := testEvent(replaced, corev1.EventTypeWarning, "Unhealthy", "Readiness probe failed", 0, 0, 0)
+	series.FirstTimestamp, series.LastTimestamp = metav1.Time{}, metav1.Time{}
+	series.EventTime = metav1.NewMicroTime(eventsStart.Add(4 * time.Minute))
+	series.Series = &corev1.EventSeries{Count: 6, LastObservedTime: metav1.NewMicroTime(eventsStart.Add(10 * time.Minute))}
+	events = append(events, series)
+
+	scope := &eventScope{podPrefixes: []string{"web-6d4f9-"}}
+
+	type summary struct {
+		Name    string
+		Reason  string
+		Message string
+		Count   int32
+		First   int
+		Last    int
+	}
+	summarize := func(infos []EventInfo) []summary {
+		result := make([]summary, 0, len(infos))
+		for _, info := range infos {
+			result = append(result, summary{
+				Name:    info.Name,
+				Reason:  info.Reason,
+				Message: info.Message,
+				Count:   info.Count,
+				First:   int(info.FirstTimestamp.Sub(eventsStart) / time.Minute),
+				Last:    int(info.LastTimestamp.Sub(eventsStart) / time.Minute),
+			})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		scope  *eventScope
+		want   []summary
+	}{
+		{
+			name:  "repeated events are merged, newest first",
+			scope: scope,
+			want: []summary{
+				{"web-6d4f9-h8j3k", "Unhealthy", "Readiness probe failed", 6, 4, 10},
+				{"web-6d4f9-x7k2p", "BackOff", "Back-off restarting failed container app", 7, 0, 9},
+				{"web-6d4f9-h8j3k", "BackOff", "Back-off restarting failed container app", 2, 2, 8},
+				{"web-6d4f9-x7k2p", "BackOff", "Back-off restarting failed container sidecar", 1, 7, 7},
+				{"web-6d4f9-x7k2p", "Pulled", "Container image \"web:v2\" already present on machine", 1, 1, 1},
+			},
+		},
+		{
+			name:   "filter by type and reason",
+			filter: EventFilter{Types: []string{"warning"}, Reasons: []string{"BackOff"}},
+			scope:  scope,
+			want: []summary{
+				{"web-6d4f9-x7k2p", "BackOff", "Back-off restarting failed container app", 7, 0, 9},
+				{"web-6d4f9-h8j3k", "BackOff", "Back-off restarting failed container app", 2, 2, 8},
+				{"web-6d4f9-x7k2p", "BackOff", "Back-off restarting failed container sidecar", 1, 7, 7},
+			},
+		},
+		{
+			name:   "without scope every object is kept",
+			filter: EventFilter{Reasons: []string{"BackOff"}},
+			want: []summary{
+				{"web-6d4f9-x7k2p", "BackOff", "Back-off restarting failed container app", 7, 0, 9},
+				{"web-6d4f9-h8j3k", "BackOff", "Back-off restarting failed container app", 2, 2, 8},
+				{"web-6d4f9-x7k2p", "BackOff", "Back-off restarting failed container sidecar", 1, 7, 7},
+				{"other-5b7c8-q9z4r", "BackOff", "Back-off restarting failed container app", 1, 3, 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(summarizeEvents(events, tt.filter, tt.scope))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got events\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDeploymentEvents(t *testing.T) {
+	deleted := testEvent(podRef("web-58b7c-q2w9d"), corev1.EventTypeWarning, "Evicted", "The node was low on resource: memory", 1, 0, 0)
+	scaled := testEvent(corev1.ObjectReference{Kind: KindDeployment, Namespace: "default", Name: "web", UID: "web-uid"},
+		corev1.EventTypeNormal, "ScalingReplicaSet", "Scaled up replica set web-6d4f9 to 2", 1, 1, 1)
+	other := testEvent(podRef("web-api-5b7c8-q9z4r"), corev1.EventTypeWarning, "BackOff", "Back-off restarting failed container app", 1, 2, 2)
+
+	// The pod of the eviction no longer exists, its ReplicaSet does
+	client, _ := newTestClient(
+		testDeployment("web:v2", 2, ""),
+		testReplicaSet("web-6d4f9", "web-uid", 2, "web:v2", ""),
+		testReplicaSet("web-58b7c", "web-uid", 1, "web:v1", ""),
+		&deleted, &scaled, &other,
+	)
+
+	events, err := client.GetDeploymentEvents(context.Background(), "default", "web", EventFilter{})
+	if err != nil {
+		t.Fatalf("GetDeploymentEvents: %v", err)
+	}
+
+	var reasons []string
+	for _, event := range events {
+		reasons = append(reasons, event.Reason)
+	}
+	if want := []string{"ScalingReplicaSet", "Evicted"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("got events %v, want %v", reasons, want)
+	}
+}
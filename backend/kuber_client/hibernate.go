@@ -0,0 +1,179 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// HibernateAnnotation holds the replica count a deployment had before it was put to sleep
+const HibernateAnnotation = "chatops/hibernated-replicas"
+
+// Outcomes of putting a deployment to sleep or waking it up
+const (
+	HibernateScaled  = "scaled"  // Replicas were changed
+	HibernateSkipped = "skipped" // Nothing to do, see Reason
+	HibernateFailed  = "failed"  // The update failed, see Reason
+)
+
+// HibernateResult is the outcome of putting one deployment to sleep or waking it up
+type HibernateResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Replicas int32  `json:"replicas"` // Replicas before the operation
+	Target   int32  `json:"target"`   // Replicas after the operation
+	Reason   string `json:"reason,omitempty"`
+}
+
+// SleepNamespace scales every deployment of a namespace to zero and records its replica count in
+// HibernateAnnotation. Deployments already asleep or already at zero replicas and the excluded ones
+// are skipped. A failing deployment does not stop the others, it is reported as failed.
+func (c *Client) SleepNamespace(ctx context.Context, namespace string, exclude []string) ([]HibernateResult, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	deployments, err := c.listDeployments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	sortDeployments(deployments)
+
+	results := make([]HibernateResult, 0, len(deployments))
+	for _, deployment := range deployments {
+		result := HibernateResult{Name: deployment.Name, Status: HibernateSkipped}
+		if deployment.Spec.Replicas != nil {
+			result.Replicas = *deployment.Spec.Replicas
+			result.Target = result.Replicas
+		}
+
+		switch {
+		case len(exclude) > 0 && matchesAny(exclude, deployment.Name):
+			result.Reason = "excluded by the schedule"
+		case deployment.Labels[CanaryLabel] != "":
+			result.Reason = "canary deployments are left to their canary run"
+		default:
+			c.sleepDeployment(ctx, namespace, deployment.Name, &result)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// WakeNamespace restores the replica count recorded by SleepNamespace on every deployment of a namespace
+// and removes HibernateAnnotation. A deployment scaled up by hand while asleep keeps its replicas.
+func (c *Client) WakeNamespace(ctx context.Context, namespace string) ([]HibernateResult, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	deployments, err := c.listDeployments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	sortDeployments(deployments)
+
+	results := make([]HibernateResult, 0, len(deployments))
+	for _, deployment := range deployments {
+		if _, ok := deployment.Annotations[HibernateAnnotation]; !ok {
+			continue
+		}
+
+		result := HibernateResult{Name: deployment.Name, Status: HibernateSkipped}
+		c.wakeDeployment(ctx, namespace, deployment.Name, &result)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// sleepDeployment scales a single deployment to zero like ScaleDeployment, recording its replicas in the same update
+func (c *Client) sleepDeployment(ctx context.Context, namespace, name string, result *HibernateResult) {
+	_, err := c.updateDeployment(ctx, namespace, name, false, func(deployment *appsv1.Deployment) error {
+		// Start over on every attempt, a conflict retries with a fresh deployment
+		result.Status = HibernateSkipped
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		result.Replicas = replicas
+		result.Target = replicas
+
+		if _, asleep := deployment.Annotations[HibernateAnnotation]; asleep {
+			result.Reason = "already asleep"
+			return errNoChange
+		}
+		if replicas == 0 {
+			result.Reason = "already scaled to zero"
+			return errNoChange
+		}
+
+		if deployment.Annotations == nil {
+			deployment.Annotations = make(map[string]string)
+		}
+		deployment.Annotations[HibernateAnnotation] = strconv.Itoa(int(replicas))
+		zero := int32(0)
+		deployment.Spec.Replicas = &zero
+
+		result.Status = HibernateScaled
+		result.Target = 0
+		result.Reason = ""
+		return nil
+	})
+	if err != nil {
+		result.Status = HibernateFailed
+		result.Target = result.Replicas
+		result.Reason = err.Error()
+	}
+}
+
+// wakeDeployment restores the replicas recorded in HibernateAnnotation and removes the annotation
+func (c *Client) wakeDeployment(ctx context.Context, namespace, name string, result *HibernateResult) {
+	_, err := c.updateDeployment(ctx, namespace, name, false, func(deployment *appsv1.Deployment) error {
+		result.Status = HibernateSkipped
+		value, asleep := deployment.Annotations[HibernateAnnotation]
+		if !asleep {
+			result.Reason = "not asleep"
+			return errNoChange
+		}
+
+		var replicas int32
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		result.Replicas = replicas
+		result.Target = replicas
+		delete(deployment.Annotations, HibernateAnnotation)
+
+		recorded, err := strconv.ParseInt(value, 10, 32)
+		switch {
+		case err != nil || recorded < 0:
+			result.Reason = fmt.Sprintf("invalid replica count %q in %s, annotation removed", value, HibernateAnnotation)
+		case replicas > 0:
+			result.Reason = fmt.Sprintf("scaled to %d replicas while asleep, kept", replicas)
+		default:
+			target := int32(recorded)
+			deployment.Spec.Replicas = &target
+			result.Status = HibernateScaled
+			result.Target = target
+			result.Reason = ""
+		}
+		return nil
+	})
+	if err != nil {
+		result.Status = HibernateFailed
+		result.Target = result.Replicas
+		result.Reason = err.Error()
+	}
+}
+
+// sortDeployments sorts deployments by name, so results are reported in a stable order
+func sortDeployments(deployments []appsv1.Deployment) {
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].Name < deployments[j].Name
+	})
+}
@@ -0,0 +1,307 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const kindHPA = "HorizontalPodAutoscaler"
+
+// ErrManagedByHPA is returned when scaling a workload whose replicas are controlled by an HPA
+var ErrManagedByHPA = errors.New("workload is managed by a HorizontalPodAutoscaler")
+
+// HPAInfo describes a HorizontalPodAutoscaler and its current state
+type HPAInfo struct {
+	Name            string         `json:"name"`
+	Namespace       string         `json:"namespace"`
+	TargetKind      string         `json:"targetKind"`
+	TargetName      string         `json:"targetName"`
+	MinReplicas     int32          `json:"minReplicas"`
+	MaxReplicas     int32          `json:"maxReplicas"`
+	CurrentReplicas int32          `json:"currentReplicas"`
+	DesiredReplicas int32          `json:"desiredReplicas"`
+	Metrics         []HPAMetric    `json:"metrics"`
+	Conditions      []HPACondition `json:"conditions,omitempty"`
+	LastScaleTime   *time.Time     `json:"lastScaleTime,omitempty"`
+}
+
+// HPAMetric is a metric the HPA scales on, with its target and the last observed value
+type HPAMetric struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Container string `json:"container,omitempty"`
+	Target    string `json:"target"`
+	Current   string `json:"current,omitempty"`
+}
+
+// HPACondition is a status condition of an HPA, e.g. ScalingLimited
+type HPACondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// HPAAdjustment records the HPA bounds changed to let a manual scale stick
+type HPAAdjustment struct {
+	Name                string `json:"name"`
+	PreviousMinReplicas int32  `json:"previousMinReplicas"`
+	PreviousMaxReplicas int32  `json:"previousMaxReplicas"`
+	MinReplicas         int32  `json:"minReplicas"`
+	MaxReplicas         int32  `json:"maxReplicas"`
+}
+
+// hpaMinReplicas returns the effective minReplicas of an HPA
+func hpaMinReplicas(hpa *autoscalingv2.HorizontalPodAutoscaler) int32 {
+	if hpa.Spec.MinReplicas != nil {
+		return *hpa.Spec.MinReplicas
+	}
+	return 1
+}
+
+// toHPAInfo converts an HPA into its API representation
+func toHPAInfo(hpa *autoscalingv2.HorizontalPodAutoscaler) HPAInfo {
+	info := HPAInfo{
+		Name:            hpa.Name,
+		Namespace:       hpa.Namespace,
+		TargetKind:      hpa.Spec.ScaleTargetRef.Kind,
+		TargetName:      hpa.Spec.ScaleTargetRef.Name,
+		MinReplicas:     hpaMinReplicas(hpa),
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		Metrics:         make([]HPAMetric, 0, len(hpa.Spec.Metrics)),
+	}
+
+	if hpa.Status.LastScaleTime != nil {
+		lastScale := hpa.Status.LastScaleTime.Time
+		info.LastScaleTime = &lastScale
+	}
+
+	for _, spec := range hpa.Spec.Metrics {
+		metric := metricSpecInfo(spec)
+		for _, status := range hpa.Status.CurrentMetrics {
+			if current, ok := metricStatusValue(metric, status); ok {
+				metric.Current = current
+				break
+			}
+		}
+		info.Metrics = append(info.Metrics, metric)
+	}
+
+	for _, condition := range hpa.Status.Conditions {
+		info.Conditions = append(info.Conditions, HPACondition{
+			Type:    string(condition.Type),
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		})
+	}
+
+	return info
+}
+
+// metricSpecInfo describes a metric spec without its current value
+func metricSpecInfo(spec autoscalingv2.MetricSpec) HPAMetric {
+	metric := HPAMetric{Type: string(spec.Type)}
+
+	switch spec.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if spec.Resource != nil {
+			metric.Name = string(spec.Resource.Name)
+			metric.Target = formatMetricTarget(spec.Resource.Target)
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if spec.ContainerResource != nil {
+			metric.Name = string(spec.ContainerResource.Name)
+			metric.Container = spec.ContainerResource.Container
+			metric.Target = formatMetricTarget(spec.ContainerResource.Target)
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if spec.Pods != nil {
+			metric.Name = spec.Pods.Metric.Name
+			metric.Target = formatMetricTarget(spec.Pods.Target)
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if spec.Object != nil {
+			metric.Name = spec.Object.Metric.Name
+			metric.Target = formatMetricTarget(spec.Object.Target)
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if spec.External != nil {
+			metric.Name = spec.External.Metric.Name
+			metric.Target = formatMetricTarget(spec.External.Target)
+		}
+	}
+
+	return metric
+}
+
+// metricStatusValue returns the current value of a status entry if it belongs to the given metric
+func metricStatusValue(metric HPAMetric, status autoscalingv2.MetricStatus) (string, bool) {
+	if string(status.Type) != metric.Type {
+		return "", false
+	}
+
+	switch status.Type {
+	case autoscalingv2.ResourceMetricSourceType:
+		if status.Resource != nil && string(status.Resource.Name) == metric.Name {
+			return formatMetricValue(status.Resource.Current), true
+		}
+	case autoscalingv2.ContainerResourceMetricSourceType:
+		if status.ContainerResource != nil && string(status.ContainerResource.Name) == metric.Name &&
+			status.ContainerResource.Container == metric.Container {
+			return formatMetricValue(status.ContainerResource.Current), true
+		}
+	case autoscalingv2.PodsMetricSourceType:
+		if status.Pods != nil && status.Pods.Metric.Name == metric.Name {
+			return formatMetricValue(status.Pods.Current), true
+		}
+	case autoscalingv2.ObjectMetricSourceType:
+		if status.Object != nil && status.Object.Metric.Name == metric.Name {
+			return formatMetricValue(status.Object.Current), true
+		}
+	case autoscalingv2.ExternalMetricSourceType:
+		if status.External != nil && status.External.Metric.Name == metric.Name {
+			return formatMetricValue(status.External.Current), true
+		}
+	}
+
+	return "", false
+}
+
+// formatMetricTarget formats a target the way kubectl does, e.g. "80%" or "500m (avg)"
+func formatMetricTarget(target autoscalingv2.MetricTarget) string {
+	switch {
+	case target.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *target.AverageUtilization)
+	case target.AverageValue != nil:
+		return target.AverageValue.String() + " (avg)"
+	case target.Value != nil:
+		return target.Value.String()
+	default:
+		return ""
+	}
+}
+
+// formatMetricValue formats a current metric value in the same units as formatMetricTarget
+func formatMetricValue(value autoscalingv2.MetricValueStatus) string {
+	switch {
+	case value.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *value.AverageUtilization)
+	case value.AverageValue != nil:
+		return value.AverageValue.String() + " (avg)"
+	case value.Value != nil:
+		return value.Value.String()
+	default:
+		return ""
+	}
+}
+
+// ListHPAs returns the HorizontalPodAutoscalers of a namespace
+func (c *Client) ListHPAs(ctx context.Context, namespace string) ([]HPAInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	hpas, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get horizontal pod autoscalers in namespace %s: %v", namespace, err)
+	}
+
+	result := make([]HPAInfo, 0, len(hpas.Items))
+	for i := range hpas.Items {
+		result = append(result, toHPAInfo(&hpas.Items[i]))
+	}
+
+	return result, nil
+}
+
+// GetHPA returns a single HorizontalPodAutoscaler
+func (c *Client) GetHPA(ctx context.Context, namespace, name string) (*HPAInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	hpa, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get horizontal pod autoscaler %s in namespace %s: %v", name, namespace, err)
+	}
+
+	info := toHPAInfo(hpa)
+	return &info, nil
+}
+
+// UpdateHPABounds changes the min and/or max replicas of an HPA. Nil bounds are left unchanged.
+func (c *Client) UpdateHPABounds(ctx context.Context, namespace, name string, minReplicas, maxReplicas *int32, dryRun bool) (*MutationResult, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	return c.updateHPA(ctx, namespace, name, dryRun, func(hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+		if minReplicas != nil {
+			hpa.Spec.MinReplicas = minReplicas
+		}
+		if maxReplicas != nil {
+			hpa.Spec.MaxReplicas = *maxReplicas
+		}
+		return validateHPABounds(hpaMinReplicas(hpa), hpa.Spec.MaxReplicas)
+	})
+}
+
+// validateHPABounds checks the bounds before they are sent to the API server
+func validateHPABounds(minReplicas, maxReplicas int32) error {
+	if minReplicas < 1 {
+		return fmt.Errorf("minReplicas must be at least 1, got %d", minReplicas)
+	}
+	if maxReplicas < minReplicas {
+		return fmt.Errorf("maxReplicas (%d) must not be lower than minReplicas (%d)", maxReplicas, minReplicas)
+	}
+	return nil
+}
+
+// updateHPA is updateWorkload for horizontal pod autoscalers
+func (c *Client) updateHPA(ctx context.Context, namespace, name string, dryRun bool, mutate func(*autoscalingv2.HorizontalPodAutoscaler) error) (*MutationResult, error) {
+	return updateWorkload(ctx, dryRunAPI[*autoscalingv2.HorizontalPodAutoscaler](c, c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace)), kindHPA, namespace, name, dryRun,
+		func(hpa *autoscalingv2.HorizontalPodAutoscaler) interface{} { return hpa.Spec }, mutate)
+}
+
+// findWorkloadHPA returns the HPA targeting a workload, or nil if there is none
+func (c *Client) findWorkloadHPA(ctx context.Context, namespace, kind, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpas, err := c.clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get horizontal pod autoscalers in namespace %s: %v", namespace, err)
+	}
+
+	for i := range hpas.Items {
+		target := hpas.Items[i].Spec.ScaleTargetRef
+		if target.Kind == kind && target.Name == name {
+			return &hpas.Items[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// pinHPA sets both HPA bounds to replicas, so the autoscaler keeps the workload at the requested size
+func (c *Client) pinHPA(ctx context.Context, hpa *autoscalingv2.HorizontalPodAutoscaler, replicas int32, dryRun bool) (*HPAAdjustment, error) {
+	adjustment := &HPAAdjustment{
+		Name:                hpa.Name,
+		PreviousMinReplicas: hpaMinReplicas(hpa),
+		PreviousMaxReplicas: hpa.Spec.MaxReplicas,
+		MinReplicas:         replicas,
+		MaxReplicas:         replicas,
+	}
+
+	_, err := c.UpdateHPABounds(ctx, hpa.Namespace, hpa.Name, &replicas, &replicas, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	return adjustment, nil
+}
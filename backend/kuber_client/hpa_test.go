@@ -0,0 +1,126 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// testHPA returns an HPA scaling the workload of kind named web between 2 and 10 replicas
+func testHPA(kind string) *autoscalingv2.HorizontalPodAutoscaler {
+	minReplicas := int32(2)
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-hpa", Namespace: "default"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: kind, Name: "web"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    10,
+		},
+	}
+}
+
+func TestScaleWorkloadHPA(t *testing.T) {
+	tests := []struct {
+		name           string
+		hpa            *autoscalingv2.HorizontalPodAutoscaler
+		config         ServiceConfig
+		wantErr        error
+		wantReplicas   int32 // Stored replicas of the deployment, 0 when left unset
+		wantAdjustment *HPAAdjustment
+		wantBounds     [2]int32 // Stored HPA min and max replicas
+	}{
+		{
+			name:         "without an HPA",
+			config:       ServiceConfig{Replicas: 5},
+			wantReplicas: 5,
+		},
+		{
+			name:       "refused while managed by an HPA",
+			hpa:        testHPA(KindDeployment),
+			config:     ServiceConfig{Replicas: 5},
+			wantErr:    ErrManagedByHPA,
+			wantBounds: [2]int32{2, 10},
+		},
+		{
+			name:         "HPA of another kind does not guard the deployment",
+			hpa:          testHPA(KindStatefulSet),
+			config:       ServiceConfig{Replicas: 5},
+			wantReplicas: 5,
+			wantBounds:   [2]int32{2, 10},
+		},
+		{
+			name:           "HPA bounds are pinned",
+			hpa:            testHPA(KindDeployment),
+			config:         ServiceConfig{Replicas: 5, AdjustHPA: true},
+			wantReplicas:   5,
+			wantAdjustment: &HPAAdjustment{Name: "web-hpa", PreviousMinReplicas: 2, PreviousMaxReplicas: 10, MinReplicas: 5, MaxReplicas: 5},
+			wantBounds:     [2]int32{5, 5},
+		},
+		{
+			name:           "dry run leaves the HPA bounds",
+			hpa:            testHPA(KindDeployment),
+			config:         ServiceConfig{Replicas: 5, AdjustHPA: true, DryRun: true},
+			wantAdjustment: &HPAAdjustment{Name: "web-hpa", PreviousMinReplicas: 2, PreviousMaxReplicas: 10, MinReplicas: 5, MaxReplicas: 5},
+			wantBounds:     [2]int32{2, 10},
+		},
+		{
+			name:         "scaling to zero disables the HPA without pinning it",
+			hpa:          testHPA(KindDeployment),
+			config:       ServiceConfig{Replicas: 0},
+			wantReplicas: 0,
+			wantBounds:   [2]int32{2, 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{testDeployment("web:v2", 2, "")}
+			if tt.hpa != nil {
+				objects = append(objects, tt.hpa)
+			}
+			client, clientset := newTestClient(objects...)
+
+			config := tt.config
+			config.Namespace = "default"
+			config.Name = "web"
+			result, err := client.ScaleWorkload(context.Background(), config)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got result %+v and error %v, want %v", result, err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ScaleWorkload: %v", err)
+			} else if !reflect.DeepEqual(result.HPA, tt.wantAdjustment) {
+				t.Errorf("got HPA adjustment %+v, want %+v", result.HPA, tt.wantAdjustment)
+			}
+
+			deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var replicas int32
+			if deployment.Spec.Replicas != nil {
+				replicas = *deployment.Spec.Replicas
+			}
+			if replicas != tt.wantReplicas {
+				t.Errorf("deployment has %d replicas, want %d", replicas, tt.wantReplicas)
+			}
+
+			if tt.hpa == nil {
+				return
+			}
+			hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("default").Get(context.Background(), "web-hpa", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bounds := [2]int32{hpaMinReplicas(hpa), hpa.Spec.MaxReplicas}; bounds != tt.wantBounds {
+				t.Errorf("HPA bounds are %v, want %v", bounds, tt.wantBounds)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package kuberclient
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Docker Hub defaults applied when a reference has no registry
+const (
+	defaultRegistry       = "docker.io"
+	officialRepoNamespace = "library"
+)
+
+var (
+	// A single path component, e.g. "my-app" or "team_x"
+	pathComponentRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	// A tag, e.g. "v1.2.3" or "1.25-alpine"
+	tagRegexp = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	// A digest, e.g. "sha256:..."
+	digestRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]{32,}$`)
+)
+
+// ImageReference is a parsed OCI image reference: [registry[:port]/]path[:tag][@digest]
+type ImageReference struct {
+	Registry string `json:"registry,omitempty"`
+	Path     string `json:"path"`
+	Tag      string `json:"tag,omitempty"`
+	Digest   string `json:"digest,omitempty"`
+}
+
+// ParseImageReference parses an image reference such as "registry:5000/team/app:v1.2.3"
+// or "app@sha256:...". No defaults are filled in, use Repository for comparisons.
+func ParseImageReference(ref string) (ImageReference, error) {
+	var image ImageReference
+	remainder := ref
+
+	if i := strings.Index(remainder, "@"); i >= 0 {
+		image.Digest = remainder[i+1:]
+		remainder = remainder[:i]
+		if !digestRegexp.MatchString(image.Digest) {
+			return ImageReference{}, fmt.Errorf("invalid digest %q in image reference %q", image.Digest, ref)
+		}
+	}
+
+	// A tag can only follow the last path separator, a colon before it belongs to a registry port
+	if i := strings.LastIndex(remainder, ":"); i > strings.LastIndex(remainder, "/") {
+		image.Tag = remainder[i+1:]
+		remainder = remainder[:i]
+		if !tagRegexp.MatchString(image.Tag) {
+			return ImageReference{}, fmt.Errorf("invalid tag %q in image reference %q", image.Tag, ref)
+		}
+	}
+
+	// The first component is a registry if it looks like a host name
+	if i := strings.Index(remainder, "/"); i >= 0 {
+		first := remainder[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" || strings.ToLower(first) != first {
+			image.Registry = first
+			remainder = remainder[i+1:]
+		}
+	}
+
+	if remainder == "" {
+		return ImageReference{}, fmt.Errorf("missing repository in image reference %q", ref)
+	}
+	for _, component := range strings.Split(remainder, "/") {
+		if !pathComponentRegexp.MatchString(component) {
+			return ImageReference{}, fmt.Errorf("invalid repository %q in image reference %q", remainder, ref)
+		}
+	}
+	image.Path = remainder
+
+	return image, nil
+}
+
+// Name returns the reference without tag and digest, as written
+func (r ImageReference) Name() string {
+	if r.Registry == "" {
+		return r.Path
+	}
+	return r.Registry + "/" + r.Path
+}
+
+// Repository returns the fully qualified repository, so "nginx" and "docker.io/library/nginx" compare equal
+func (r ImageReference) Repository() string {
+	registry := r.Registry
+	if registry == "" || registry == "index.docker.io" {
+		registry = defaultRegistry
+	}
+
+	path := r.Path
+	if registry == defaultRegistry && !strings.Contains(path, "/") {
+		path = officialRepoNamespace + "/" + path
+	}
+
+	return registry + "/" + path
+}
+
+// Version returns the tag, or the digest for digest-only references
+func (r ImageReference) Version() string {
+	if r.Tag != "" {
+		return r.Tag
+	}
+	return r.Digest
+}
+
+// WithVersion returns the reference pinned to a new tag or, for "algo:hex" versions, a new digest
+func (r ImageReference) WithVersion(version string) (ImageReference, error) {
+	updated := ImageReference{Registry: r.Registry, Path: r.Path}
+	switch {
+	case digestRegexp.MatchString(version):
+		updated.Digest = version
+	case tagRegexp.MatchString(version):
+		updated.Tag = version
+	default:
+		return ImageReference{}, fmt.Errorf("invalid version %q, expected a tag or a digest", version)
+	}
+	return updated, nil
+}
+
+// String formats the reference back into its textual form
+func (r ImageReference) String() string {
+	ref := r.Name()
+	if r.Tag != "" {
+		ref += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		ref += "@" + r.Digest
+	}
+	return ref
+}
+
+// MatchesVersion reports whether the reference is pinned to the given tag or digest
+func (r ImageReference) MatchesVersion(version string) bool {
+	return version != "" && (r.Tag == version || r.Digest == version)
+}
+
+// SameImage reports whether two references point to the same repository, tag and digest.
+// A reference without tag and digest means "latest".
+func (r ImageReference) SameImage(other ImageReference) bool {
+	return r.Repository() == other.Repository() && r.effectiveTag() == other.effectiveTag() && r.Digest == other.Digest
+}
+
+// effectiveTag returns the tag a container runtime would pull
+func (r ImageReference) effectiveTag() string {
+	if r.Tag == "" && r.Digest == "" {
+		return "latest"
+	}
+	return r.Tag
+}
@@ -0,0 +1,165 @@
+package kuberclient
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDigest = "sha256:4f2c1c0a8b3e5d7f9a1b2c3d4e5f60718293a4b5c6d7e8f90123456789abcdef"
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want ImageReference
+	}{
+		{"nginx", ImageReference{Path: "nginx"}},
+		{"nginx:1.25-alpine", ImageReference{Path: "nginx", Tag: "1.25-alpine"}},
+		{"team/app:v1.2.3", ImageReference{Path: "team/app", Tag: "v1.2.3"}},
+		{"docker.io/library/nginx:latest", ImageReference{Registry: "docker.io", Path: "library/nginx", Tag: "latest"}},
+		{"localhost/app", ImageReference{Registry: "localhost", Path: "app"}},
+		{"localhost:5000/app:dev", ImageReference{Registry: "localhost:5000", Path: "app", Tag: "dev"}},
+		{"registry.example.com:5000/team/app:v1.2.3", ImageReference{Registry: "registry.example.com:5000", Path: "team/app", Tag: "v1.2.3"}},
+		{"registry.example.com:5000/team/app", ImageReference{Registry: "registry.example.com:5000", Path: "team/app"}},
+		{"app@" + testDigest, ImageReference{Path: "app", Digest: testDigest}},
+		{"registry.example.com:5000/team/app:v1.2.3@" + testDigest, ImageReference{Registry: "registry.example.com:5000", Path: "team/app", Tag: "v1.2.3", Digest: testDigest}},
+		{"Registry/app", ImageReference{Registry: "Registry", Path: "app"}},
+		{"my_team/my-app__x", ImageReference{Path: "my_team/my-app__x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseImageReference(tt.ref)
+			if err != nil {
+				t.Fatalf("ParseImageReference(%q): %v", tt.ref, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseImageReference(%q) = %+v, want %+v", tt.ref, got, tt.want)
+			}
+			if got.String() != tt.ref {
+				t.Errorf("ParseImageReference(%q).String() = %q, want the reference back", tt.ref, got.String())
+			}
+		})
+	}
+}
+
+func TestParseImageReferenceInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+	}{
+		{"empty", ""},
+		{"only a tag", ":v1"},
+		{"only a registry", "registry.example.com:5000/"},
+		{"uppercase path", "team/App"},
+		{"empty path component", "team//app"},
+		{"invalid tag", "app:-v1"},
+		{"tag too long", "app:" + strings.Repeat("a", 129)},
+		{"empty tag", "app:"},
+		{"short digest", "app@sha256:abc"},
+		{"digest without algorithm", "app@" + strings.Repeat("a", 64)},
+		{"empty digest", "app@"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ParseImageReference(tt.ref); err == nil {
+				t.Errorf("ParseImageReference(%q) = %+v, want an error", tt.ref, got)
+			}
+		})
+	}
+}
+
+func TestImageReferenceRepository(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"nginx", "docker.io/library/nginx"},
+		{"docker.io/nginx", "docker.io/library/nginx"},
+		{"index.docker.io/library/nginx", "docker.io/library/nginx"},
+		{"team/app", "docker.io/team/app"},
+		{"registry.example.com:5000/app", "registry.example.com:5000/app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			image, err := ParseImageReference(tt.ref)
+			if err != nil {
+				t.Fatalf("ParseImageReference(%q): %v", tt.ref, err)
+			}
+			if got := image.Repository(); got != tt.want {
+				t.Errorf("Repository() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageReferenceSameImage(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"nginx", "docker.io/library/nginx:latest", true},
+		{"nginx:1.25", "index.docker.io/library/nginx:1.25", true},
+		{"nginx:1.25", "nginx:1.26", false},
+		{"nginx", "team/nginx", false},
+		{"app@" + testDigest, "app@" + testDigest, true},
+		{"app:v1@" + testDigest, "app:v1", false},
+		{"registry.example.com:5000/app:v1", "registry.example.com/app:v1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			a, err := ParseImageReference(tt.a)
+			if err != nil {
+				t.Fatalf("ParseImageReference(%q): %v", tt.a, err)
+			}
+			b, err := ParseImageReference(tt.b)
+			if err != nil {
+				t.Fatalf("ParseImageReference(%q): %v", tt.b, err)
+			}
+			if got := a.SameImage(b); got != tt.want {
+				t.Errorf("SameImage = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageReferenceWithVersion(t *testing.T) {
+	image, err := ParseImageReference("registry.example.com:5000/team/app:v1.2.3@" + testDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"v1.3.0", "registry.example.com:5000/team/app:v1.3.0", false},
+		{testDigest, "registry.example.com:5000/team/app@" + testDigest, false},
+		{"not a tag!", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := image.WithVersion(tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("WithVersion(%q) = %q, want an error", tt.version, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WithVersion(%q): %v", tt.version, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("WithVersion(%q) = %q, want %q", tt.version, got.String(), tt.want)
+			}
+			if !got.MatchesVersion(tt.version) {
+				t.Errorf("WithVersion(%q).MatchesVersion(%q) = false", tt.version, tt.version)
+			}
+		})
+	}
+}
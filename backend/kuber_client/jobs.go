@@ -0,0 +1,493 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	kindCronJob = "CronJob"
+	kindJob     = "Job"
+
+	// instantiateAnnotation marks a Job created from a CronJob by hand, like kubectl create job --from does
+	instantiateAnnotation = "cronjob.kubernetes.io/instantiate"
+)
+
+// States of a Job
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobSuspended = "suspended"
+	JobPending   = "pending" // Created but no pod is active yet
+)
+
+// ErrJobNotFailed is returned when deleting a Job that has not failed
+var ErrJobNotFailed = errors.New("job has not failed")
+
+// JobInfo describes a Job and the pods that failed while running it
+type JobInfo struct {
+	Name           string      `json:"name"`
+	Namespace      string      `json:"namespace"`
+	CronJob        string      `json:"cronJob,omitempty"` // CronJob the Job was created by
+	Manual         bool        `json:"manual,omitempty"`  // Triggered by hand instead of by the schedule
+	Status         string      `json:"status"`
+	Reason         string      `json:"reason,omitempty"` // Why the Job failed, e.g. BackoffLimitExceeded
+	Message        string      `json:"message,omitempty"`
+	Completions    *int32      `json:"completions,omitempty"` // Unset for a Job that runs until one pod succeeds
+	Active         int32       `json:"active"`
+	Succeeded      int32       `json:"succeeded"`
+	Failed         int32       `json:"failed"`
+	StartTime      *time.Time  `json:"startTime,omitempty"`
+	CompletionTime *time.Time  `json:"completionTime,omitempty"`
+	FailedPods     []FailedPod `json:"failedPods,omitempty"`
+
+	cronJobUID types.UID // UID of the CronJob, a recreated CronJob of the same name does not own the Job
+}
+
+// FailedPod is a pod of a Job that failed, or whose container failed and was restarted
+type FailedPod struct {
+	Name       string     `json:"name"`
+	Node       string     `json:"node,omitempty"`
+	Phase      string     `json:"phase"`
+	Container  string     `json:"container,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	ExitCode   *int32     `json:"exitCode,omitempty"`
+	Restarts   int32      `json:"restarts,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// CronJobInfo describes a CronJob with the Jobs it still keeps, newest first
+type CronJobInfo struct {
+	Name               string     `json:"name"`
+	Namespace          string     `json:"namespace"`
+	Schedule           string     `json:"schedule"`
+	TimeZone           string     `json:"timeZone,omitempty"`
+	Suspended          bool       `json:"suspended"`
+	ConcurrencyPolicy  string     `json:"concurrencyPolicy"`
+	LastScheduleTime   *time.Time `json:"lastScheduleTime,omitempty"`
+	LastSuccessfulTime *time.Time `json:"lastSuccessfulTime,omitempty"`
+	LastStatus         string     `json:"lastStatus,omitempty"` // Status of the newest Job
+	ActiveJobs         []string   `json:"activeJobs"`
+	Jobs               []JobInfo  `json:"jobs"`
+}
+
+// ListCronJobs returns the CronJobs of a namespace with their Jobs
+func (c *Client) ListCronJobs(ctx context.Context, namespace string) ([]CronJobInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	cronJobs, err := c.clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cronjobs in namespace %s: %v", namespace, err)
+	}
+
+	jobs, err := c.namespaceJobs(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]CronJobInfo, 0, len(cronJobs.Items))
+	for i := range cronJobs.Items {
+		result = append(result, toCronJobInfo(&cronJobs.Items[i], jobs))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// GetCronJob returns a single CronJob with its Jobs
+func (c *Client) GetCronJob(ctx context.Context, namespace, name string) (*CronJobInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	cronJob, err := c.clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cronjob %s in namespace %s: %v", name, namespace, err)
+	}
+
+	jobs, err := c.namespaceJobs(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	info := toCronJobInfo(cronJob, jobs)
+	return &info, nil
+}
+
+// ListJobs returns the Jobs of a namespace, newest first. A non-empty status keeps only the Jobs in that state.
+func (c *Client) ListJobs(ctx context.Context, namespace, status string) ([]JobInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	jobs, err := c.namespaceJobs(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == "" {
+		return jobs, nil
+	}
+	filtered := make([]JobInfo, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Status == status {
+			filtered = append(filtered, job)
+		}
+	}
+	return filtered, nil
+}
+
+// GetJob returns a single Job with its failed pods
+func (c *Client) GetJob(ctx context.Context, namespace, name string) (*JobInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	job, err := c.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %s in namespace %s: %v", name, namespace, err)
+	}
+
+	pods, err := c.jobPods(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	info := toJobInfo(job, pods[job.UID])
+	return &info, nil
+}
+
+// TriggerCronJob runs a CronJob right away by creating a Job from its template, like
+// kubectl create job --from=cronjob/<name>. The Job is owned by the CronJob, so it counts towards its history.
+// This works for suspended CronJobs too.
+func (c *Client) TriggerCronJob(ctx context.Context, namespace, name string) (*JobInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	cronJob, err := c.clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cronjob %s in namespace %s: %v", name, namespace, err)
+	}
+
+	job := newManualJob(cronJob)
+	created, err := c.clientset.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job from cronjob %s: %v", name, err)
+	}
+
+	info := toJobInfo(created, nil)
+	return &info, nil
+}
+
+// SuspendCronJob stops a CronJob from scheduling new Jobs, Jobs already running are not affected.
+// It reports whether the CronJob was changed, false means it already was suspended.
+func (c *Client) SuspendCronJob(ctx context.Context, namespace, name string) (bool, error) {
+	return c.setCronJobSuspended(ctx, namespace, name, true)
+}
+
+// ResumeCronJob lets a suspended CronJob schedule Jobs again.
+// It reports whether the CronJob was changed, false means it was not suspended.
+func (c *Client) ResumeCronJob(ctx context.Context, namespace, name string) (bool, error) {
+	return c.setCronJobSuspended(ctx, namespace, name, false)
+}
+
+// setCronJobSuspended sets spec.suspend of a CronJob, skipping the update when it is already set
+func (c *Client) setCronJobSuspended(ctx context.Context, namespace, name string, suspend bool) (bool, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	changed := false
+	api := c.clientset.BatchV1().CronJobs(namespace)
+	_, err := updateWorkload[*batchv1.CronJob](ctx, api, kindCronJob, namespace, name, false,
+		func(cronJob *batchv1.CronJob) interface{} { return cronJob.Spec },
+		func(cronJob *batchv1.CronJob) error {
+			changed = false
+			if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend == suspend {
+				return errNoChange
+			}
+			if cronJob.Spec.Suspend == nil && !suspend {
+				return errNoChange
+			}
+			cronJob.Spec.Suspend = &suspend
+			changed = true
+			return nil
+		})
+	if err != nil {
+		return false, err
+	}
+
+	return changed, nil
+}
+
+// DeleteFailedJob deletes a failed Job together with its pods. Jobs that are running or succeeded are
+// refused with ErrJobNotFailed, they are cleaned up by their CronJob history limits or TTL.
+func (c *Client) DeleteFailedJob(ctx context.Context, namespace, name string) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	jobs := c.clientset.BatchV1().Jobs(namespace)
+	job, err := jobs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get job %s in namespace %s: %v", name, namespace, err)
+	}
+
+	if status, _, _ := jobStatus(job); status != JobFailed {
+		return fmt.Errorf("%w: job %s is %s", ErrJobNotFailed, name, status)
+	}
+
+	// Without a propagation policy the batch API orphans the pods
+	propagation := metav1.DeletePropagationBackground
+	err = jobs.Delete(ctx, name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+		Preconditions:     &metav1.Preconditions{UID: &job.UID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete job %s in namespace %s: %v", name, namespace, err)
+	}
+
+	return nil
+}
+
+// namespaceJobs returns all Jobs of a namespace with their failed pods, newest first
+func (c *Client) namespaceJobs(ctx context.Context, namespace string) ([]JobInfo, error) {
+	jobs, err := c.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs in namespace %s: %v", namespace, err)
+	}
+
+	pods, err := c.jobPods(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]JobInfo, 0, len(jobs.Items))
+	for i := range jobs.Items {
+		result = append(result, toJobInfo(&jobs.Items[i], pods[jobs.Items[i].UID]))
+	}
+	sortJobs(result)
+
+	return result, nil
+}
+
+// jobPods returns the pods of a namespace that belong to a Job, by the UID of the Job
+func (c *Client) jobPods(ctx context.Context, namespace string) (map[types.UID][]corev1.Pod, error) {
+	pods, err := c.listPods(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	byJob := make(map[types.UID][]corev1.Pod)
+	for _, pod := range pods {
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == kindJob {
+			byJob[owner.UID] = append(byJob[owner.UID], pod)
+		}
+	}
+	return byJob, nil
+}
+
+// toCronJobInfo converts a CronJob into its API representation, picking its Jobs from jobs
+func toCronJobInfo(cronJob *batchv1.CronJob, jobs []JobInfo) CronJobInfo {
+	info := CronJobInfo{
+		Name:               cronJob.Name,
+		Namespace:          cronJob.Namespace,
+		Schedule:           cronJob.Spec.Schedule,
+		Suspended:          cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+		ConcurrencyPolicy:  string(cronJob.Spec.ConcurrencyPolicy),
+		LastScheduleTime:   metaTime(cronJob.Status.LastScheduleTime),
+		LastSuccessfulTime: metaTime(cronJob.Status.LastSuccessfulTime),
+		ActiveJobs:         make([]string, 0, len(cronJob.Status.Active)),
+		Jobs:               []JobInfo{},
+	}
+	if cronJob.Spec.TimeZone != nil {
+		info.TimeZone = *cronJob.Spec.TimeZone
+	}
+	if info.ConcurrencyPolicy == "" {
+		info.ConcurrencyPolicy = string(batchv1.AllowConcurrent)
+	}
+
+	for _, active := range cronJob.Status.Active {
+		info.ActiveJobs = append(info.ActiveJobs, active.Name)
+	}
+
+	// jobs are sorted newest first
+	for _, job := range jobs {
+		if job.cronJobUID == cronJob.UID {
+			info.Jobs = append(info.Jobs, job)
+		}
+	}
+	if len(info.Jobs) > 0 {
+		info.LastStatus = info.Jobs[0].Status
+	}
+
+	return info
+}
+
+// toJobInfo converts a Job and its pods into its API representation
+func toJobInfo(job *batchv1.Job, pods []corev1.Pod) JobInfo {
+	status, reason, message := jobStatus(job)
+	info := JobInfo{
+		Name:           job.Name,
+		Namespace:      job.Namespace,
+		Manual:         job.Annotations[instantiateAnnotation] == "manual",
+		Status:         status,
+		Reason:         reason,
+		Message:        message,
+		Completions:    job.Spec.Completions,
+		Active:         job.Status.Active,
+		Succeeded:      job.Status.Succeeded,
+		Failed:         job.Status.Failed,
+		StartTime:      metaTime(job.Status.StartTime),
+		CompletionTime: metaTime(job.Status.CompletionTime),
+	}
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == kindCronJob {
+		info.CronJob = owner.Name
+		info.cronJobUID = owner.UID
+	}
+
+	for i := range pods {
+		if failed, ok := failedPod(&pods[i]); ok {
+			info.FailedPods = append(info.FailedPods, failed)
+		}
+	}
+	sort.Slice(info.FailedPods, func(i, j int) bool {
+		return info.FailedPods[i].Name < info.FailedPods[j].Name
+	})
+
+	return info
+}
+
+// jobStatus returns the state of a Job and, for failed Jobs, the reason and message of the failure
+func jobStatus(job *batchv1.Job) (string, string, string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobFailed:
+			return JobFailed, condition.Reason, condition.Message
+		case batchv1.JobComplete:
+			return JobSucceeded, "", ""
+		}
+	}
+
+	switch {
+	case job.Spec.Suspend != nil && *job.Spec.Suspend:
+		return JobSuspended, "", ""
+	case job.Status.Active > 0:
+		return JobRunning, "", ""
+	default:
+		return JobPending, "", ""
+	}
+}
+
+// failedPod reports a pod that failed, or one of whose containers exited with an error and was restarted
+func failedPod(pod *corev1.Pod) (FailedPod, bool) {
+	failed := FailedPod{
+		Name:  pod.Name,
+		Node:  pod.Spec.NodeName,
+		Phase: string(pod.Status.Phase),
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		failed.Restarts += status.RestartCount
+
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			terminated = status.LastTerminationState.Terminated
+		}
+		if terminated == nil || terminated.ExitCode == 0 || failed.Container != "" {
+			continue
+		}
+
+		exitCode := terminated.ExitCode
+		failed.Container = status.Name
+		failed.Reason = terminated.Reason
+		failed.Message = terminated.Message
+		failed.ExitCode = &exitCode
+		failed.FinishedAt = metaTime(&terminated.FinishedAt)
+	}
+
+	if pod.Status.Phase == corev1.PodFailed {
+		// Evictions and deadlines fail the pod without a container exit code
+		if pod.Status.Reason != "" {
+			failed.Reason = pod.Status.Reason
+			failed.Message = pod.Status.Message
+		}
+		return failed, true
+	}
+	return failed, failed.Container != ""
+}
+
+// newManualJob builds a Job from the template of a CronJob, owned by the CronJob
+func newManualJob(cronJob *batchv1.CronJob) *batchv1.Job {
+	// Job names end up in a pod label, which is limited to 63 characters
+	suffix := "-manual-" + rand.String(5)
+	prefix := cronJob.Name
+	if len(prefix)+len(suffix) > 63 {
+		prefix = prefix[:63-len(suffix)]
+	}
+
+	annotations := map[string]string{instantiateAnnotation: "manual"}
+	for key, value := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[key] = value
+	}
+	labels := make(map[string]string, len(cronJob.Spec.JobTemplate.Labels))
+	for key, value := range cronJob.Spec.JobTemplate.Labels {
+		labels[key] = value
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        prefix + suffix,
+			Namespace:   cronJob.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind(kindCronJob)),
+			},
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+}
+
+// sortJobs sorts Jobs newest first, Jobs that have not started yet come first
+func sortJobs(jobs []JobInfo) {
+	sort.SliceStable(jobs, func(i, j int) bool {
+		a, b := jobs[i].StartTime, jobs[j].StartTime
+		switch {
+		case a == nil || b == nil:
+			return a == nil && b != nil
+		case !a.Equal(*b):
+			return a.After(*b)
+		default:
+			return jobs[i].Name < jobs[j].Name
+		}
+	})
+}
+
+// metaTime converts an optional API time, nil and zero times become nil
+func metaTime(t *metav1.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	value := t.Time
+	return &value
+}
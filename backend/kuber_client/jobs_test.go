@@ -0,0 +1,255 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testCronJob returns a CronJob running a report container every night
+func testCronJob(name string, suspend *bool) *batchv1.CronJob {
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name + "-uid")},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 2 * * *",
+			Suspend:  suspend,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "report"}},
+				Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers:    []corev1.Container{{Name: "report", Image: "report:v1"}},
+						RestartPolicy: corev1.RestartPolicyNever,
+					},
+				}},
+			},
+		},
+	}
+}
+
+// testJob returns a Job controlled by the CronJob with ownerUID, started the given minutes after eventsStart.
+// A condition type of JobFailed or JobComplete ends the Job, an empty one leaves it running.
+func testJob(name, owner string, ownerUID types.UID, condition batchv1.JobConditionType, started int) *batchv1.Job {
+	controller := true
+	startTime := metav1.NewTime(eventsStart.Add(time.Duration(started) * time.Minute))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name + "-uid"),
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "batch/v1", Kind: kindCronJob, Name: owner, UID: ownerUID, Controller: &controller},
+			},
+		},
+		Status: batchv1.JobStatus{StartTime: &startTime},
+	}
+	if condition == "" {
+		job.Status.Active = 1
+	} else {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+	}
+	return job
+}
+
+func TestGetCronJob(t *testing.T) {
+	parallel := int32(3)
+	latest := testJob("nightly-29117160", "nightly", "nightly-uid", batchv1.JobFailed, 60)
+	latest.Spec.Completions = &parallel
+
+	client, _ := newTestClient(
+		testCronJob("nightly", nil),
+		latest,
+		testJob("nightly-29115720", "nightly", "nightly-uid", batchv1.JobComplete, 0),
+		// Left behind by a CronJob of the same name that was deleted and created again
+		testJob("nightly-29100000", "nightly", "old-nightly-uid", batchv1.JobComplete, 30),
+		testJob("hourly-29117100", "hourly", "hourly-uid", "", 90),
+	)
+
+	info, err := client.GetCronJob(context.Background(), "default", "nightly")
+	if err != nil {
+		t.Fatalf("GetCronJob: %v", err)
+	}
+
+	var names []string
+	for _, job := range info.Jobs {
+		names = append(names, job.Name)
+	}
+	if want := []string{"nightly-29117160", "nightly-29115720"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got jobs %v, want %v", names, want)
+	}
+	if info.LastStatus != JobFailed || info.ConcurrencyPolicy != string(batchv1.AllowConcurrent) {
+		t.Errorf("got last status %q and concurrency policy %q, want failed and Allow", info.LastStatus, info.ConcurrencyPolicy)
+	}
+	if len(info.Jobs) == 2 {
+		if completions := info.Jobs[0].Completions; completions == nil || *completions != 3 {
+			t.Errorf("got completions %v, want 3", completions)
+		}
+		if completions := info.Jobs[1].Completions; completions != nil {
+			t.Errorf("got completions %d for a Job without spec.completions, want none", *completions)
+		}
+	}
+}
+
+func TestTriggerCronJob(t *testing.T) {
+	suspended := true
+	longName := strings.Repeat("a", 60)
+	client, clientset := newTestClient(testCronJob("nightly", &suspended), testCronJob(longName, nil))
+
+	tests := []struct {
+		name   string
+		prefix string
+	}{
+		{"nightly", "nightly-manual-"},
+		{longName, longName[:50] + "-manual-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := client.TriggerCronJob(context.Background(), "default", tt.name)
+			if err != nil {
+				t.Fatalf("TriggerCronJob: %v", err)
+			}
+			if !strings.HasPrefix(info.Name, tt.prefix) || len(info.Name) > 63 {
+				t.Errorf("got job name %q, want prefix %q and at most 63 characters", info.Name, tt.prefix)
+			}
+			if !info.Manual || info.CronJob != tt.name {
+				t.Errorf("got job %+v, want a manual job of %s", info, tt.name)
+			}
+
+			job, err := clientset.BatchV1().Jobs("default").Get(context.Background(), info.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			owner := metav1.GetControllerOf(job)
+			if owner == nil || owner.Kind != kindCronJob || owner.UID != types.UID(tt.name+"-uid") {
+				t.Errorf("got controller %+v, want the CronJob", owner)
+			}
+			if job.Labels["app"] != "report" || job.Spec.Template.Spec.Containers[0].Image != "report:v1" {
+				t.Errorf("got job %+v, want the job template of the CronJob", job)
+			}
+		})
+	}
+
+	if _, err := client.TriggerCronJob(context.Background(), "default", "missing"); err == nil {
+		t.Error("triggered a CronJob that does not exist")
+	}
+}
+
+func TestSetCronJobSuspended(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name        string
+		suspend     *bool // spec.suspend of the CronJob
+		suspendIt   bool  // Suspend instead of resume
+		wantChanged bool
+		wantSuspend bool
+	}{
+		{"suspend", nil, true, true, true},
+		{"suspend a suspended CronJob", &yes, true, false, true},
+		{"resume", &yes, false, true, false},
+		{"resume a CronJob without spec.suspend", nil, false, false, false},
+		{"resume a running CronJob", &no, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, clientset := newTestClient(testCronJob("nightly", tt.suspend))
+
+			var updates int
+			clientset.PrependReactor("update", "cronjobs", func(k8stesting.Action) (bool, runtime.Object, error) {
+				updates++
+				return false, nil, nil
+			})
+
+			setSuspended := client.ResumeCronJob
+			if tt.suspendIt {
+				setSuspended = client.SuspendCronJob
+			}
+			changed, err := setSuspended(context.Background(), "default", "nightly")
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("got changed %v, want %v", changed, tt.wantChanged)
+			}
+			// An unchanged CronJob is not updated
+			if tt.wantChanged != (updates == 1) || updates > 1 {
+				t.Errorf("got %d updates, want changed %v", updates, tt.wantChanged)
+			}
+
+			cronJob, err := clientset.BatchV1().CronJobs("default").Get(context.Background(), "nightly", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend; suspended != tt.wantSuspend {
+				t.Errorf("CronJob is suspended: %v, want %v", suspended, tt.wantSuspend)
+			}
+		})
+	}
+}
+
+func TestDeleteFailedJob(t *testing.T) {
+	tests := []struct {
+		name        string
+		condition   batchv1.JobConditionType
+		wantErr     error
+		wantDeleted bool
+	}{
+		{"failed", batchv1.JobFailed, nil, true},
+		{"succeeded", batchv1.JobComplete, ErrJobNotFailed, false},
+		{"running", "", ErrJobNotFailed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, clientset := newTestClient(testJob("nightly-29117160", "nightly", "nightly-uid", tt.condition, 0))
+
+			var options *metav1.DeleteOptions
+			clientset.PrependReactor("delete", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				deleteOptions := action.(k8stesting.DeleteActionImpl).DeleteOptions
+				options = &deleteOptions
+				return false, nil, nil
+			})
+
+			err := client.DeleteFailedJob(context.Background(), "default", "nightly-29117160")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("DeleteFailedJob: %v", err)
+			}
+
+			_, err = clientset.BatchV1().Jobs("default").Get(context.Background(), "nightly-29117160", metav1.GetOptions{})
+			if deleted := apierrors.IsNotFound(err); deleted != tt.wantDeleted {
+				t.Errorf("job deleted: %v, want %v", deleted, tt.wantDeleted)
+			}
+			if !tt.wantDeleted {
+				return
+			}
+
+			// The pods go with the Job, and a Job recreated under the same name is not deleted
+			if options == nil || options.PropagationPolicy == nil || *options.PropagationPolicy != metav1.DeletePropagationBackground {
+				t.Errorf("got delete options %+v, want background propagation", options)
+			} else if options.Preconditions == nil || options.Preconditions.UID == nil || *options.Preconditions.UID != "nightly-29117160-uid" {
+				t.Errorf("got preconditions %+v, want the UID of the Job", options.Preconditions)
+			}
+		})
+	}
+
+	client, _ := newTestClient()
+	if err := client.DeleteFailedJob(context.Background(), "default", "missing"); err == nil || errors.Is(err, ErrJobNotFailed) {
+		t.Errorf("got error %v for a missing job, want a lookup error", err)
+	}
+}
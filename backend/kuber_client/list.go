@@ -0,0 +1,328 @@
+package kuberclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ErrInvalidListQuery is returned when a selector, sort key, limit or continue token cannot be used
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+// Sort keys of a ListQuery, prefix with "-" to reverse the order
+const (
+	SortByName     = "name"     // Namespace, then name, A to Z
+	SortByAge      = "age"      // Oldest first
+	SortByRestarts = "restarts" // Most restarts first, pods only
+)
+
+// ListQuery filters, sorts and pages a list. Selectors use the kubectl syntax and fields are limited to
+// what the API server supports for the resource, so the same query works with kubectl.
+type ListQuery struct {
+	LabelSelector string // e.g. "app=backend,tier!=cache"
+	FieldSelector string // e.g. "spec.nodeName=node-1"
+	Status        string // Case-insensitive status, e.g. Running, CrashLoopBackOff, NotReady
+	Sort          string // name (default), age or restarts
+	Limit         int    // Maximum number of items, 0 for all
+	Continue      string // Token of the previous page
+}
+
+// ListPage describes the page a ListQuery returned
+type ListPage struct {
+	Total    int    // Number of items matching the query across all pages
+	Continue string // Token for the next page, empty on the last page
+}
+
+// listSchema describes what a list can be filtered and sorted on
+type listSchema struct {
+	resource string
+	fields   []string
+	restarts bool
+}
+
+var (
+	podListSchema = listSchema{
+		resource: "pods",
+		fields: []string{"metadata.name", "metadata.namespace", "spec.nodeName", "spec.restartPolicy",
+			"spec.schedulerName", "spec.serviceAccountName", "status.phase", "status.podIP", "status.nominatedNodeName"},
+		restarts: true,
+	}
+	nodeListSchema     = listSchema{resource: "nodes", fields: []string{"metadata.name", "spec.unschedulable"}}
+	workloadListSchema = listSchema{resource: "workloads", fields: []string{"metadata.name", "metadata.namespace"}}
+)
+
+// listEntry is what a ListQuery looks at in an item
+type listEntry struct {
+	name      string
+	namespace string
+	labels    map[string]string
+	fields    fields.Set
+	statuses  []string
+	created   time.Time
+	restarts  int32
+}
+
+// applyListQuery filters, sorts and pages items listed from namespace, empty for all namespaces
+func applyListQuery[T any](items []T, query ListQuery, schema listSchema, namespace string, entry func(T) listEntry) ([]T, *ListPage, error) {
+	labelSelector, err := labels.Parse(query.LabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: labelSelector: %v", ErrInvalidListQuery, err)
+	}
+
+	fieldSelector, err := parseFieldSelector(query.FieldSelector, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	less, err := listOrder(query.Sort, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if query.Limit < 0 {
+		return nil, nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidListQuery)
+	}
+	offset, err := decodeContinue(query, schema.resource, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type matched struct {
+		item  T
+		entry listEntry
+	}
+	var matches []matched
+	for _, item := range items {
+		e := entry(item)
+		if !labelSelector.Matches(labels.Set(e.labels)) || !fieldSelector.Matches(e.fields) {
+			continue
+		}
+		if query.Status != "" && !hasStatus(e.statuses, query.Status) {
+			continue
+		}
+		matches = append(matches, matched{item: item, entry: e})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return less(matches[i].entry, matches[j].entry)
+	})
+
+	page := &ListPage{Total: len(matches)}
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	end := len(matches)
+	if query.Limit > 0 && offset+query.Limit < end {
+		end = offset + query.Limit
+		page.Continue = encodeContinue(end, query, schema.resource, namespace)
+	}
+
+	result := make([]T, 0, end-offset)
+	for _, m := range matches[offset:end] {
+		result = append(result, m.item)
+	}
+
+	return result, page, nil
+}
+
+// parseFieldSelector parses a field selector and rejects fields the API server would not accept either
+func parseFieldSelector(selector string, schema listSchema) (fields.Selector, error) {
+	parsed, err := fields.ParseSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fieldSelector: %v", ErrInvalidListQuery, err)
+	}
+
+	for _, requirement := range parsed.Requirements() {
+		supported := false
+		for _, field := range schema.fields {
+			if requirement.Field == field {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return nil, fmt.Errorf("%w: field %q is not supported for %s, use one of: %s",
+				ErrInvalidListQuery, requirement.Field, schema.resource, strings.Join(schema.fields, ", "))
+		}
+	}
+
+	return parsed, nil
+}
+
+// listOrder returns the comparison for a sort key
+func listOrder(key string, schema listSchema) (func(a, b listEntry) bool, error) {
+	reverse := strings.HasPrefix(key, "-")
+	key = strings.ToLower(strings.TrimPrefix(key, "-"))
+
+	byName := func(a, b listEntry) bool {
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		return a.name < b.name
+	}
+
+	var less func(a, b listEntry) bool
+	switch key {
+	case "", SortByName:
+		less = byName
+	case SortByAge:
+		less = func(a, b listEntry) bool {
+			if !a.created.Equal(b.created) {
+				return a.created.Before(b.created)
+			}
+			return byName(a, b)
+		}
+	case SortByRestarts:
+		if !schema.restarts {
+			return nil, fmt.Errorf("%w: sorting by restarts is not supported for %s", ErrInvalidListQuery, schema.resource)
+		}
+		less = func(a, b listEntry) bool {
+			if a.restarts != b.restarts {
+				return a.restarts > b.restarts
+			}
+			return byName(a, b)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown sort %q, use %s, %s or %s", ErrInvalidListQuery, key, SortByName, SortByAge, SortByRestarts)
+	}
+
+	if reverse {
+		return func(a, b listEntry) bool { return less(b, a) }, nil
+	}
+	return less, nil
+}
+
+// hasStatus reports whether one of the statuses matches case-insensitively
+func hasStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if strings.EqualFold(s, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeContinue turns the offset of the next page into an opaque token. The token carries a checksum of
+// the offset, the query, the resource and the namespace, so an edited token or one from a different list
+// is rejected.
+func encodeContinue(offset int, query ListQuery, resource, namespace string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset) + "." + continueChecksum(offset, query, resource, namespace)))
+}
+
+// decodeContinue returns the offset stored in the continue token of a query, 0 for an empty token
+func decodeContinue(query ListQuery, resource, namespace string) (int, error) {
+	if query.Continue == "" {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(query.Continue)
+	if err == nil {
+		offsetPart, checksum, _ := strings.Cut(string(data), ".")
+		if offset, err := strconv.Atoi(offsetPart); err == nil && offset >= 0 && checksum == continueChecksum(offset, query, resource, namespace) {
+			return offset, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: malformed continue token, or it belongs to a different query", ErrInvalidListQuery)
+}
+
+// continueChecksum hashes an offset with the list and the parts of a query that decide what the offset points at
+func continueChecksum(offset int, query ListQuery, resource, namespace string) string {
+	hash := fnv.New32a()
+	for _, part := range []string{strconv.Itoa(offset), resource, namespace,
+		query.LabelSelector, query.FieldSelector, query.Status, query.Sort, strconv.Itoa(query.Limit)} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return strconv.FormatUint(uint64(hash.Sum32()), 36)
+}
+
+// podListEntry describes a pod for a ListQuery. Its statuses are the phase and the reasons
+// containers are waiting or terminated with, e.g. CrashLoopBackOff or OOMKilled.
+func podListEntry(pod corev1.Pod) listEntry {
+	statuses := []string{string(pod.Status.Phase)}
+	for _, containerStatuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, container := range containerStatuses {
+			if container.State.Waiting != nil && container.State.Waiting.Reason != "" {
+				statuses = append(statuses, container.State.Waiting.Reason)
+			}
+			if container.State.Terminated != nil && container.State.Terminated.Reason != "" {
+				statuses = append(statuses, container.State.Terminated.Reason)
+			}
+		}
+	}
+
+	return listEntry{
+		name:      pod.Name,
+		namespace: pod.Namespace,
+		labels:    pod.Labels,
+		fields: fields.Set{
+			"metadata.name":            pod.Name,
+			"metadata.namespace":       pod.Namespace,
+			"spec.nodeName":            pod.Spec.NodeName,
+			"spec.restartPolicy":       string(pod.Spec.RestartPolicy),
+			"spec.schedulerName":       pod.Spec.SchedulerName,
+			"spec.serviceAccountName":  pod.Spec.ServiceAccountName,
+			"status.phase":             string(pod.Status.Phase),
+			"status.podIP":             pod.Status.PodIP,
+			"status.nominatedNodeName": pod.Status.NominatedNodeName,
+		},
+		statuses: statuses,
+		created:  pod.CreationTimestamp.Time,
+		restarts: podRestarts(&pod),
+	}
+}
+
+// nodeListEntry describes a node for a ListQuery, cordoned nodes also have the status SchedulingDisabled
+func nodeListEntry(node corev1.Node) listEntry {
+	statuses := []string{getNodeStatus(node)}
+	if node.Spec.Unschedulable {
+		statuses = append(statuses, "SchedulingDisabled")
+	}
+
+	return listEntry{
+		name:   node.Name,
+		labels: node.Labels,
+		fields: fields.Set{
+			"metadata.name":      node.Name,
+			"spec.unschedulable": strconv.FormatBool(node.Spec.Unschedulable),
+		},
+		statuses: statuses,
+		created:  node.CreationTimestamp.Time,
+	}
+}
+
+// workloadListEntry describes a deployment, statefulset or daemonset for a ListQuery
+func workloadListEntry(workload DeploymentInfo) listEntry {
+	return listEntry{
+		name:      workload.Name,
+		namespace: workload.Namespace,
+		labels:    workload.Labels,
+		fields: fields.Set{
+			"metadata.name":      workload.Name,
+			"metadata.namespace": workload.Namespace,
+		},
+		statuses: []string{workload.Status},
+		created:  workload.CreationTimestamp.Time,
+	}
+}
+
+// podRestarts sums the restart counts of a pod's containers
+func podRestarts(pod *corev1.Pod) int32 {
+	var restarts int32
+	for _, container := range pod.Status.InitContainerStatuses {
+		restarts += container.RestartCount
+	}
+	for _, container := range pod.Status.ContainerStatuses {
+		restarts += container.RestartCount
+	}
+	return restarts
+}
@@ -0,0 +1,201 @@
+package kuberclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testPods returns pods created a minute apart in the order given
+func testPods() []corev1.Pod {
+	created := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	pod := func(namespace, name, app, node string, phase corev1.PodPhase, restarts int32, waiting string) corev1.Pod {
+		created = created.Add(time.Minute)
+		status := corev1.ContainerStatus{Name: "app", RestartCount: restarts}
+		if waiting != "" {
+			status.State.Waiting = &corev1.ContainerStateWaiting{Reason: waiting}
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				Labels:            map[string]string{"app": app},
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Phase:             phase,
+				ContainerStatuses: []corev1.ContainerStatus{status},
+			},
+		}
+	}
+
+	return []corev1.Pod{
+		pod("production", "backend-2", "backend", "node-1", corev1.PodRunning, 7, "CrashLoopBackOff"),
+		pod("production", "backend-1", "backend", "node-2", corev1.PodRunning, 0, ""),
+		pod("default", "debug", "shell", "node-1", corev1.PodPending, 0, ""),
+		pod("production", "frontend-1", "frontend", "node-2", corev1.PodRunning, 2, ""),
+	}
+}
+
+func podNames(pods []corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestApplyListQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query ListQuery
+		want  []string
+		total int
+	}{
+		{"everything sorted by namespace and name", ListQuery{}, []string{"debug", "backend-1", "backend-2", "frontend-1"}, 4},
+		{"label selector", ListQuery{LabelSelector: "app=backend"}, []string{"backend-1", "backend-2"}, 2},
+		{"label selector with inequality", ListQuery{LabelSelector: "app!=backend"}, []string{"debug", "frontend-1"}, 2},
+		{"label selector set", ListQuery{LabelSelector: "app in (shell,frontend)"}, []string{"debug", "frontend-1"}, 2},
+		{"field selector", ListQuery{FieldSelector: "spec.nodeName=node-1"}, []string{"debug", "backend-2"}, 2},
+		{"field and label selector", ListQuery{LabelSelector: "app=backend", FieldSelector: "spec.nodeName=node-2"}, []string{"backend-1"}, 1},
+		{"phase status", ListQuery{Status: "pending"}, []string{"debug"}, 1},
+		{"container status", ListQuery{Status: "crashloopbackoff"}, []string{"backend-2"}, 1},
+		{"sort by age", ListQuery{Sort: "age"}, []string{"backend-2", "backend-1", "debug", "frontend-1"}, 4},
+		{"sort by age reversed", ListQuery{Sort: "-age"}, []string{"frontend-1", "debug", "backend-1", "backend-2"}, 4},
+		{"sort by restarts", ListQuery{Sort: "restarts"}, []string{"backend-2", "frontend-1", "debug", "backend-1"}, 4},
+		{"sort key is case-insensitive", ListQuery{Sort: "Restarts", Limit: 1}, []string{"backend-2"}, 4},
+		{"limit", ListQuery{Limit: 2}, []string{"debug", "backend-1"}, 4},
+		{"limit larger than the list", ListQuery{Limit: 10}, []string{"debug", "backend-1", "backend-2", "frontend-1"}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods, page, err := applyListQuery(testPods(), tt.query, podListSchema, "", podListEntry)
+			if err != nil {
+				t.Fatalf("applyListQuery: %v", err)
+			}
+			if got := podNames(pods); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got pods %v, want %v", got, tt.want)
+			}
+			if page.Total != tt.total {
+				t.Errorf("got total %d, want %d", page.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestApplyListQueryInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  ListQuery
+		schema listSchema
+	}{
+		{"malformed label selector", ListQuery{LabelSelector: "app in (backend"}, podListSchema},
+		{"malformed field selector", ListQuery{FieldSelector: "spec.nodeName"}, podListSchema},
+		{"unsupported field", ListQuery{FieldSelector: "spec.hostname=a"}, podListSchema},
+		{"field of another resource", ListQuery{FieldSelector: "spec.nodeName=node-1"}, nodeListSchema},
+		{"unknown sort", ListQuery{Sort: "size"}, podListSchema},
+		{"restarts sort without restarts", ListQuery{Sort: "restarts"}, workloadListSchema},
+		{"negative limit", ListQuery{Limit: -1}, podListSchema},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := applyListQuery(testPods(), tt.query, tt.schema, "", podListEntry)
+			if !errors.Is(err, ErrInvalidListQuery) {
+				t.Errorf("got error %v, want ErrInvalidListQuery", err)
+			}
+		})
+	}
+}
+
+func TestApplyListQueryPages(t *testing.T) {
+	query := ListQuery{Sort: "age", Limit: 3}
+
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 2 {
+			t.Fatal("paging did not end")
+		}
+		pods, page, err := applyListQuery(testPods(), query, podListSchema, "", podListEntry)
+		if err != nil {
+			t.Fatalf("applyListQuery: %v", err)
+		}
+		names = append(names, podNames(pods)...)
+		if page.Continue == "" {
+			break
+		}
+		query.Continue = page.Continue
+	}
+
+	want := []string{"backend-2", "backend-1", "debug", "frontend-1"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got pods %v across pages, want %v", names, want)
+	}
+}
+
+func TestDecodeContinue(t *testing.T) {
+	query := ListQuery{LabelSelector: "app=backend", Sort: "age", Limit: 2}
+	token := encodeContinue(2, query, "pods", "default")
+	raw := func(value string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+
+	tests := []struct {
+		name      string
+		query     ListQuery
+		resource  string // Resource the token is decoded for, pods if empty
+		namespace string // Namespace the token is decoded for, default if empty
+		want      int
+		valid     bool
+	}{
+		{"empty token", ListQuery{}, "", "", 0, true},
+		{"round trip", withContinue(query, token), "", "", 2, true},
+		{"not base64", withContinue(query, "not a token!"), "", "", 0, false},
+		{"not a number", withContinue(query, raw("two")), "", "", 0, false},
+		{"bare offset without checksum", withContinue(query, raw("2")), "", "", 0, false},
+		{"negative offset", withContinue(query, encodeContinue(-2, query, "pods", "default")), "", "", 0, false},
+		{"edited offset", withContinue(query, raw("4."+continueChecksum(2, query, "pods", "default"))), "", "", 0, false},
+		{"different selector", withContinue(ListQuery{LabelSelector: "app=frontend", Sort: "age", Limit: 2}, token), "", "", 0, false},
+		{"different sort", withContinue(ListQuery{LabelSelector: "app=backend", Sort: "name", Limit: 2}, token), "", "", 0, false},
+		{"different limit", withContinue(ListQuery{LabelSelector: "app=backend", Sort: "age", Limit: 5}, token), "", "", 0, false},
+		{"different namespace", withContinue(query, token), "", "kube-system", 0, false},
+		{"different resource", withContinue(query, token), "secrets", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, namespace := "pods", "default"
+			if tt.resource != "" {
+				resource = tt.resource
+			}
+			if tt.namespace != "" {
+				namespace = tt.namespace
+			}
+			got, err := decodeContinue(tt.query, resource, namespace)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidListQuery) {
+					t.Errorf("got offset %d and error %v, want ErrInvalidListQuery", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeContinue: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got offset %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func withContinue(query ListQuery, token string) ListQuery {
+	query.Continue = token
+	return query
+}
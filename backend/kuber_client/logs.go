@@ -0,0 +1,92 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotation kubectl uses to pick the container of a multi-container pod
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// LogOptions selects which part of a pod's logs to read
+type LogOptions struct {
+	Container    string
+	TailLines    *int64
+	SinceSeconds *int64
+	LimitBytes   *int64
+	Previous     bool // Logs of the previous, terminated container instance
+	Follow       bool
+}
+
+// resolveLogContainer picks the container to read logs from when none was requested
+func (c *Client) resolveLogContainer(ctx context.Context, namespace, name, container string) (string, error) {
+	if container != "" {
+		return container, nil
+	}
+
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod %s in namespace %s: %v", name, namespace, err)
+	}
+
+	if defaultContainer := pod.Annotations[defaultContainerAnnotation]; defaultContainer != "" {
+		return defaultContainer, nil
+	}
+	if len(pod.Spec.Containers) == 1 {
+		return pod.Spec.Containers[0].Name, nil
+	}
+
+	return "", fmt.Errorf("pod %s has %d containers (%s), specify which one to read logs from",
+		name, len(pod.Spec.Containers), strings.Join(containerNames(&pod.Spec), ", "))
+}
+
+// StreamPodLogs opens a stream of a pod container's logs, the caller must close it.
+// It returns the name of the container the logs belong to.
+func (c *Client) StreamPodLogs(ctx context.Context, namespace, name string, options LogOptions) (io.ReadCloser, string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	container, err := c.resolveLogContainer(ctx, namespace, name, options.Container)
+	if err != nil {
+		return nil, "", err
+	}
+
+	stream, err := c.clientset.CoreV1().Pods(namespace).GetLogs(name, &corev1.PodLogOptions{
+		Container:    container,
+		TailLines:    options.TailLines,
+		SinceSeconds: options.SinceSeconds,
+		LimitBytes:   options.LimitBytes,
+		Previous:     options.Previous,
+		Follow:       options.Follow,
+	}).Stream(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get logs of pod %s in namespace %s: %v", name, namespace, err)
+	}
+
+	return stream, container, nil
+}
+
+// GetPodLogs returns a pod container's logs, follow is ignored.
+// It returns the name of the container the logs belong to.
+func (c *Client) GetPodLogs(ctx context.Context, namespace, name string, options LogOptions) (string, string, error) {
+	options.Follow = false
+
+	stream, container, err := c.StreamPodLogs(ctx, namespace, name, options)
+	if err != nil {
+		return "", "", err
+	}
+	defer stream.Close()
+
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read logs of pod %s: %v", name, err)
+	}
+
+	return string(logs), container, nil
+}
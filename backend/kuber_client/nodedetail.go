@@ -0,0 +1,212 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodePressure tells which resources the kubelet reports to be running low on a node.
+// A node under pressure evicts pods and refuses new ones.
+type NodePressure struct {
+	Memory bool `json:"memory"`
+	Disk   bool `json:"disk"`
+	PID    bool `json:"pid"`
+}
+
+// NodeTaint keeps pods without a matching toleration away from a node
+type NodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// AllocatedResource is how much of one allocatable resource the pods on a node requested and limit
+type AllocatedResource struct {
+	Requests        string   `json:"requests"`
+	Limits          string   `json:"limits"`
+	Allocatable     string   `json:"allocatable,omitempty"`
+	RequestsPercent *float64 `json:"requestsPercent,omitempty"`
+	LimitsPercent   *float64 `json:"limitsPercent,omitempty"`
+}
+
+// Allocated sums the requests and limits of the pods running on a node, like kubectl describe node.
+// Limits can go above 100%, the node is then overcommitted.
+type Allocated struct {
+	CPU         AllocatedResource `json:"cpu"`
+	Memory      AllocatedResource `json:"memory"`
+	Pods        int               `json:"pods"`
+	PodsPercent *float64          `json:"podsPercent,omitempty"`
+}
+
+// NodePod is a pod scheduled on a node with what it requests from it
+type NodePod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Phase     string `json:"phase"`
+	Ready     bool   `json:"ready"`
+	Restarts  int32  `json:"restarts"`
+	CPU       string `json:"cpuRequests,omitempty"`
+	Memory    string `json:"memoryRequests,omitempty"`
+}
+
+// NodeDetail is a node with its health, scheduling state and the pods running on it
+type NodeDetail struct {
+	Name             string                 `json:"name"`
+	Status           string                 `json:"status"`
+	Unschedulable    bool                   `json:"unschedulable"`
+	KubeletVersion   string                 `json:"kubeletVersion"`
+	Pressure         NodePressure           `json:"pressure"`
+	Taints           []NodeTaint            `json:"taints"`
+	Allocatable      map[string]interface{} `json:"allocatable"`
+	Capacity         map[string]interface{} `json:"capacity"`
+	Allocated        Allocated              `json:"allocated"`
+	Usage            *Usage                 `json:"usage,omitempty"`
+	UsageUnavailable string                 `json:"usageUnavailable,omitempty"`
+	Pods             []NodePod              `json:"pods"`
+}
+
+// GetNodeDetail returns a node with its pressure conditions, taints, allocated resources and pods
+func (c *Client) GetNodeDetail(ctx context.Context, name string) (*NodeDetail, error) {
+	node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %v", name, err)
+	}
+
+	pods, err := c.listPods(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	scheduled := nodePods(pods)[node.Name]
+
+	detail := &NodeDetail{
+		Name:           node.Name,
+		Status:         getNodeStatus(*node),
+		Unschedulable:  node.Spec.Unschedulable,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+		Pressure:       nodePressure(node),
+		Taints:         nodeTaints(node),
+		Allocatable:    resourceListToMap(node.Status.Allocatable),
+		Capacity:       resourceListToMap(node.Status.Capacity),
+		Allocated:      nodeAllocated(node, scheduled),
+		Pods:           make([]NodePod, 0, len(scheduled)),
+	}
+
+	for i := range scheduled {
+		pod := &scheduled[i]
+		requests, _ := podResources(pod)
+		entry := NodePod{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Phase:     string(pod.Status.Phase),
+			Ready:     podReady(pod),
+			Restarts:  podRestarts(pod),
+		}
+		if cpu, ok := requests[corev1.ResourceCPU]; ok {
+			entry.CPU = cpu.String()
+		}
+		if memory, ok := requests[corev1.ResourceMemory]; ok {
+			entry.Memory = memory.String()
+		}
+		detail.Pods = append(detail.Pods, entry)
+	}
+	sort.Slice(detail.Pods, func(i, j int) bool {
+		if detail.Pods[i].Namespace != detail.Pods[j].Namespace {
+			return detail.Pods[i].Namespace < detail.Pods[j].Namespace
+		}
+		return detail.Pods[i].Name < detail.Pods[j].Name
+	})
+
+	// Live usage is optional, the node is still reported without metrics-server
+	usage, err := c.listNodeUsage(ctx)
+	if err != nil {
+		detail.UsageUnavailable = err.Error()
+	} else if used, ok := usage[node.Name]; ok {
+		detail.Usage = nodeUsage(used, node.Status.Allocatable)
+	}
+
+	return detail, nil
+}
+
+// nodePods groups the pods that hold resources on a node by node name.
+// Pods that succeeded or failed no longer count against the node.
+func nodePods(pods []corev1.Pod) map[string][]corev1.Pod {
+	byNode := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		byNode[pod.Spec.NodeName] = append(byNode[pod.Spec.NodeName], pod)
+	}
+	return byNode
+}
+
+// nodePressure reads the pressure conditions of a node
+func nodePressure(node *corev1.Node) NodePressure {
+	var pressure NodePressure
+	for _, condition := range node.Status.Conditions {
+		underPressure := condition.Status == corev1.ConditionTrue
+		switch condition.Type {
+		case corev1.NodeMemoryPressure:
+			pressure.Memory = underPressure
+		case corev1.NodeDiskPressure:
+			pressure.Disk = underPressure
+		case corev1.NodePIDPressure:
+			pressure.PID = underPressure
+		}
+	}
+	return pressure
+}
+
+// nodeTaints lists the taints of a node
+func nodeTaints(node *corev1.Node) []NodeTaint {
+	taints := make([]NodeTaint, 0, len(node.Spec.Taints))
+	for _, taint := range node.Spec.Taints {
+		taints = append(taints, NodeTaint{
+			Key:    taint.Key,
+			Value:  taint.Value,
+			Effect: string(taint.Effect),
+		})
+	}
+	return taints
+}
+
+// nodeAllocated sums the effective requests and limits of the pods on a node, see podResources,
+// and compares them with its allocatable resources
+func nodeAllocated(node *corev1.Node, pods []corev1.Pod) Allocated {
+	requests := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+	for i := range pods {
+		podRequests, podLimits := podResources(&pods[i])
+		addResources(requests, podRequests)
+		addResources(limits, podLimits)
+	}
+
+	allocated := Allocated{Pods: len(pods)}
+	for _, entry := range []struct {
+		name   corev1.ResourceName
+		target *AllocatedResource
+	}{
+		{corev1.ResourceCPU, &allocated.CPU},
+		{corev1.ResourceMemory, &allocated.Memory},
+	} {
+		request, limit := requests[entry.name], limits[entry.name]
+		entry.target.Requests = request.String()
+		entry.target.Limits = limit.String()
+		if total, ok := node.Status.Allocatable[entry.name]; ok {
+			entry.target.Allocatable = total.String()
+			entry.target.RequestsPercent = percentOf(request, total)
+			entry.target.LimitsPercent = percentOf(limit, total)
+		}
+	}
+
+	if total, ok := node.Status.Allocatable[corev1.ResourcePods]; ok {
+		allocated.PodsPercent = percentOf(*resource.NewQuantity(int64(len(pods)), resource.DecimalSI), total)
+	}
+
+	return allocated
+}
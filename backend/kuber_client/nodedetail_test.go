@@ -0,0 +1,79 @@
+package kuberclient
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeAllocated(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("4Gi"),
+			corev1.ResourcePods:   resource.MustParse("20"),
+		}},
+	}
+
+	pod := func(name string, phase corev1.PodPhase, spec corev1.PodSpec) corev1.Pod {
+		spec.NodeName = "node-1"
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       spec,
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	pods := nodePods([]corev1.Pod{
+		// The init container outweighs the regular container, the overhead comes on top
+		pod("web", corev1.PodRunning, corev1.PodSpec{
+			InitContainers: []corev1.Container{testContainer("setup", "500m", "1Gi")},
+			Containers:     []corev1.Container{testContainer("app", "250m", "256Mi")},
+			Overhead:       resourceList("100m", "128Mi"),
+		}),
+		pod("worker", corev1.PodPending, corev1.PodSpec{
+			Containers: []corev1.Container{testContainer("app", "400m", "512Mi")},
+		}),
+		// Finished pods reserve nothing
+		pod("migration", corev1.PodSucceeded, corev1.PodSpec{
+			Containers: []corev1.Container{testContainer("app", "1", "1Gi")},
+		}),
+	})["node-1"]
+
+	allocated := nodeAllocated(node, pods)
+
+	percent := func(value *float64) float64 {
+		if value == nil {
+			return -1
+		}
+		return *value
+	}
+	tests := []struct {
+		name            string
+		got             AllocatedResource
+		requests        string
+		limits          string
+		requestsPercent float64
+		limitsPercent   float64
+	}{
+		{"cpu", allocated.CPU, "1", "1900m", 50, 95},
+		{"memory", allocated.Memory, "1664Mi", "3200Mi", 40.6, 78.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got.Requests != tt.requests || tt.got.Limits != tt.limits {
+				t.Errorf("got requests %s and limits %s, want %s and %s", tt.got.Requests, tt.got.Limits, tt.requests, tt.limits)
+			}
+			if percent(tt.got.RequestsPercent) != tt.requestsPercent || percent(tt.got.LimitsPercent) != tt.limitsPercent {
+				t.Errorf("got %v%% and %v%%, want %v%% and %v%%",
+					percent(tt.got.RequestsPercent), percent(tt.got.LimitsPercent), tt.requestsPercent, tt.limitsPercent)
+			}
+		})
+	}
+
+	if allocated.Pods != 2 || percent(allocated.PodsPercent) != 10 {
+		t.Errorf("got %d pods (%v%%), want 2 (10%%)", allocated.Pods, percent(allocated.PodsPercent))
+	}
+}
@@ -0,0 +1,241 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// Default time a drain waits for all pods to be evicted
+const defaultDrainTimeout = 5 * time.Minute
+
+var (
+	// Pause between eviction attempts blocked by a PodDisruptionBudget
+	evictionRetryInterval = 5 * time.Second
+	// Pause between checks whether an evicted pod is gone
+	podDeletionPollInterval = 2 * time.Second
+)
+
+// Per-pod drain states
+const (
+	DrainPodSkipped  = "skipped"
+	DrainPodEvicting = "evicting"
+	DrainPodBlocked  = "blocked"
+	DrainPodEvicted  = "evicted"
+	DrainPodFailed   = "failed"
+)
+
+// DrainOptions configures a node drain
+type DrainOptions struct {
+	Force              bool          // Also evict pods that are not managed by a controller
+	Timeout            time.Duration // Time to wait for all evictions, defaults to 5 minutes
+	GracePeriodSeconds *int64        // Overrides the pods' termination grace period
+}
+
+// DrainPodStatus is the drain progress of a single pod
+type DrainPodStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+}
+
+// DrainResult is the outcome of a node drain
+type DrainResult struct {
+	Node      string           `json:"node"`
+	Pods      []DrainPodStatus `json:"pods"`
+	Completed bool             `json:"completed"`
+}
+
+// setNodeUnschedulable cordons or uncordons a node
+func (c *Client) setNodeUnschedulable(ctx context.Context, name string, unschedulable bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := c.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get node %s: %v", name, err)
+		}
+
+		if node.Spec.Unschedulable == unschedulable {
+			return nil
+		}
+		node.Spec.Unschedulable = unschedulable
+
+		_, err = c.clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// CordonNode marks a node as unschedulable
+func (c *Client) CordonNode(ctx context.Context, name string) error {
+	return c.setNodeUnschedulable(ctx, name, true)
+}
+
+// UncordonNode marks a node as schedulable again
+func (c *Client) UncordonNode(ctx context.Context, name string) error {
+	return c.setNodeUnschedulable(ctx, name, false)
+}
+
+// DrainNode cordons a node and evicts its pods through the Eviction API, so PodDisruptionBudgets are respected.
+// DaemonSet and mirror pods are skipped. onProgress, if set, is called on every per-pod state change.
+func (c *Client) DrainNode(ctx context.Context, name string, options DrainOptions, onProgress func(DrainPodStatus)) (*DrainResult, error) {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultDrainTimeout
+	}
+
+	if err := c.CordonNode(ctx, name); err != nil {
+		return nil, err
+	}
+
+	pods, err := c.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", name).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods on node %s: %v", name, err)
+	}
+
+	result := &DrainResult{Node: name}
+	var mu sync.Mutex
+	report := func(status DrainPodStatus) {
+		mu.Lock()
+		defer mu.Unlock()
+		if onProgress != nil {
+			onProgress(status)
+		}
+	}
+
+	var toEvict []corev1.Pod
+	var unmanaged []string
+	for _, pod := range pods.Items {
+		// Guard against clientsets that ignore the field selector
+		if pod.Spec.NodeName != name {
+			continue
+		}
+		if reason := drainSkipReason(&pod); reason != "" {
+			status := DrainPodStatus{Name: pod.Name, Namespace: pod.Namespace, Status: DrainPodSkipped, Message: reason}
+			result.Pods = append(result.Pods, status)
+			report(status)
+			continue
+		}
+		if metav1.GetControllerOf(&pod) == nil && !options.Force {
+			unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+		}
+		toEvict = append(toEvict, pod)
+	}
+
+	// Like kubectl, refuse to delete pods nothing would recreate unless forced
+	if len(unmanaged) > 0 {
+		return nil, fmt.Errorf("node %s is cordoned, but these pods are not managed by a controller and need force: %s",
+			name, strings.Join(unmanaged, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	statuses := make([]DrainPodStatus, len(toEvict))
+	var wg sync.WaitGroup
+	for i := range toEvict {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i] = c.evictPod(ctx, &toEvict[i], options.GracePeriodSeconds, report)
+		}(i)
+	}
+	wg.Wait()
+
+	result.Completed = true
+	for _, status := range statuses {
+		if status.Status != DrainPodEvicted {
+			result.Completed = false
+		}
+	}
+	result.Pods = append(result.Pods, statuses...)
+
+	return result, nil
+}
+
+// drainSkipReason returns why a pod is left on the node during a drain, or an empty string
+func drainSkipReason(pod *corev1.Pod) string {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return "mirror pod, managed by the kubelet"
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == KindDaemonSet {
+		return fmt.Sprintf("managed by DaemonSet %s", owner.Name)
+	}
+	return ""
+}
+
+// evictPod evicts a pod, retrying while a PodDisruptionBudget blocks it, and waits until it is gone
+func (c *Client) evictPod(ctx context.Context, pod *corev1.Pod, gracePeriodSeconds *int64, report func(DrainPodStatus)) DrainPodStatus {
+	status := DrainPodStatus{Name: pod.Name, Namespace: pod.Namespace, Status: DrainPodEvicting}
+	report(status)
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: gracePeriodSeconds,
+		},
+	}
+
+	for {
+		err := c.clientset.CoreV1().Pods(pod.Namespace).EvictV1(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+
+		if !apierrors.IsTooManyRequests(err) {
+			status.Status = DrainPodFailed
+			status.Message = fmt.Sprintf("eviction failed: %v", err)
+			report(status)
+			return status
+		}
+
+		// Evicting now would violate a PodDisruptionBudget, try again later
+		if status.Status != DrainPodBlocked {
+			status.Status = DrainPodBlocked
+			status.Message = fmt.Sprintf("blocked by PodDisruptionBudget: %v", err)
+			report(status)
+		}
+
+		select {
+		case <-ctx.Done():
+			status.Status = DrainPodFailed
+			status.Message = fmt.Sprintf("timed out while blocked by PodDisruptionBudget: %v", err)
+			report(status)
+			return status
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+
+	// Wait until the pod is deleted or replaced by a new pod with the same name
+	err := wait.PollUntilContextCancel(ctx, podDeletionPollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := c.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return current.UID != pod.UID, nil
+	})
+	if err != nil {
+		status.Status = DrainPodFailed
+		status.Message = fmt.Sprintf("evicted but not deleted in time: %v", err)
+		report(status)
+		return status
+	}
+
+	status.Status = DrainPodEvicted
+	status.Message = ""
+	report(status)
+	return status
+}
@@ -0,0 +1,193 @@
+package kuberclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testNodePod returns a pod on node, controlled by an owner of kind unless kind is empty
+func testNodePod(name, node, kind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name + "-uid")},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+	if kind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: kind, Name: name + "-owner", UID: types.UID(name + "-owner-uid"), Controller: &controller},
+		}
+	}
+	return pod
+}
+
+// reactEvictions deletes evicted pods, after answering the first blocked evictions of every pod
+// with 429 like a PodDisruptionBudget does. A negative blocked never lets an eviction through.
+func reactEvictions(clientset *fake.Clientset, blocked int) *atomic.Int32 {
+	var evictions atomic.Int32
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		evictions.Add(1)
+
+		mu.Lock()
+		attempts[eviction.Name]++
+		attempt := attempts[eviction.Name]
+		mu.Unlock()
+
+		if blocked < 0 || attempt <= blocked {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		podsResource := corev1.SchemeGroupVersion.WithResource("pods")
+		return true, nil, clientset.Tracker().Delete(podsResource, action.GetNamespace(), eviction.Name)
+	})
+
+	return &evictions
+}
+
+func TestDrainNode(t *testing.T) {
+	retryInterval, pollInterval := evictionRetryInterval, podDeletionPollInterval
+	evictionRetryInterval, podDeletionPollInterval = 10*time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() {
+		evictionRetryInterval, podDeletionPollInterval = retryInterval, pollInterval
+	})
+
+	mirror := testNodePod("kube-proxy", "node-1", "")
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "mirror"}
+
+	tests := []struct {
+		name          string
+		pods          []runtime.Object
+		options       DrainOptions
+		blocked       int
+		want          map[string]string // Final status by pod
+		wantBlocked   bool              // A pod was reported blocked by a PodDisruptionBudget
+		wantCompleted bool
+		wantErr       bool
+	}{
+		{
+			name: "skips DaemonSet and mirror pods",
+			pods: []runtime.Object{
+				testNodePod("web", "node-1", "ReplicaSet"),
+				testNodePod("agent", "node-1", KindDaemonSet),
+				mirror,
+				testNodePod("elsewhere", "node-2", "ReplicaSet"),
+			},
+			want: map[string]string{
+				"web":        DrainPodEvicted,
+				"agent":      DrainPodSkipped,
+				"kube-proxy": DrainPodSkipped,
+			},
+			wantCompleted: true,
+		},
+		{
+			name: "refuses unmanaged pods",
+			pods: []runtime.Object{
+				testNodePod("web", "node-1", "ReplicaSet"),
+				testNodePod("debug", "node-1", ""),
+			},
+			wantErr: true,
+		},
+		{
+			name: "force evicts unmanaged pods",
+			pods: []runtime.Object{
+				testNodePod("web", "node-1", "ReplicaSet"),
+				testNodePod("debug", "node-1", ""),
+			},
+			options: DrainOptions{Force: true},
+			want: map[string]string{
+				"web":   DrainPodEvicted,
+				"debug": DrainPodEvicted,
+			},
+			wantCompleted: true,
+		},
+		{
+			name:          "retries while a PodDisruptionBudget blocks",
+			pods:          []runtime.Object{testNodePod("web", "node-1", "ReplicaSet")},
+			blocked:       2,
+			want:          map[string]string{"web": DrainPodEvicted},
+			wantBlocked:   true,
+			wantCompleted: true,
+		},
+		{
+			name:          "times out while a PodDisruptionBudget blocks",
+			pods:          []runtime.Object{testNodePod("web", "node-1", "ReplicaSet")},
+			options:       DrainOptions{Timeout: 100 * time.Millisecond},
+			blocked:       -1,
+			want:          map[string]string{"web": DrainPodFailed},
+			wantBlocked:   true,
+			wantCompleted: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}}, tt.pods...)
+			client, clientset := newTestClient(objects...)
+			evictions := reactEvictions(clientset, tt.blocked)
+
+			blocked := false
+			result, err := client.DrainNode(context.Background(), "node-1", tt.options, func(status DrainPodStatus) {
+				if status.Status == DrainPodBlocked {
+					blocked = true
+				}
+			})
+
+			node, getErr := clientset.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+			if getErr != nil {
+				t.Fatal(getErr)
+			}
+			if !node.Spec.Unschedulable {
+				t.Error("node was not cordoned")
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("DrainNode = %+v, want an error", result)
+				}
+				if n := evictions.Load(); n != 0 {
+					t.Errorf("got %d evictions, want none when the drain is refused", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DrainNode: %v", err)
+			}
+
+			got := make(map[string]string)
+			for _, pod := range result.Pods {
+				got[pod.Name] = pod.Status
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got pods %v, want %v", got, tt.want)
+			}
+			for name, status := range tt.want {
+				if got[name] != status {
+					t.Errorf("pod %s is %q, want %q", name, got[name], status)
+				}
+			}
+			if blocked != tt.wantBlocked {
+				t.Errorf("got blocked %v, want %v", blocked, tt.wantBlocked)
+			}
+			if result.Completed != tt.wantCompleted {
+				t.Errorf("got completed %v, want %v", result.Completed, tt.wantCompleted)
+			}
+		})
+	}
+}
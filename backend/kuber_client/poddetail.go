@@ -0,0 +1,279 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const kindReplicaSet = "ReplicaSet"
+
+const (
+	ContainerRunning    = "running"
+	ContainerWaiting    = "waiting"
+	ContainerTerminated = "terminated"
+)
+
+// PodOwner is the workload a pod belongs to. Pods of a ReplicaSet report its Deployment
+// and pods of a Job its CronJob, ReplicaSet and Job only show up when they stand alone.
+type PodOwner struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Termination describes the last time a container stopped
+type Termination struct {
+	Reason     string     `json:"reason,omitempty"`
+	Message    string     `json:"message,omitempty"`
+	ExitCode   int32      `json:"exitCode"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// ContainerDetail is the status of one container of a pod
+type ContainerDetail struct {
+	Name            string       `json:"name"`
+	Init            bool         `json:"init,omitempty"`
+	Image           string       `json:"image"`
+	ImageID         string       `json:"imageID,omitempty"`
+	Ready           bool         `json:"ready"`
+	RestartCount    int32        `json:"restartCount"`
+	State           string       `json:"state,omitempty"` // running, waiting or terminated, empty before the kubelet reports
+	Reason          string       `json:"reason,omitempty"`
+	Message         string       `json:"message,omitempty"`
+	StartedAt       *time.Time   `json:"startedAt,omitempty"`
+	LastTermination *Termination `json:"lastTermination,omitempty"`
+}
+
+// PodDetail is a pod with the status of each of its containers
+type PodDetail struct {
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace"`
+	Phase      string            `json:"phase"`
+	Reason     string            `json:"reason,omitempty"`
+	Ready      bool              `json:"ready"`
+	Node       string            `json:"node,omitempty"`
+	HostIP     string            `json:"hostIP,omitempty"`
+	PodIP      string            `json:"podIP,omitempty"`
+	QOSClass   string            `json:"qosClass"`
+	StartTime  *time.Time        `json:"startTime,omitempty"`
+	Restarts   int32             `json:"restarts"`
+	Owner      *PodOwner         `json:"owner,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Containers []ContainerDetail `json:"containers"`
+}
+
+// GetPodDetail returns a pod with its container statuses and owning workload
+func (c *Client) GetPodDetail(ctx context.Context, namespace, name string) (*PodDetail, error) {
+	pod, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod %s: %v", name, err)
+	}
+
+	detail := podDetail(pod)
+	owner, err := newOwnerResolver(c).resolve(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
+	detail.Owner = owner
+
+	return &detail, nil
+}
+
+// podDetails describes the given pods, resolving each owning workload once
+func (c *Client) podDetails(ctx context.Context, pods []corev1.Pod) ([]PodDetail, error) {
+	resolver := newOwnerResolver(c)
+
+	details := make([]PodDetail, 0, len(pods))
+	for i := range pods {
+		detail := podDetail(&pods[i])
+		owner, err := resolver.resolve(ctx, &pods[i])
+		if err != nil {
+			return nil, err
+		}
+		detail.Owner = owner
+		details = append(details, detail)
+	}
+	return details, nil
+}
+
+// podDetail describes a pod without its owner, which takes extra API calls
+func podDetail(pod *corev1.Pod) PodDetail {
+	detail := PodDetail{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Phase:     string(pod.Status.Phase),
+		Reason:    pod.Status.Reason,
+		Ready:     podReady(pod),
+		Node:      pod.Spec.NodeName,
+		HostIP:    pod.Status.HostIP,
+		PodIP:     pod.Status.PodIP,
+		QOSClass:  string(podQOSClass(pod)),
+		StartTime: metaTime(pod.Status.StartTime),
+		Restarts:  podRestarts(pod),
+		Labels:    pod.Labels,
+	}
+
+	detail.Containers = append(detail.Containers, containerDetails(pod.Spec.InitContainers, pod.Status.InitContainerStatuses, true)...)
+	detail.Containers = append(detail.Containers, containerDetails(pod.Spec.Containers, pod.Status.ContainerStatuses, false)...)
+
+	return detail
+}
+
+// containerDetails merges container specs with their statuses in spec order.
+// Containers the kubelet has not reported yet only carry their name and image.
+func containerDetails(containers []corev1.Container, statuses []corev1.ContainerStatus, init bool) []ContainerDetail {
+	byName := make(map[string]corev1.ContainerStatus, len(statuses))
+	for _, status := range statuses {
+		byName[status.Name] = status
+	}
+
+	details := make([]ContainerDetail, 0, len(containers))
+	for _, container := range containers {
+		detail := ContainerDetail{
+			Name:  container.Name,
+			Init:  init,
+			Image: container.Image,
+		}
+
+		if status, ok := byName[container.Name]; ok {
+			detail.ImageID = status.ImageID
+			detail.Ready = status.Ready
+			detail.RestartCount = status.RestartCount
+
+			switch state := status.State; {
+			case state.Running != nil:
+				detail.State = ContainerRunning
+				detail.StartedAt = metaTime(&state.Running.StartedAt)
+			case state.Waiting != nil:
+				detail.State = ContainerWaiting
+				detail.Reason = state.Waiting.Reason
+				detail.Message = state.Waiting.Message
+			case state.Terminated != nil:
+				detail.State = ContainerTerminated
+				detail.Reason = state.Terminated.Reason
+				detail.Message = state.Terminated.Message
+				detail.StartedAt = metaTime(&state.Terminated.StartedAt)
+			}
+
+			if last := status.LastTerminationState.Terminated; last != nil {
+				detail.LastTermination = &Termination{
+					Reason:     last.Reason,
+					Message:    last.Message,
+					ExitCode:   last.ExitCode,
+					StartedAt:  metaTime(&last.StartedAt),
+					FinishedAt: metaTime(&last.FinishedAt),
+				}
+			}
+		}
+
+		details = append(details, detail)
+	}
+	return details
+}
+
+// podReady reports whether the Ready condition of a pod is true
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podQOSClass returns the QoS class the API server assigned to a pod, or derives it from the
+// container resources for pods that have not been admitted yet, e.g. in the sandbox
+func podQOSClass(pod *corev1.Pod) corev1.PodQOSClass {
+	if pod.Status.QOSClass != "" {
+		return pod.Status.QOSClass
+	}
+
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+
+	bestEffort, guaranteed := true, true
+	for _, container := range containers {
+		requests, limits := container.Resources.Requests, container.Resources.Limits
+		if len(requests) > 0 || len(limits) > 0 {
+			bestEffort = false
+		}
+		for _, resource := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			limit, ok := limits[resource]
+			if !ok {
+				guaranteed = false
+				continue
+			}
+			// Requests default to limits when left out
+			if request, ok := requests[resource]; ok && request.Cmp(limit) != 0 {
+				guaranteed = false
+			}
+		}
+	}
+
+	switch {
+	case bestEffort:
+		return corev1.PodQOSBestEffort
+	case guaranteed:
+		return corev1.PodQOSGuaranteed
+	default:
+		return corev1.PodQOSBurstable
+	}
+}
+
+// ownerResolver finds the workloads pods belong to, looking each intermediate owner up only once
+type ownerResolver struct {
+	client *Client
+	// Workload of each ReplicaSet or Job by namespace/kind/name, nil when it stands alone
+	parents map[string]*PodOwner
+}
+
+func newOwnerResolver(c *Client) *ownerResolver {
+	return &ownerResolver{
+		client:  c,
+		parents: make(map[string]*PodOwner),
+	}
+}
+
+// resolve returns the workload of a pod, or nil for a bare pod
+func (r *ownerResolver) resolve(ctx context.Context, pod *corev1.Pod) (*PodOwner, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return nil, nil
+	}
+
+	owner := &PodOwner{Kind: ref.Kind, Name: ref.Name}
+	if ref.Kind != kindReplicaSet && ref.Kind != kindJob {
+		return owner, nil
+	}
+
+	key := pod.Namespace + "/" + ref.Kind + "/" + ref.Name
+	parent, ok := r.parents[key]
+	if !ok {
+		var object metav1.Object
+		var err error
+		if ref.Kind == kindJob {
+			object, err = r.client.clientset.BatchV1().Jobs(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		} else {
+			object, err = r.client.clientset.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		}
+		// An owner that is already gone leaves the pod with its direct owner
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get owner %s %s of pod %s: %v", ref.Kind, ref.Name, pod.Name, err)
+		}
+		if err == nil {
+			if parentRef := metav1.GetControllerOf(object); parentRef != nil {
+				parent = &PodOwner{Kind: parentRef.Kind, Name: parentRef.Name}
+			}
+		}
+		r.parents[key] = parent
+	}
+
+	if parent != nil {
+		return parent, nil
+	}
+	return owner, nil
+}
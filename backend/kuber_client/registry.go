@@ -0,0 +1,265 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// inClusterSource selects the service account of the pod the backend runs in
+const inClusterSource = "in-cluster"
+
+// ErrUnknownCluster is returned when a request names a cluster that is not registered
+var ErrUnknownCluster = errors.New("unknown cluster")
+
+// ClusterConfig describes how to connect to a named cluster.
+// Without Kubeconfig and Context the in-cluster config is tried first, then the default kubeconfig.
+type ClusterConfig struct {
+	Name       string
+	Kubeconfig string // Path to a kubeconfig file, defaults to $KUBECONFIG or ~/.kube/config
+	Context    string // Kubeconfig context, defaults to the current context
+	InCluster  bool   // Use the in-cluster service account
+}
+
+// ParseClusters parses a cluster list such as
+// "prod-eu=/etc/kube/prod.yaml,staging=@staging,local=in-cluster".
+// Each entry is name=[kubeconfig path][@context] or name=in-cluster.
+func ParseClusters(spec string) ([]ClusterConfig, error) {
+	var clusters []ClusterConfig
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, source, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		source = strings.TrimSpace(source)
+		if !ok || name == "" || source == "" {
+			return nil, fmt.Errorf("invalid cluster %q, expected name=kubeconfig[@context] or name=%s", entry, inClusterSource)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("cluster %s is defined twice", name)
+		}
+		seen[name] = true
+
+		cluster := ClusterConfig{Name: name}
+		if source == inClusterSource {
+			cluster.InCluster = true
+		} else {
+			cluster.Kubeconfig, cluster.Context, _ = strings.Cut(source, "@")
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
+}
+
+// restConfig builds the client configuration of a cluster
+func (cc ClusterConfig) restConfig() (*rest.Config, error) {
+	if cc.InCluster {
+		return rest.InClusterConfig()
+	}
+
+	if cc.Kubeconfig == "" && cc.Context == "" {
+		// Try in-cluster config first (for running inside a pod)
+		if config, err := rest.InClusterConfig(); err == nil {
+			return config, nil
+		}
+	}
+
+	// Fall back to kubeconfig file, $KUBECONFIG is honoured when no path is given
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if cc.Kubeconfig != "" {
+		rules.ExplicitPath = cc.Kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: cc.Context}
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config from kubeconfig: %v", err)
+	}
+	return config, nil
+}
+
+// Registry holds a client per configured cluster
+type Registry struct {
+	clients        map[string]*Client
+	failures       map[string]error
+	names          []string
+	defaultCluster string
+}
+
+// ClusterHealth is the result of a health check against one cluster
+type ClusterHealth struct {
+	Name      string       `json:"name"`
+	Default   bool         `json:"default"`
+	Healthy   bool         `json:"healthy"`
+	Version   string       `json:"version,omitempty"`
+	LatencyMs int64        `json:"latencyMs"`
+	Error     string       `json:"error,omitempty"`
+	Cache     *CacheStatus `json:"cache,omitempty"`
+}
+
+// NewRegistry creates clients for the given clusters. Without clusters a single cluster named
+// "default" is registered. A cluster whose client cannot be created is kept and reported as
+// unhealthy, so one broken kubeconfig does not take the others down.
+func NewRegistry(clusters []ClusterConfig, defaultCluster string) (*Registry, error) {
+	if len(clusters) == 0 {
+		clusters = []ClusterConfig{{Name: "default"}}
+	}
+	if defaultCluster == "" {
+		defaultCluster = clusters[0].Name
+	}
+
+	registry := &Registry{
+		clients:        make(map[string]*Client),
+		failures:       make(map[string]error),
+		defaultCluster: defaultCluster,
+	}
+
+	for _, cluster := range clusters {
+		registry.names = append(registry.names, cluster.Name)
+
+		client, err := NewClientForCluster(cluster)
+		if err != nil {
+			registry.failures[cluster.Name] = err
+			continue
+		}
+		registry.clients[cluster.Name] = client
+	}
+
+	if _, ok := registry.clients[defaultCluster]; !ok {
+		if _, failed := registry.failures[defaultCluster]; !failed {
+			return nil, fmt.Errorf("default cluster %s is not configured", defaultCluster)
+		}
+	}
+
+	return registry, nil
+}
+
+// Get returns the client of a cluster, an empty name selects the default cluster
+func (r *Registry) Get(name string) (*Client, error) {
+	if name == "" {
+		name = r.defaultCluster
+	}
+
+	if client, ok := r.clients[name]; ok {
+		return client, nil
+	}
+	if err, ok := r.failures[name]; ok {
+		return nil, fmt.Errorf("cluster %s is not available: %v", name, err)
+	}
+
+	return nil, fmt.Errorf("%w %q, known clusters: %s", ErrUnknownCluster, name, strings.Join(r.names, ", "))
+}
+
+// Default returns the name of the default cluster
+func (r *Registry) Default() string {
+	return r.defaultCluster
+}
+
+// Names returns the names of all configured clusters in configuration order
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Health checks all clusters in parallel by asking their API servers for the version
+func (r *Registry) Health(ctx context.Context) []ClusterHealth {
+	results := make([]ClusterHealth, len(r.names))
+
+	var wg sync.WaitGroup
+	for i, name := range r.names {
+		results[i] = ClusterHealth{Name: name, Default: name == r.defaultCluster}
+
+		client, err := r.Get(name)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+
+		if status := client.CacheStatus(); status.Enabled {
+			results[i].Cache = &status
+		}
+
+		wg.Add(1)
+		go func(health *ClusterHealth, client *Client) {
+			defer wg.Done()
+
+			start := time.Now()
+			version, err := client.ServerVersion(ctx)
+			health.LatencyMs = time.Since(start).Milliseconds()
+			if err != nil {
+				health.Error = err.Error()
+				return
+			}
+			health.Healthy = true
+			health.Version = version
+		}(&results[i], client)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// StartCaches starts the informer cache of every available cluster until ctx is done
+func (r *Registry) StartCaches(ctx context.Context) {
+	for _, name := range r.names {
+		if client, ok := r.clients[name]; ok {
+			client.StartCache(ctx)
+		}
+	}
+}
+
+// CachesSynced reports whether the cache of every available cluster has synced, with the status per cluster.
+// Clusters without a client are left out, they cannot become ready by waiting.
+func (r *Registry) CachesSynced() (bool, map[string]CacheStatus) {
+	synced := true
+	statuses := make(map[string]CacheStatus, len(r.clients))
+	for _, name := range r.names {
+		client, ok := r.clients[name]
+		if !ok {
+			continue
+		}
+		status := client.CacheStatus()
+		statuses[name] = status
+		if status.Enabled && !status.Synced {
+			synced = false
+		}
+	}
+	return synced, statuses
+}
+
+// ServerVersion returns the Kubernetes version of the API server, giving up when ctx expires
+func (c *Client) ServerVersion(ctx context.Context) (string, error) {
+	type versionResult struct {
+		version string
+		err     error
+	}
+
+	// The discovery client does not take a context, so wait for it in the background
+	done := make(chan versionResult, 1)
+	go func() {
+		info, err := c.clientset.Discovery().ServerVersion()
+		if err != nil {
+			done <- versionResult{err: fmt.Errorf("failed to reach API server: %v", err)}
+			return
+		}
+		done <- versionResult{version: info.GitVersion}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("failed to reach API server: %v", ctx.Err())
+	case result := <-done:
+		return result.version, result.err
+	}
+}
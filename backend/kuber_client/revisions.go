@@ -0,0 +1,349 @@
+package kuberclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+	podTemplateHashLabel  = appsv1.DefaultDeploymentUniqueLabelKey
+)
+
+// Annotations managed by the deployment controller or kubectl that must not be
+// copied from a ReplicaSet back onto the Deployment during a rollback
+var rollbackSkippedAnnotations = map[string]bool{
+	corev1.LastAppliedConfigAnnotation:          true,
+	revisionAnnotation:                          true,
+	"deployment.kubernetes.io/revision-history": true,
+	"deployment.kubernetes.io/desired-replicas": true,
+	"deployment.kubernetes.io/max-replicas":     true,
+	appsv1.DeprecatedRollbackTo:                 true,
+}
+
+// RollbackResult describes the revision a rollback landed on
+type RollbackResult struct {
+	MutationResult
+	Revision           int64    `json:"revision"`
+	ReplicaSet         string   `json:"replicaSet,omitempty"`
+	ControllerRevision string   `json:"controllerRevision,omitempty"`
+	ChangeCause        string   `json:"changeCause,omitempty"` // Change-cause of the target revision, restored on the workload
+	Skipped            bool     `json:"skipped"`
+	Warnings           []string `json:"warnings,omitempty"`
+}
+
+// replicaSetRevision returns the revision number stored on a ReplicaSet, or 0 if it is missing or invalid
+func replicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// getDeploymentReplicaSets returns the ReplicaSets controlled by the deployment, newest revision first.
+// The second return value is the number of ReplicaSets that matched the selector but belong to another owner.
+func (c *Client) getDeploymentReplicaSets(ctx context.Context, deployment *appsv1.Deployment) ([]appsv1.ReplicaSet, int, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid selector on deployment %s: %v", deployment.Name, err)
+	}
+
+	replicaSets, err := c.clientset.AppsV1().ReplicaSets(deployment.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get deployment history: %v", err)
+	}
+
+	owned := make([]appsv1.ReplicaSet, 0, len(replicaSets.Items))
+	foreign := 0
+	for i := range replicaSets.Items {
+		if metav1.IsControlledBy(&replicaSets.Items[i], deployment) {
+			owned = append(owned, replicaSets.Items[i])
+		} else {
+			foreign++
+		}
+	}
+
+	sort.SliceStable(owned, func(i, j int) bool {
+		return replicaSetRevision(&owned[i]) > replicaSetRevision(&owned[j])
+	})
+
+	return owned, foreign, nil
+}
+
+// templateWithoutHash returns a copy of the pod template with the pod-template-hash label removed
+func templateWithoutHash(template corev1.PodTemplateSpec) corev1.PodTemplateSpec {
+	copied := *template.DeepCopy()
+	delete(copied.Labels, podTemplateHashLabel)
+	return copied
+}
+
+// equalIgnoreHash reports whether two pod templates are equal, ignoring the pod-template-hash label
+func equalIgnoreHash(a, b corev1.PodTemplateSpec) bool {
+	return apiequality.Semantic.DeepEqual(templateWithoutHash(a), templateWithoutHash(b))
+}
+
+// templateRevision is a pod template recorded in a workload's revision history
+type templateRevision struct {
+	Number      int64
+	Name        string
+	Template    corev1.PodTemplateSpec
+	Annotations map[string]string
+}
+
+// replicaSetTemplateRevision converts a ReplicaSet into a revision history entry
+func replicaSetTemplateRevision(rs *appsv1.ReplicaSet) templateRevision {
+	return templateRevision{
+		Number:      replicaSetRevision(rs),
+		Name:        rs.Name,
+		Template:    rs.Spec.Template,
+		Annotations: rs.Annotations,
+	}
+}
+
+// applyRevision replaces the deployment's pod template with the revision's one and
+// copies the ReplicaSet annotations, the same way `kubectl rollout undo` does
+func applyRevision(deployment *appsv1.Deployment, revision *templateRevision) {
+	deployment.Spec.Template = templateWithoutHash(revision.Template)
+
+	if deployment.Annotations == nil {
+		deployment.Annotations = make(map[string]string)
+	}
+	for key, value := range revision.Annotations {
+		if !rollbackSkippedAnnotations[key] {
+			deployment.Annotations[key] = value
+		}
+	}
+	restoreChangeCause(deployment, revision)
+}
+
+// restoreChangeCause sets the change-cause annotation to the one of the revision, so the history keeps
+// describing what the restored template is. A revision without a change-cause removes the current one.
+func restoreChangeCause(obj metav1.Object, revision *templateRevision) {
+	annotations := obj.GetAnnotations()
+	cause, ok := revision.Annotations[changeCauseAnnotation]
+	if !ok {
+		delete(annotations, changeCauseAnnotation)
+		return
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[changeCauseAnnotation] = cause
+	obj.SetAnnotations(annotations)
+}
+
+// prepareRollback picks the rollback target from a history sorted newest first.
+// It returns errNoChange, together with the result, when the live template already matches the target.
+func prepareRollback(kind string, config ServiceConfig, history []templateRevision, currentRevision int64, foreign int, live corev1.PodTemplateSpec) (*RollbackResult, *templateRevision, error) {
+	if len(history) == 0 {
+		return nil, nil, fmt.Errorf("no revisions found for %s %s", strings.ToLower(kind), config.Name)
+	}
+
+	target, err := findRollbackTarget(kind, history, currentRevision, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &RollbackResult{Revision: target.Number, ChangeCause: target.Annotations[changeCauseAnnotation]}
+	if kind == KindDeployment {
+		result.ReplicaSet = target.Name
+	} else {
+		result.ControllerRevision = target.Name
+	}
+	if foreign > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"ignored %d revision(s) matching the selector that are not owned by %s %s", foreign, strings.ToLower(kind), config.Name))
+	}
+
+	// Nothing to do if the workload already runs the target template
+	if equalIgnoreHash(live, target.Template) {
+		result.Skipped = true
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"current template already matches revision %d, rollback skipped", result.Revision))
+		return result, nil, errNoChange
+	}
+
+	return result, target, nil
+}
+
+// findRevision returns the revision whose number or name matches id, or nil
+func findRevision(history []templateRevision, id string) *templateRevision {
+	for i := range history {
+		if history[i].Name == id || strconv.FormatInt(history[i].Number, 10) == id {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// findRollbackTarget picks the revision to roll back to from a history sorted newest first
+func findRollbackTarget(kind string, history []templateRevision, currentRevision int64, config ServiceConfig) (*templateRevision, error) {
+	// Case 1: Specific revision ID requested
+	if config.RevisionID != "" {
+		if revision := findRevision(history, config.RevisionID); revision != nil {
+			return revision, nil
+		}
+		return nil, fmt.Errorf("revision %s not found for %s %s", config.RevisionID, strings.ToLower(kind), config.Name)
+	}
+
+	// Case 2: Specific image or version requested, newest matching revision wins
+	if config.RevisionImage != "" || config.Version != "" {
+		var targetImage *ImageReference
+		if config.RevisionImage != "" {
+			parsed, err := ParseImageReference(config.RevisionImage)
+			if err != nil {
+				return nil, err
+			}
+			targetImage = &parsed
+		}
+
+		for i := range history {
+			for _, container := range templateContainers(history[i].Template, config.Container) {
+				image, err := ParseImageReference(container.Image)
+				if err != nil {
+					// Fall back to a plain comparison for references the parser does not understand
+					if container.Image == config.RevisionImage || container.Image == config.Version {
+						return &history[i], nil
+					}
+					continue
+				}
+				if targetImage != nil && image.SameImage(*targetImage) {
+					return &history[i], nil
+				}
+				// If only version is specified, search for any image with that tag or digest
+				if targetImage == nil && image.MatchesVersion(config.Version) {
+					return &history[i], nil
+				}
+			}
+		}
+		target := config.RevisionImage
+		if target == "" {
+			target = config.Version
+		}
+		return nil, fmt.Errorf("no revision found with image %s for %s %s", target, strings.ToLower(kind), config.Name)
+	}
+
+	// Case 3: Default to the newest revision older than the current one
+	for i := range history {
+		if history[i].Number < currentRevision {
+			return &history[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("only one revision found, cannot rollback %s %s", strings.ToLower(kind), config.Name)
+}
+
+// RevisionInfo describes a single revision in a deployment's rollout history
+type RevisionInfo struct {
+	Revision    int64       `json:"revision"`
+	ReplicaSet  string      `json:"replicaSet"`
+	Images      []string    `json:"images"`
+	ChangeCause string      `json:"changeCause,omitempty"`
+	CreatedAt   metav1.Time `json:"createdAt"`
+	Replicas    int32       `json:"replicas"`
+	Current     bool        `json:"current"`
+}
+
+// GetDeploymentHistory returns the rollout history of a deployment, newest revision first
+func (c *Client) GetDeploymentHistory(ctx context.Context, namespace, name string) ([]RevisionInfo, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	// Get the deployment
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s in namespace %s: %v", name, namespace, err)
+	}
+
+	history, _, err := c.getDeploymentReplicaSets(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	currentRevision := deployment.Annotations[revisionAnnotation]
+
+	revisions := make([]RevisionInfo, 0, len(history))
+	for i := range history {
+		rs := &history[i]
+
+		images := make([]string, 0, len(rs.Spec.Template.Spec.Containers))
+		for _, container := range rs.Spec.Template.Spec.Containers {
+			images = append(images, container.Image)
+		}
+
+		revisions = append(revisions, RevisionInfo{
+			Revision:    replicaSetRevision(rs),
+			ReplicaSet:  rs.Name,
+			Images:      images,
+			ChangeCause: rs.Annotations[changeCauseAnnotation],
+			CreatedAt:   rs.CreationTimestamp,
+			Replicas:    rs.Status.Replicas,
+			Current:     rs.Annotations[revisionAnnotation] == currentRevision,
+		})
+	}
+
+	return revisions, nil
+}
+
+// getControllerRevisions returns the revision history of a statefulset or daemonset, newest first.
+// The second return value is the number of ControllerRevisions that matched the selector but belong to another owner.
+func (c *Client) getControllerRevisions(ctx context.Context, owner metav1.Object, selector *metav1.LabelSelector) ([]templateRevision, int, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid selector on %s: %v", owner.GetName(), err)
+	}
+
+	revisions, err := c.clientset.AppsV1().ControllerRevisions(owner.GetNamespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector.String(),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get revision history: %v", err)
+	}
+
+	history := make([]templateRevision, 0, len(revisions.Items))
+	foreign := 0
+	for i := range revisions.Items {
+		revision := &revisions.Items[i]
+		if !metav1.IsControlledBy(revision, owner) {
+			foreign++
+			continue
+		}
+
+		// Revision data is a patch of the form {"spec":{"template":{...}}}
+		var patch struct {
+			Spec struct {
+				Template corev1.PodTemplateSpec `json:"template"`
+			} `json:"spec"`
+		}
+		if err := json.Unmarshal(revision.Data.Raw, &patch); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode controller revision %s: %v", revision.Name, err)
+		}
+
+		history = append(history, templateRevision{
+			Number:      revision.Revision,
+			Name:        revision.Name,
+			Template:    patch.Spec.Template,
+			Annotations: revision.Annotations,
+		})
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Number > history[j].Number
+	})
+
+	return history, foreign, nil
+}
@@ -0,0 +1,325 @@
+package kuberclient
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestClient returns a client backed by a fake clientset holding objects.
+// Dry runs are evaluated client-side, the fake clientset would persist them.
+func newTestClient(objects ...runtime.Object) (*Client, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	client := NewClientFromInterface(clientset, nil)
+	client.localDryRun = true
+	return client, clientset
+}
+
+// testTemplate returns a pod template of the "web" app running image
+func testTemplate(image string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: image}},
+		},
+	}
+}
+
+// testDeployment returns the "web" deployment running image at revision
+func testDeployment(image string, revision int64, changeCause string) *appsv1.Deployment {
+	annotations := map[string]string{revisionAnnotation: strconv.FormatInt(revision, 10)}
+	if changeCause != "" {
+		annotations[changeCauseAnnotation] = changeCause
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "web-uid", Annotations: annotations},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: testTemplate(image),
+		},
+	}
+}
+
+// testReplicaSet returns a ReplicaSet of the "web" app controlled by the owner with ownerUID
+func testReplicaSet(name string, ownerUID types.UID, revision int64, image, changeCause string) *appsv1.ReplicaSet {
+	annotations := map[string]string{revisionAnnotation: strconv.FormatInt(revision, 10)}
+	if changeCause != "" {
+		annotations[changeCauseAnnotation] = changeCause
+	}
+	template := testTemplate(image)
+	template.Labels[podTemplateHashLabel] = name
+	controller := true
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			UID:         types.UID(name + "-uid"),
+			Labels:      map[string]string{"app": "web", podTemplateHashLabel: name},
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: KindDeployment, Name: "web", UID: ownerUID, Controller: &controller},
+			},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: template},
+	}
+}
+
+// testControllerRevision returns a ControllerRevision of owner recording template
+func testControllerRevision(t *testing.T, owner metav1.Object, kind, name string, revision int64, template corev1.PodTemplateSpec, changeCause string) *appsv1.ControllerRevision {
+	t.Helper()
+
+	var patch struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	patch.Spec.Template = template
+	data, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var annotations map[string]string
+	if changeCause != "" {
+		annotations = map[string]string{changeCauseAnnotation: changeCause}
+	}
+	controller := true
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   owner.GetNamespace(),
+			Labels:      map[string]string{"app": "web"},
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: kind, Name: owner.GetName(), UID: owner.GetUID(), Controller: &controller},
+			},
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: revision,
+	}
+}
+
+func TestGetDeploymentReplicaSets(t *testing.T) {
+	deployment := testDeployment("web:v10", 10, "")
+	client, _ := newTestClient(
+		testReplicaSet("web-2", "web-uid", 2, "web:v2", ""),
+		testReplicaSet("web-10", "web-uid", 10, "web:v10", ""),
+		testReplicaSet("web-9", "web-uid", 9, "web:v9", ""),
+		testReplicaSet("other-11", "other-uid", 11, "web:v11", ""),
+	)
+
+	replicaSets, foreign, err := client.getDeploymentReplicaSets(context.Background(), deployment)
+	if err != nil {
+		t.Fatalf("getDeploymentReplicaSets: %v", err)
+	}
+
+	names := make([]string, 0, len(replicaSets))
+	for _, rs := range replicaSets {
+		names = append(names, rs.Name)
+	}
+	if want := []string{"web-10", "web-9", "web-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got ReplicaSets %v, want %v", names, want)
+	}
+	if foreign != 1 {
+		t.Errorf("got %d foreign ReplicaSets, want 1", foreign)
+	}
+}
+
+func TestRollbackDeployment(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ServiceConfig
+		wantRevision    int64
+		wantImage       string
+		wantChangeCause string // Empty when the annotation must be removed
+		wantSkipped     bool
+		wantErr         bool
+	}{
+		{
+			name:            "previous revision restores its change-cause",
+			config:          ServiceConfig{},
+			wantRevision:    9,
+			wantImage:       "web:v9",
+			wantChangeCause: "update to v9",
+		},
+		{
+			name:         "revision without change-cause removes the current one",
+			config:       ServiceConfig{RevisionID: "2"},
+			wantRevision: 2,
+			wantImage:    "web:v2",
+		},
+		{
+			name:            "revision by ReplicaSet name",
+			config:          ServiceConfig{RevisionID: "web-9"},
+			wantRevision:    9,
+			wantImage:       "web:v9",
+			wantChangeCause: "update to v9",
+		},
+		{
+			name:         "revision by version",
+			config:       ServiceConfig{Version: "v2"},
+			wantRevision: 2,
+			wantImage:    "web:v2",
+		},
+		{
+			name:    "ReplicaSet of another deployment is ignored",
+			config:  ServiceConfig{Version: "v11"},
+			wantErr: true,
+		},
+		{
+			name:            "current revision is skipped",
+			config:          ServiceConfig{RevisionID: "10"},
+			wantRevision:    10,
+			wantImage:       "web:v10",
+			wantChangeCause: "update to v10",
+			wantSkipped:     true,
+		},
+		{
+			name:    "unknown revision",
+			config:  ServiceConfig{RevisionID: "5"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, clientset := newTestClient(
+				testDeployment("web:v10", 10, "update to v10"),
+				testReplicaSet("web-2", "web-uid", 2, "web:v2", ""),
+				testReplicaSet("web-10", "web-uid", 10, "web:v10", "update to v10"),
+				testReplicaSet("web-9", "web-uid", 9, "web:v9", "update to v9"),
+				testReplicaSet("other-11", "other-uid", 11, "web:v11", "update to v11"),
+			)
+
+			config := tt.config
+			config.Namespace = "default"
+			config.Name = "web"
+			result, err := client.RollbackDeployment(context.Background(), config)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RollbackDeployment = %+v, want an error", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RollbackDeployment: %v", err)
+			}
+			if result.Revision != tt.wantRevision || result.Skipped != tt.wantSkipped || result.ChangeCause != tt.wantChangeCause {
+				t.Errorf("got revision %d, skipped %v, change-cause %q, want %d, %v, %q",
+					result.Revision, result.Skipped, result.ChangeCause, tt.wantRevision, tt.wantSkipped, tt.wantChangeCause)
+			}
+
+			deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if image := deployment.Spec.Template.Spec.Containers[0].Image; image != tt.wantImage {
+				t.Errorf("deployment runs %s, want %s", image, tt.wantImage)
+			}
+			if _, ok := deployment.Spec.Template.Labels[podTemplateHashLabel]; ok {
+				t.Errorf("restored template kept the %s label", podTemplateHashLabel)
+			}
+			if cause := deployment.Annotations[changeCauseAnnotation]; cause != tt.wantChangeCause {
+				t.Errorf("deployment change-cause is %q, want %q", cause, tt.wantChangeCause)
+			}
+			if revision := deployment.Annotations[revisionAnnotation]; revision != "10" {
+				t.Errorf("deployment revision annotation is %q, the rollback must leave it to the controller", revision)
+			}
+		})
+	}
+}
+
+func TestRollbackWorkloadControllerRevisions(t *testing.T) {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", UID: "sts-uid",
+			Annotations: map[string]string{changeCauseAnnotation: "update to v3"},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: testTemplate("web:v3"),
+		},
+		Status: appsv1.StatefulSetStatus{UpdateRevision: "web-3"},
+	}
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "agent", Namespace: "default", UID: "ds-uid",
+			Annotations: map[string]string{changeCauseAnnotation: "update to v3"},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			Template: testTemplate("web:v3"),
+		},
+	}
+
+	// The previous template also differs outside the image, the whole template must round-trip
+	previous := testTemplate("web:v2")
+	previous.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "MODE", Value: "legacy"}}
+	previous.Annotations = map[string]string{"team": "platform"}
+
+	tests := []struct {
+		name   string
+		kind   string
+		owner  metav1.Object
+		object string
+	}{
+		{"statefulset", KindStatefulSet, statefulSet, "web"},
+		{"daemonset", KindDaemonSet, daemonSet, "agent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, clientset := newTestClient(
+				statefulSet.DeepCopy(),
+				daemonSet.DeepCopy(),
+				testControllerRevision(t, tt.owner, tt.kind, tt.object+"-1", 1, testTemplate("web:v1"), ""),
+				testControllerRevision(t, tt.owner, tt.kind, tt.object+"-2", 2, previous, "update to v2"),
+				testControllerRevision(t, tt.owner, tt.kind, tt.object+"-3", 3, testTemplate("web:v3"), "update to v3"),
+			)
+
+			result, err := client.RollbackWorkload(context.Background(), ServiceConfig{Namespace: "default", Name: tt.object, Kind: tt.kind})
+			if err != nil {
+				t.Fatalf("RollbackWorkload: %v", err)
+			}
+			if result.Revision != 2 || result.ControllerRevision != tt.object+"-2" || result.ChangeCause != "update to v2" {
+				t.Errorf("got revision %d (%s) with change-cause %q, want 2 (%s-2) with %q",
+					result.Revision, result.ControllerRevision, result.ChangeCause, tt.object, "update to v2")
+			}
+			if len(result.Warnings) != 0 {
+				t.Errorf("got warnings %v, the revisions of the other workload have different owners", result.Warnings)
+			}
+
+			var template corev1.PodTemplateSpec
+			var annotations map[string]string
+			switch tt.kind {
+			case KindStatefulSet:
+				updated, err := clientset.AppsV1().StatefulSets("default").Get(context.Background(), tt.object, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				template, annotations = updated.Spec.Template, updated.Annotations
+			case KindDaemonSet:
+				updated, err := clientset.AppsV1().DaemonSets("default").Get(context.Background(), tt.object, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				template, annotations = updated.Spec.Template, updated.Annotations
+			}
+			if !equalIgnoreHash(template, previous) {
+				t.Errorf("restored template %+v, want %+v", template, previous)
+			}
+			if cause := annotations[changeCauseAnnotation]; cause != "update to v2" {
+				t.Errorf("change-cause is %q, want %q", cause, "update to v2")
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Reason set on the Progressing condition once a rollout exceeds its progress deadline
+const progressDeadlineExceededReason = "ProgressDeadlineExceeded"
+
+// Pause before re-watching a rollout, doubled while watches keep closing right away up to rewatchMaxDelay
+var (
+	rewatchInitialDelay = 500 * time.Millisecond
+	rewatchMaxDelay     = 10 * time.Second
+)
+
+// RolloutStatus is a snapshot of a deployment rollout
+type RolloutStatus struct {
+	Name                string `json:"name"`
+	Namespace           string `json:"namespace"`
+	Generation          int64  `json:"generation"`
+	ObservedGeneration  int64  `json:"observedGeneration"`
+	Replicas            int32  `json:"replicas"`
+	UpdatedReplicas     int32  `json:"updated"`
+	ReadyReplicas       int32  `json:"ready"`
+	AvailableReplicas   int32  `json:"available"`
+	UnavailableReplicas int32  `json:"unavailable"`
+	Message             string `json:"message"`
+	Done                bool   `json:"done"`
+	Failed              bool   `json:"failed"`
+	Paused              bool   `json:"paused"`
+}
+
+// getRolloutStatus evaluates a deployment the same way `kubectl rollout status` does
+func getRolloutStatus(deployment *appsv1.Deployment) RolloutStatus {
+	status := RolloutStatus{
+		Name:                deployment.Name,
+		Namespace:           deployment.Namespace,
+		Generation:          deployment.Generation,
+		ObservedGeneration:  deployment.Status.ObservedGeneration,
+		Replicas:            deployment.Status.Replicas,
+		UpdatedReplicas:     deployment.Status.UpdatedReplicas,
+		ReadyReplicas:       deployment.Status.ReadyReplicas,
+		AvailableReplicas:   deployment.Status.AvailableReplicas,
+		UnavailableReplicas: deployment.Status.UnavailableReplicas,
+		Paused:              deployment.Spec.Paused,
+	}
+
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		status.Message = "waiting for deployment spec update to be observed"
+		return status
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == progressDeadlineExceededReason {
+			status.Failed = true
+			status.Message = fmt.Sprintf("deployment %s exceeded its progress deadline", deployment.Name)
+			return status
+		}
+	}
+
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
+	switch {
+	case deployment.Status.UpdatedReplicas < desired:
+		status.Message = fmt.Sprintf("%d out of %d new replicas have been updated", deployment.Status.UpdatedReplicas, desired)
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("%d old replicas are pending termination", deployment.Status.Replicas-deployment.Status.UpdatedReplicas)
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		status.Message = fmt.Sprintf("%d of %d updated replicas are available", deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas)
+	default:
+		status.Done = true
+		status.Message = fmt.Sprintf("deployment %s successfully rolled out", deployment.Name)
+	}
+
+	if status.Paused && !status.Done {
+		status.Message = "rollout is paused, " + status.Message
+	}
+
+	return status
+}
+
+// GetRolloutStatus returns the current rollout status of a deployment
+func (c *Client) GetRolloutStatus(ctx context.Context, namespace, name string) (*RolloutStatus, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s in namespace %s: %v", name, namespace, err)
+	}
+
+	status := getRolloutStatus(deployment)
+	return &status, nil
+}
+
+// WaitForRollout watches a deployment until its rollout completes, fails, is found paused or ctx expires.
+// onProgress, if set, is called with every observed status including the final one.
+func (c *Client) WaitForRollout(ctx context.Context, namespace, name string, onProgress func(RolloutStatus)) (*RolloutStatus, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	var last *RolloutStatus
+	report := func(deployment *appsv1.Deployment) bool {
+		status := getRolloutStatus(deployment)
+		last = &status
+		if onProgress != nil {
+			onProgress(status)
+		}
+		// A paused rollout makes no progress until it is resumed
+		return status.Done || status.Failed || status.Paused
+	}
+
+	backoff := newRewatchBackoff()
+	for {
+		deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return last, fmt.Errorf("failed to get deployment %s in namespace %s: %v", name, namespace, err)
+		}
+		if report(deployment) {
+			return last, rolloutError(last)
+		}
+
+		watcher, err := c.clientset.AppsV1().Deployments(namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+			ResourceVersion: deployment.ResourceVersion,
+		})
+		if err != nil {
+			return last, fmt.Errorf("failed to watch deployment %s in namespace %s: %v", name, namespace, err)
+		}
+
+		started := time.Now()
+		finished, err := watchRollout(ctx, watcher, name, report)
+		watcher.Stop()
+		if err != nil {
+			return last, err
+		}
+		if finished {
+			return last, rolloutError(last)
+		}
+
+		// The watch was closed by the API server, start over from a fresh Get. An expired resource
+		// version or a restarting API server closes every watch right away, so pause before retrying.
+		if time.Since(started) > rewatchMaxDelay {
+			backoff = newRewatchBackoff()
+		}
+		select {
+		case <-ctx.Done():
+			return last, fmt.Errorf("timed out waiting for rollout of deployment %s: %v", name, ctx.Err())
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// newRewatchBackoff returns the pauses between watches of a rollout
+func newRewatchBackoff() wait.Backoff {
+	return wait.Backoff{
+		Duration: rewatchInitialDelay,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    math.MaxInt32,
+		Cap:      rewatchMaxDelay,
+	}
+}
+
+// watchRollout feeds deployment events to report until it returns true, the watch closes or ctx expires
+func watchRollout(ctx context.Context, watcher watch.Interface, name string, report func(*appsv1.Deployment) bool) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("timed out waiting for rollout of deployment %s: %v", name, ctx.Err())
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+
+			switch event.Type {
+			case watch.Deleted:
+				return false, fmt.Errorf("deployment %s was deleted during rollout", name)
+			case watch.Error:
+				// Usually an expired resource version, restart the watch
+				return false, nil
+			}
+
+			// Clientsets that ignore the field selector deliver every deployment of the namespace
+			deployment, ok := event.Object.(*appsv1.Deployment)
+			if !ok || deployment.Name != name {
+				continue
+			}
+			if report(deployment) {
+				return true, nil
+			}
+		}
+	}
+}
+
+// rolloutError converts a failed or paused rollout status into an error
+func rolloutError(status *RolloutStatus) error {
+	if status != nil && status.Failed {
+		return fmt.Errorf("rollout failed: %s", status.Message)
+	}
+	if status != nil && status.Paused && !status.Done {
+		return fmt.Errorf("deployment %s is paused, resume it to continue the rollout", status.Name)
+	}
+	return nil
+}
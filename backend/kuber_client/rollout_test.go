@@ -0,0 +1,92 @@
+package kuberclient
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// rollingDeployment returns the "web" deployment with updated of 2 replicas rolled out
+func rollingDeployment(updated int32) *appsv1.Deployment {
+	deployment := testDeployment("web:v2", 2, "")
+	replicas := int32(2)
+	deployment.Generation = 1
+	deployment.Spec.Replicas = &replicas
+	deployment.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: 1,
+		Replicas:           2,
+		UpdatedReplicas:    updated,
+		ReadyReplicas:      updated,
+		AvailableReplicas:  updated,
+	}
+	return deployment
+}
+
+func TestWaitForRollout(t *testing.T) {
+	client, clientset := newTestClient(rollingDeployment(1))
+
+	watcher := watch.NewFake()
+	clientset.PrependWatchReactor("deployments", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watcher, nil
+	})
+	go func() {
+		watcher.Modify(rollingDeployment(2))
+	}()
+
+	var reported []RolloutStatus
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	status, err := client.WaitForRollout(ctx, "default", "web", func(status RolloutStatus) {
+		reported = append(reported, status)
+	})
+	if err != nil {
+		t.Fatalf("WaitForRollout: %v", err)
+	}
+	if !status.Done {
+		t.Errorf("got status %+v, want a finished rollout", status)
+	}
+	if len(reported) != 2 || reported[0].Done {
+		t.Errorf("got progress %+v, want the rollout in progress and then finished", reported)
+	}
+}
+
+func TestWaitForRolloutRewatchBackoff(t *testing.T) {
+	initialDelay, maxDelay := rewatchInitialDelay, rewatchMaxDelay
+	rewatchInitialDelay, rewatchMaxDelay = 20*time.Millisecond, 100*time.Millisecond
+	t.Cleanup(func() {
+		rewatchInitialDelay, rewatchMaxDelay = initialDelay, maxDelay
+	})
+
+	client, clientset := newTestClient(rollingDeployment(1))
+
+	// Every watch is closed right away, like after an expired resource version
+	var watches atomic.Int32
+	clientset.PrependWatchReactor("deployments", func(k8stesting.Action) (bool, watch.Interface, error) {
+		watches.Add(1)
+		watcher := watch.NewFake()
+		watcher.Stop()
+		return true, watcher, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	status, err := client.WaitForRollout(ctx, "default", "web", nil)
+	if err == nil {
+		t.Fatalf("WaitForRollout = %+v, want a timeout", status)
+	}
+	if status == nil || status.Done {
+		t.Errorf("got status %+v, want the last status of the unfinished rollout", status)
+	}
+
+	// 20, 40, 80 and then 100ms pauses allow about 7 watches in 500ms, without them there are thousands
+	if got := watches.Load(); got < 2 || got > 10 {
+		t.Errorf("got %d watches within 500ms, want the re-watches to back off", got)
+	}
+}
@@ -0,0 +1,559 @@
+package kuberclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// SandboxCluster is the name of the only cluster in sandbox mode
+const SandboxCluster = "sandbox"
+
+var (
+	deploymentsResource = appsv1.SchemeGroupVersion.WithResource("deployments")
+	replicaSetsResource = appsv1.SchemeGroupVersion.WithResource("replicasets")
+	podsResource        = corev1.SchemeGroupVersion.WithResource("pods")
+	nodesResource       = corev1.SchemeGroupVersion.WithResource("nodes")
+)
+
+// NewSandboxClient creates a client backed by an in-memory fake cluster seeded from a YAML fixture.
+// Deployments are rolled out instantly by a minimal controller, so scale, restart and rollback
+// behave like on a real cluster. Dry runs are evaluated client-side and metrics are unavailable.
+func NewSandboxClient(fixturePath string) (*Client, error) {
+	objects, err := loadFixture(fixturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset := fake.NewSimpleClientset()
+	for _, obj := range objects {
+		if err := clientset.Tracker().Add(obj); err != nil {
+			return nil, fmt.Errorf("failed to add fixture object: %v", err)
+		}
+	}
+
+	if discovery, ok := clientset.Discovery().(*fakediscovery.FakeDiscovery); ok {
+		discovery.FakedServerVersion = &version.Info{GitVersion: "v1.28.4-sandbox", Platform: "sandbox"}
+	}
+
+	controller := &sandboxController{tracker: clientset.Tracker()}
+	if err := controller.rollOutAll(); err != nil {
+		return nil, err
+	}
+	clientset.PrependReactor("create", "deployments", controller.reactDeploymentCreate)
+	clientset.PrependReactor("update", "deployments", controller.reactDeploymentUpdate)
+	clientset.PrependReactor("delete", "deployments", controller.reactDeploymentDelete)
+	clientset.PrependReactor("create", "pods", controller.reactEviction)
+
+	client := NewClientFromInterface(clientset, nil)
+	client.localDryRun = true
+	return client, nil
+}
+
+// NewSandboxRegistry registers the sandbox client as the only, default cluster
+func NewSandboxRegistry(fixturePath string) (*Registry, error) {
+	client, err := NewSandboxClient(fixturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registry{
+		clients:        map[string]*Client{SandboxCluster: client},
+		failures:       make(map[string]error),
+		names:          []string{SandboxCluster},
+		defaultCluster: SandboxCluster,
+	}, nil
+}
+
+// loadFixture decodes a multi-document YAML file of Kubernetes objects. Missing UIDs and creation
+// timestamps are filled in, and owner references without a UID are resolved by kind and name.
+func loadFixture(path string) ([]runtime.Object, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sandbox fixture: %v", err)
+	}
+
+	decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	deserializer := scheme.Codecs.UniversalDeserializer()
+
+	var objects []runtime.Object
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse sandbox fixture: %v", err)
+		}
+		if len(bytes.TrimSpace(raw.Raw)) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+
+		obj, _, err := deserializer.Decode(raw.Raw, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode sandbox fixture object: %v", err)
+		}
+		objects = append(objects, obj)
+	}
+
+	uids := make(map[string]metav1.Object)
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sandbox fixture object: %v", err)
+		}
+		if accessor.GetUID() == "" {
+			accessor.SetUID(uuid.NewUUID())
+		}
+		if created := accessor.GetCreationTimestamp(); created.IsZero() {
+			accessor.SetCreationTimestamp(metav1.Now())
+		}
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		uids[kind+"/"+accessor.GetNamespace()+"/"+accessor.GetName()] = accessor
+	}
+
+	for _, obj := range objects {
+		accessor, _ := meta.Accessor(obj)
+		references := accessor.GetOwnerReferences()
+		for i := range references {
+			if references[i].UID != "" {
+				continue
+			}
+			owner, ok := uids[references[i].Kind+"/"+accessor.GetNamespace()+"/"+references[i].Name]
+			if !ok {
+				return nil, fmt.Errorf("owner %s %s of %s not found in sandbox fixture", references[i].Kind, references[i].Name, accessor.GetName())
+			}
+			references[i].UID = owner.GetUID()
+		}
+		accessor.SetOwnerReferences(references)
+	}
+
+	return objects, nil
+}
+
+// sandboxController plays the deployment and ReplicaSet controllers for the fake clientset
+type sandboxController struct {
+	tracker ktesting.ObjectTracker
+}
+
+// rollOutAll brings the fixture into the state the controllers would have produced
+func (sc *sandboxController) rollOutAll() error {
+	list, err := sc.tracker.List(deploymentsResource, appsv1.SchemeGroupVersion.WithKind("Deployment"), "")
+	if err != nil {
+		return err
+	}
+
+	for i := range list.(*appsv1.DeploymentList).Items {
+		deployment := &list.(*appsv1.DeploymentList).Items[i]
+		if err := sc.rollOut(deployment); err != nil {
+			return err
+		}
+		if err := sc.tracker.Update(deploymentsResource, deployment, deployment.Namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reactDeploymentUpdate stores an updated deployment and immediately rolls it out
+func (sc *sandboxController) reactDeploymentUpdate(action ktesting.Action) (bool, runtime.Object, error) {
+	update, ok := action.(ktesting.UpdateAction)
+	if !ok || action.GetSubresource() != "" {
+		return false, nil, nil
+	}
+	deployment, ok := update.GetObject().(*appsv1.Deployment)
+	if !ok {
+		return false, nil, nil
+	}
+	deployment = deployment.DeepCopy()
+
+	current, err := sc.tracker.Get(deploymentsResource, action.GetNamespace(), deployment.Name)
+	if err != nil {
+		return true, nil, err
+	}
+	previous := current.(*appsv1.Deployment)
+
+	// The API server bumps the generation on every spec change
+	deployment.Generation = previous.Generation
+	if !apiequality.Semantic.DeepEqual(previous.Spec, deployment.Spec) {
+		deployment.Generation++
+	}
+
+	if err := sc.rollOut(deployment); err != nil {
+		return true, nil, err
+	}
+	if err := sc.tracker.Update(deploymentsResource, deployment, action.GetNamespace()); err != nil {
+		return true, nil, err
+	}
+
+	return true, deployment, nil
+}
+
+// reactDeploymentCreate stores a new deployment and immediately rolls it out
+func (sc *sandboxController) reactDeploymentCreate(action ktesting.Action) (bool, runtime.Object, error) {
+	create, ok := action.(ktesting.CreateAction)
+	if !ok || action.GetSubresource() != "" {
+		return false, nil, nil
+	}
+	deployment, ok := create.GetObject().(*appsv1.Deployment)
+	if !ok {
+		return false, nil, nil
+	}
+	deployment = deployment.DeepCopy()
+
+	if _, err := sc.tracker.Get(deploymentsResource, action.GetNamespace(), deployment.Name); err == nil {
+		return true, nil, apierrors.NewAlreadyExists(deploymentsResource.GroupResource(), deployment.Name)
+	}
+
+	deployment.Namespace = action.GetNamespace()
+	deployment.UID = uuid.NewUUID()
+	deployment.CreationTimestamp = metav1.Now()
+	deployment.Generation = 1
+
+	if err := sc.rollOut(deployment); err != nil {
+		return true, nil, err
+	}
+	if err := sc.tracker.Create(deploymentsResource, deployment, action.GetNamespace()); err != nil {
+		return true, nil, err
+	}
+
+	return true, deployment, nil
+}
+
+// reactDeploymentDelete deletes a deployment together with its ReplicaSets and their pods,
+// the fake clientset does not collect garbage
+func (sc *sandboxController) reactDeploymentDelete(action ktesting.Action) (bool, runtime.Object, error) {
+	del, ok := action.(ktesting.DeleteAction)
+	if !ok || action.GetSubresource() != "" {
+		return false, nil, nil
+	}
+
+	current, err := sc.tracker.Get(deploymentsResource, action.GetNamespace(), del.GetName())
+	if err != nil {
+		return true, nil, err
+	}
+	deployment := current.(*appsv1.Deployment)
+
+	list, err := sc.tracker.List(replicaSetsResource, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), deployment.Namespace)
+	if err != nil {
+		return true, nil, err
+	}
+	for i := range list.(*appsv1.ReplicaSetList).Items {
+		replicaSet := &list.(*appsv1.ReplicaSetList).Items[i]
+		if !metav1.IsControlledBy(replicaSet, deployment) {
+			continue
+		}
+		if err := sc.syncPods(replicaSet, 0); err != nil {
+			return true, nil, err
+		}
+		if err := sc.tracker.Delete(replicaSetsResource, replicaSet.Namespace, replicaSet.Name); err != nil {
+			return true, nil, err
+		}
+	}
+
+	return true, nil, sc.tracker.Delete(deploymentsResource, deployment.Namespace, deployment.Name)
+}
+
+// reactEviction deletes an evicted pod right away
+func (sc *sandboxController) reactEviction(action ktesting.Action) (bool, runtime.Object, error) {
+	if action.GetSubresource() != "eviction" {
+		return false, nil, nil
+	}
+	create, ok := action.(ktesting.CreateAction)
+	if !ok {
+		return false, nil, nil
+	}
+	eviction, ok := create.GetObject().(*policyv1.Eviction)
+	if !ok {
+		return false, nil, nil
+	}
+
+	return true, nil, sc.tracker.Delete(podsResource, action.GetNamespace(), eviction.Name)
+}
+
+// rollOut makes the ReplicaSet matching the deployment's template the active revision, scales it to the
+// desired replicas and all others to zero, and marks the deployment as fully rolled out.
+// A paused deployment keeps its newest ReplicaSet and is only scaled.
+func (sc *sandboxController) rollOut(deployment *appsv1.Deployment) error {
+	list, err := sc.tracker.List(replicaSetsResource, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), deployment.Namespace)
+	if err != nil {
+		return err
+	}
+
+	var owned []*appsv1.ReplicaSet
+	var active, newest *appsv1.ReplicaSet
+	maxRevision := int64(0)
+	for i := range list.(*appsv1.ReplicaSetList).Items {
+		replicaSet := &list.(*appsv1.ReplicaSetList).Items[i]
+		if !metav1.IsControlledBy(replicaSet, deployment) {
+			continue
+		}
+		owned = append(owned, replicaSet)
+		if revision := replicaSetRevision(replicaSet); revision > maxRevision || newest == nil {
+			maxRevision = revision
+			newest = replicaSet
+		}
+		if active == nil && equalIgnoreHash(replicaSet.Spec.Template, deployment.Spec.Template) {
+			active = replicaSet
+		}
+	}
+
+	if deployment.Spec.Paused && newest != nil {
+		// A paused deployment keeps running its newest revision, even when the template has changed since
+		active = newest
+	} else if active == nil {
+		active = newSandboxReplicaSet(deployment)
+		if err := sc.tracker.Create(replicaSetsResource, active, deployment.Namespace); err != nil {
+			return err
+		}
+		owned = append(owned, active)
+	}
+	updated := equalIgnoreHash(active.Spec.Template, deployment.Spec.Template)
+
+	// A new template, or a rollback to an old one, becomes the newest revision
+	if revision := replicaSetRevision(active); revision == 0 || revision < maxRevision {
+		if active.Annotations == nil {
+			active.Annotations = make(map[string]string)
+		}
+		active.Annotations[revisionAnnotation] = strconv.FormatInt(maxRevision+1, 10)
+	}
+	if cause, ok := deployment.Annotations[changeCauseAnnotation]; ok && updated {
+		active.Annotations[changeCauseAnnotation] = cause
+	}
+
+	replicas := derefReplicas(deployment.Spec.Replicas)
+	for _, replicaSet := range owned {
+		desired := int32(0)
+		if replicaSet.Name == active.Name {
+			desired = replicas
+		}
+
+		replicaSet.Spec.Replicas = &desired
+		replicaSet.Status = appsv1.ReplicaSetStatus{
+			Replicas:             desired,
+			FullyLabeledReplicas: desired,
+			ReadyReplicas:        desired,
+			AvailableReplicas:    desired,
+			ObservedGeneration:   replicaSet.Generation,
+		}
+		if err := sc.tracker.Update(replicaSetsResource, replicaSet, replicaSet.Namespace); err != nil {
+			return err
+		}
+		if err := sc.syncPods(replicaSet, desired); err != nil {
+			return err
+		}
+	}
+
+	if deployment.Annotations == nil {
+		deployment.Annotations = make(map[string]string)
+	}
+	deployment.Annotations[revisionAnnotation] = active.Annotations[revisionAnnotation]
+
+	updatedReplicas := replicas
+	progressing := appsv1.DeploymentCondition{
+		Type:    appsv1.DeploymentProgressing,
+		Status:  corev1.ConditionTrue,
+		Reason:  "NewReplicaSetAvailable",
+		Message: fmt.Sprintf("ReplicaSet %q has successfully progressed.", active.Name),
+	}
+	if !updated {
+		updatedReplicas = 0
+	}
+	if deployment.Spec.Paused {
+		progressing.Status = corev1.ConditionUnknown
+		progressing.Reason = "DeploymentPaused"
+		progressing.Message = "Deployment is paused"
+	}
+
+	now := metav1.Now()
+	progressing.LastUpdateTime = now
+	progressing.LastTransitionTime = now
+	deployment.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: deployment.Generation,
+		Replicas:           replicas,
+		UpdatedReplicas:    updatedReplicas,
+		ReadyReplicas:      replicas,
+		AvailableReplicas:  replicas,
+		Conditions: []appsv1.DeploymentCondition{
+			{
+				Type:               appsv1.DeploymentAvailable,
+				Status:             corev1.ConditionTrue,
+				Reason:             "MinimumReplicasAvailable",
+				Message:            "Deployment has minimum availability.",
+				LastUpdateTime:     now,
+				LastTransitionTime: now,
+			},
+			progressing,
+		},
+	}
+
+	return nil
+}
+
+// newSandboxReplicaSet creates the ReplicaSet the deployment controller would create for the current template
+func newSandboxReplicaSet(deployment *appsv1.Deployment) *appsv1.ReplicaSet {
+	template := templateWithoutHash(deployment.Spec.Template)
+	hash := sandboxTemplateHash(template)
+	if template.Labels == nil {
+		template.Labels = make(map[string]string)
+	}
+	template.Labels[podTemplateHashLabel] = hash
+
+	selector := deployment.Spec.Selector.DeepCopy()
+	if selector == nil {
+		selector = &metav1.LabelSelector{}
+	}
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = make(map[string]string)
+	}
+	selector.MatchLabels[podTemplateHashLabel] = hash
+
+	labels := make(map[string]string, len(template.Labels))
+	for key, value := range template.Labels {
+		labels[key] = value
+	}
+
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              deployment.Name + "-" + hash,
+			Namespace:         deployment.Namespace,
+			UID:               uuid.NewUUID(),
+			CreationTimestamp: metav1.Now(),
+			Labels:            labels,
+			Annotations:       make(map[string]string),
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind(KindDeployment)),
+			},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Selector: selector,
+			Template: template,
+		},
+	}
+}
+
+// sandboxTemplateHash derives a pod-template-hash from the template, like the deployment controller
+func sandboxTemplateHash(template corev1.PodTemplateSpec) string {
+	data, _ := json.Marshal(template)
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// syncPods creates or deletes pods until the ReplicaSet owns exactly desired running pods
+func (sc *sandboxController) syncPods(replicaSet *appsv1.ReplicaSet, desired int32) error {
+	list, err := sc.tracker.List(podsResource, corev1.SchemeGroupVersion.WithKind("Pod"), replicaSet.Namespace)
+	if err != nil {
+		return err
+	}
+
+	var owned []string
+	for i := range list.(*corev1.PodList).Items {
+		if metav1.IsControlledBy(&list.(*corev1.PodList).Items[i], replicaSet) {
+			owned = append(owned, list.(*corev1.PodList).Items[i].Name)
+		}
+	}
+
+	for i := int(desired); i < len(owned); i++ {
+		if err := sc.tracker.Delete(podsResource, replicaSet.Namespace, owned[i]); err != nil {
+			return err
+		}
+	}
+
+	nodes, err := sc.nodeNames()
+	if err != nil {
+		return err
+	}
+	for i := len(owned); i < int(desired); i++ {
+		node := ""
+		if len(nodes) > 0 {
+			node = nodes[i%len(nodes)]
+		}
+		if err := sc.tracker.Create(podsResource, newSandboxPod(replicaSet, node), replicaSet.Namespace); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nodeNames returns the names of the fixture's nodes
+func (sc *sandboxController) nodeNames() ([]string, error) {
+	list, err := sc.tracker.List(nodesResource, corev1.SchemeGroupVersion.WithKind("Node"), "")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, node := range list.(*corev1.NodeList).Items {
+		names = append(names, node.Name)
+	}
+	return names, nil
+}
+
+// newSandboxPod creates a running, ready pod for a ReplicaSet
+func newSandboxPod(replicaSet *appsv1.ReplicaSet, node string) *corev1.Pod {
+	template := replicaSet.Spec.Template.DeepCopy()
+	now := metav1.Now()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              replicaSet.Name + "-" + rand.String(5),
+			Namespace:         replicaSet.Namespace,
+			UID:               uuid.NewUUID(),
+			CreationTimestamp: now,
+			Labels:            template.Labels,
+			Annotations:       template.Annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(replicaSet, appsv1.SchemeGroupVersion.WithKind("ReplicaSet")),
+			},
+		},
+		Spec: template.Spec,
+		Status: corev1.PodStatus{
+			Phase:     corev1.PodRunning,
+			StartTime: &now,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: now},
+			},
+		},
+	}
+	pod.Spec.NodeName = node
+
+	started := true
+	for _, container := range template.Spec.Containers {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:    container.Name,
+			Image:   container.Image,
+			Ready:   true,
+			Started: &started,
+			State: corev1.ContainerState{
+				Running: &corev1.ContainerStateRunning{StartedAt: now},
+			},
+		})
+	}
+
+	return pod
+}
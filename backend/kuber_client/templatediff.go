@@ -0,0 +1,212 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// LiveRevision selects the live pod template of a deployment instead of a recorded revision
+const LiveRevision = "live"
+
+// ErrRevisionNotFound is returned when a revision to compare is not in the deployment's history
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Sections of a pod template a TemplateDiff reports as changed
+const (
+	SectionImages    = "images"
+	SectionEnv       = "env"
+	SectionResources = "resources"
+	SectionProbes    = "probes"
+	SectionVolumes   = "volumes"
+	SectionOther     = "other"
+)
+
+// TemplateRef identifies one side of a TemplateDiff
+type TemplateRef struct {
+	Revision   int64  `json:"revision,omitempty"`
+	ReplicaSet string `json:"replicaSet,omitempty"`
+	Live       bool   `json:"live,omitempty"`
+}
+
+// String names the side in the diff header, e.g. "revision 7 (app-7d9f)" or "live"
+func (r TemplateRef) String() string {
+	if r.Live {
+		return LiveRevision
+	}
+	return fmt.Sprintf("revision %d (%s)", r.Revision, r.ReplicaSet)
+}
+
+// TemplateDiff compares the pod templates of two revisions of a deployment
+type TemplateDiff struct {
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace"`
+	From      TemplateRef  `json:"from"`
+	To        TemplateRef  `json:"to"`
+	Sections  []string     `json:"sections"` // Parts of the template that changed, e.g. images and env
+	Changes   []SpecChange `json:"changes"`
+	Diff      string       `json:"diff"` // Unified diff of the templates as YAML, empty when they are equal
+}
+
+// DiffDeploymentRevisions compares the pod templates of two revisions of a deployment. A revision is
+// given by number, ReplicaSet name or LiveRevision. from defaults to the revision before the current one
+// and to defaults to the live template, answering what the last update changed.
+func (c *Client) DiffDeploymentRevisions(ctx context.Context, namespace, name, from, to string) (*TemplateDiff, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+	if to == "" {
+		to = LiveRevision
+	}
+
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s in namespace %s: %v", name, namespace, err)
+	}
+
+	replicaSets, _, err := c.getDeploymentReplicaSets(ctx, deployment)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]templateRevision, 0, len(replicaSets))
+	for i := range replicaSets {
+		history = append(history, replicaSetTemplateRevision(&replicaSets[i]))
+	}
+
+	if from == "" {
+		currentRevision, _ := strconv.ParseInt(deployment.Annotations[revisionAnnotation], 10, 64)
+		for i := range history {
+			if history[i].Number < currentRevision {
+				from = strconv.FormatInt(history[i].Number, 10)
+				break
+			}
+		}
+		if from == "" {
+			return nil, fmt.Errorf("%w: deployment %s has no revision before the current one", ErrRevisionNotFound, name)
+		}
+	}
+
+	resolve := func(id string) (TemplateRef, corev1.PodTemplateSpec, error) {
+		if strings.EqualFold(id, LiveRevision) {
+			return TemplateRef{Live: true}, deployment.Spec.Template, nil
+		}
+		revision := findRevision(history, id)
+		if revision == nil {
+			return TemplateRef{}, corev1.PodTemplateSpec{}, fmt.Errorf("%w: revision %s of deployment %s", ErrRevisionNotFound, id, name)
+		}
+		return TemplateRef{Revision: revision.Number, ReplicaSet: revision.Name}, revision.Template, nil
+	}
+
+	fromRef, fromTemplate, err := resolve(from)
+	if err != nil {
+		return nil, err
+	}
+	toRef, toTemplate, err := resolve(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffTemplates(name, namespace, fromRef, toRef, fromTemplate, toTemplate)
+}
+
+// diffTemplates compares two pod templates, ignoring the pod-template-hash label
+func diffTemplates(name, namespace string, fromRef, toRef TemplateRef, fromTemplate, toTemplate corev1.PodTemplateSpec) (*TemplateDiff, error) {
+	fromTemplate = templateWithoutHash(fromTemplate)
+	toTemplate = templateWithoutHash(toTemplate)
+
+	changes, err := diffSpecs(fromTemplate, toTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	fromYAML, err := templateYAML(fromTemplate)
+	if err != nil {
+		return nil, err
+	}
+	toYAML, err := templateYAML(toTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromYAML),
+		B:        difflib.SplitLines(toYAML),
+		FromFile: fromRef.String(),
+		ToFile:   toRef.String(),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff pod templates: %v", err)
+	}
+
+	if changes == nil {
+		changes = []SpecChange{}
+	}
+	return &TemplateDiff{
+		Name:      name,
+		Namespace: namespace,
+		From:      fromRef,
+		To:        toRef,
+		Sections:  changedSections(changes),
+		Changes:   changes,
+		Diff:      diff,
+	}, nil
+}
+
+// templateYAML renders a pod template the way kubectl shows it, without the empty creation timestamp
+func templateYAML(template corev1.PodTemplateSpec) (string, error) {
+	generic, err := toGeneric(template)
+	if err != nil {
+		return "", err
+	}
+	if metadata, ok := generic.(map[string]interface{})["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+	}
+
+	data, err := yaml.Marshal(generic)
+	if err != nil {
+		return "", fmt.Errorf("failed to render pod template: %v", err)
+	}
+	return string(data), nil
+}
+
+// changedSections sorts the changed paths of a pod template into sections
+func changedSections(changes []SpecChange) []string {
+	found := make(map[string]bool)
+	for _, change := range changes {
+		found[templateSection(change.Path)] = true
+	}
+
+	sections := make([]string, 0, len(found))
+	for section := range found {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	return sections
+}
+
+// templateSection returns the section a path of a pod template belongs to
+func templateSection(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".image"):
+		return SectionImages
+	case strings.Contains(path, ".env[") || strings.Contains(path, ".envFrom") || strings.HasSuffix(path, ".env"):
+		return SectionEnv
+	case strings.Contains(path, ".resources"):
+		return SectionResources
+	case strings.Contains(path, "Probe"):
+		return SectionProbes
+	case strings.Contains(path, "spec.volumes") || strings.Contains(path, ".volumeMounts"):
+		return SectionVolumes
+	default:
+		return SectionOther
+	}
+}
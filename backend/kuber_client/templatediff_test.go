@@ -0,0 +1,116 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// envTemplate returns the pod template of testTemplate with a LOG_LEVEL variable and the label of its ReplicaSet
+func envTemplate(image, logLevel, hash string) corev1.PodTemplateSpec {
+	template := testTemplate(image)
+	template.Labels["pod-template-hash"] = hash
+	template.Spec.Containers[0].Env = []corev1.EnvVar{
+		{Name: "PORT", Value: "8080"},
+		{Name: "LOG_LEVEL", Value: logLevel},
+	}
+	return template
+}
+
+// assertGolden compares got to the golden file testdata/name, rewriting it when -update is set
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s, run go test -update to rewrite it\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestDiffTemplates(t *testing.T) {
+	diff, err := diffTemplates("web", "default",
+		TemplateRef{Revision: 1, ReplicaSet: "web-58b7c"}, TemplateRef{Live: true},
+		envTemplate("web:v1", "info", "58b7c"), envTemplate("web:v2", "debug", "6d4f9"))
+	if err != nil {
+		t.Fatalf("diffTemplates: %v", err)
+	}
+
+	assertGolden(t, "templatediff.golden", diff.Diff)
+
+	if want := []string{SectionEnv, SectionImages}; !reflect.DeepEqual(diff.Sections, want) {
+		t.Errorf("got sections %v, want %v", diff.Sections, want)
+	}
+	want := []SpecChange{
+		{Path: "spec.containers[0].env[1].value", Before: "info", After: "debug"},
+		{Path: "spec.containers[0].image", Before: "web:v1", After: "web:v2"},
+	}
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("got changes %+v, want %+v", diff.Changes, want)
+	}
+
+	// Templates differing only in their ReplicaSet label are equal
+	same, err := diffTemplates("web", "default", TemplateRef{Revision: 1, ReplicaSet: "web-58b7c"}, TemplateRef{Live: true},
+		envTemplate("web:v1", "info", "58b7c"), envTemplate("web:v1", "info", "6d4f9"))
+	if err != nil {
+		t.Fatalf("diffTemplates: %v", err)
+	}
+	if same.Diff != "" || len(same.Changes) != 0 || len(same.Sections) != 0 {
+		t.Errorf("got diff %+v of equal templates, want none", same)
+	}
+}
+
+func TestDiffDeploymentRevisions(t *testing.T) {
+	previous := testReplicaSet("web-58b7c", "web-uid", 1, "web:v1", "")
+	current := testReplicaSet("web-6d4f9", "web-uid", 2, "web:v2", "")
+	client, _ := newTestClient(testDeployment("web:v2", 2, ""), previous, current)
+
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom TemplateRef
+		wantTo   TemplateRef
+		wantErr  error
+	}{
+		{"defaults to the last update", "", "", TemplateRef{Revision: 1, ReplicaSet: "web-58b7c"}, TemplateRef{Live: true}, nil},
+		{"by number and ReplicaSet name", "2", "web-58b7c", TemplateRef{Revision: 2, ReplicaSet: "web-6d4f9"}, TemplateRef{Revision: 1, ReplicaSet: "web-58b7c"}, nil},
+		{"unknown revision", "7", "", TemplateRef{}, TemplateRef{}, ErrRevisionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := client.DiffDeploymentRevisions(context.Background(), "default", "web", tt.from, tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DiffDeploymentRevisions: %v", err)
+			}
+			if diff.From != tt.wantFrom || diff.To != tt.wantTo {
+				t.Errorf("compared %s to %s, want %s to %s", diff.From, diff.To, tt.wantFrom, tt.wantTo)
+			}
+			if want := []string{SectionImages}; !reflect.DeepEqual(diff.Sections, want) {
+				t.Errorf("got sections %v, want %v", diff.Sections, want)
+			}
+		})
+	}
+}
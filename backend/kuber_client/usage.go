@@ -0,0 +1,223 @@
+package kuberclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResourceUsage is the live usage of one resource compared to what was requested, limited and allocatable.
+// Percentages are left out when the reference value is not set.
+type ResourceUsage struct {
+	Usage              string   `json:"usage"`
+	Requests           string   `json:"requests,omitempty"`
+	Limits             string   `json:"limits,omitempty"`
+	Allocatable        string   `json:"allocatable,omitempty"`
+	RequestsPercent    *float64 `json:"requestsPercent,omitempty"`
+	LimitsPercent      *float64 `json:"limitsPercent,omitempty"`
+	AllocatablePercent *float64 `json:"allocatablePercent,omitempty"`
+}
+
+// Usage is the CPU and memory usage reported by metrics-server
+type Usage struct {
+	CPU    ResourceUsage `json:"cpu"`
+	Memory ResourceUsage `json:"memory"`
+}
+
+// listNodeUsage returns the usage of all nodes by node name
+func (c *Client) listNodeUsage(ctx context.Context) (map[string]corev1.ResourceList, error) {
+	if c.metrics == nil {
+		return nil, fmt.Errorf("metrics API client not configured")
+	}
+
+	nodeMetrics, err := c.metrics.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("metrics API not available, is metrics-server installed? %v", err)
+	}
+
+	usage := make(map[string]corev1.ResourceList, len(nodeMetrics.Items))
+	for _, metrics := range nodeMetrics.Items {
+		usage[metrics.Name] = metrics.Usage
+	}
+
+	return usage, nil
+}
+
+// listPodUsage returns the usage of all pods in a namespace ("" for all namespaces) keyed by namespace/name
+func (c *Client) listPodUsage(ctx context.Context, namespace string) (map[string]corev1.ResourceList, error) {
+	if c.metrics == nil {
+		return nil, fmt.Errorf("metrics API client not configured")
+	}
+
+	podMetrics, err := c.metrics.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("metrics API not available, is metrics-server installed? %v", err)
+	}
+
+	usage := make(map[string]corev1.ResourceList, len(podMetrics.Items))
+	for _, metrics := range podMetrics.Items {
+		total := corev1.ResourceList{}
+		for _, container := range metrics.Containers {
+			addResources(total, container.Usage)
+		}
+		usage[metrics.Namespace+"/"+metrics.Name] = total
+	}
+
+	return usage, nil
+}
+
+// nodeUsage compares the usage of a node with its allocatable resources
+func nodeUsage(used, allocatable corev1.ResourceList) *Usage {
+	usage := &Usage{}
+	for _, entry := range []struct {
+		name   corev1.ResourceName
+		target *ResourceUsage
+	}{
+		{corev1.ResourceCPU, &usage.CPU},
+		{corev1.ResourceMemory, &usage.Memory},
+	} {
+		value := used[entry.name]
+		entry.target.Usage = value.String()
+		if total, ok := allocatable[entry.name]; ok {
+			entry.target.Allocatable = total.String()
+			entry.target.AllocatablePercent = percentOf(value, total)
+		}
+	}
+	return usage
+}
+
+// podUsage compares the usage of a pod with the requests and limits of its containers
+func podUsage(used corev1.ResourceList, pod *corev1.Pod) *Usage {
+	requests, limits := podRequestsAndLimits(pod)
+
+	usage := &Usage{}
+	for _, entry := range []struct {
+		name   corev1.ResourceName
+		target *ResourceUsage
+	}{
+		{corev1.ResourceCPU, &usage.CPU},
+		{corev1.ResourceMemory, &usage.Memory},
+	} {
+		value := used[entry.name]
+		entry.target.Usage = value.String()
+		if request, ok := requests[entry.name]; ok {
+			entry.target.Requests = request.String()
+			entry.target.RequestsPercent = percentOf(value, request)
+		}
+		if limit, ok := limits[entry.name]; ok {
+			entry.target.Limits = limit.String()
+			entry.target.LimitsPercent = percentOf(value, limit)
+		}
+	}
+	return usage
+}
+
+// podRequestsAndLimits returns the effective requests and limits of a pod, see podResources.
+// A resource is only part of limits if every container limits it, otherwise the pod is unbounded.
+func podRequestsAndLimits(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	requests, limits := podResources(pod)
+
+	for _, container := range podLongRunningContainers(pod) {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if _, ok := container.Resources.Limits[name]; !ok {
+				delete(limits, name)
+			}
+		}
+	}
+
+	return requests, limits
+}
+
+// podResources returns what a pod reserves on its node the way the scheduler and kubectl describe node count it:
+// the larger of the summed long-running containers and the biggest init container, plus the pod overhead.
+// Containers without a limit add nothing to the limits.
+func podResources(pod *corev1.Pod) (corev1.ResourceList, corev1.ResourceList) {
+	requests := corev1.ResourceList{}
+	limits := corev1.ResourceList{}
+	for _, container := range podLongRunningContainers(pod) {
+		addResources(requests, container.Resources.Requests)
+		addResources(limits, container.Resources.Limits)
+	}
+
+	// An init container runs next to the sidecars started before it
+	sidecarRequests := corev1.ResourceList{}
+	sidecarLimits := corev1.ResourceList{}
+	for _, container := range pod.Spec.InitContainers {
+		if isSidecar(container) {
+			addResources(sidecarRequests, container.Resources.Requests)
+			addResources(sidecarLimits, container.Resources.Limits)
+			continue
+		}
+		maxResources(requests, withResources(sidecarRequests, container.Resources.Requests))
+		maxResources(limits, withResources(sidecarLimits, container.Resources.Limits))
+	}
+
+	addResources(requests, pod.Spec.Overhead)
+	addResources(limits, pod.Spec.Overhead)
+
+	return requests, limits
+}
+
+// podLongRunningContainers returns the containers that run for the whole life of a pod, its regular
+// containers and the sidecars, which are init containers that restart always
+func podLongRunningContainers(pod *corev1.Pod) []corev1.Container {
+	containers := append([]corev1.Container{}, pod.Spec.Containers...)
+	for _, container := range pod.Spec.InitContainers {
+		if isSidecar(container) {
+			containers = append(containers, container)
+		}
+	}
+	return containers
+}
+
+// isSidecar reports whether an init container keeps running next to the regular containers
+func isSidecar(container corev1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// withResources returns the sum of two resource lists
+func withResources(a, b corev1.ResourceList) corev1.ResourceList {
+	sum := a.DeepCopy()
+	addResources(sum, b)
+	return sum
+}
+
+// maxResources raises every quantity in dst to the one in src where src is larger
+func maxResources(dst, src corev1.ResourceList) {
+	for name, quantity := range src {
+		if current, ok := dst[name]; !ok || quantity.Cmp(current) > 0 {
+			dst[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+// addResources adds every quantity in src to dst
+func addResources(dst, src corev1.ResourceList) {
+	for name, quantity := range src {
+		total := dst[name]
+		total.Add(quantity)
+		dst[name] = total
+	}
+}
+
+// percentOf returns value as a percentage of total rounded to one decimal, or nil for an empty total
+func percentOf(value, total resource.Quantity) *float64 {
+	if total.IsZero() {
+		return nil
+	}
+	percent := math.Round(float64(value.MilliValue())/float64(total.MilliValue())*1000) / 10
+	return &percent
+}
+
+// resourceListToMap converts a resource list into plain quantity strings
+func resourceListToMap(resources corev1.ResourceList) map[string]interface{} {
+	result := make(map[string]interface{}, len(resources))
+	for name, quantity := range resources {
+		result[string(name)] = quantity.String()
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package kuberclient
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resourceList returns a CPU and memory resource list, an empty quantity is left out
+func resourceList(cpu, memory string) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	if cpu != "" {
+		list[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+// testContainer returns a container requesting cpu and memory, limited to twice as much
+func testContainer(name, cpu, memory string) corev1.Container {
+	limits := corev1.ResourceList{}
+	for resourceName, quantity := range resourceList(cpu, memory) {
+		quantity.Add(quantity)
+		limits[resourceName] = quantity
+	}
+	return corev1.Container{
+		Name: name,
+		Resources: corev1.ResourceRequirements{
+			Requests: resourceList(cpu, memory),
+			Limits:   limits,
+		},
+	}
+}
+
+// testSidecar returns an init container that keeps running next to the regular containers
+func testSidecar(name, cpu, memory string) corev1.Container {
+	container := testContainer(name, cpu, memory)
+	always := corev1.ContainerRestartPolicyAlways
+	container.RestartPolicy = &always
+	return container
+}
+
+// equalResources reports whether two resource lists hold the same quantities
+func equalResources(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, quantity := range a {
+		other, ok := b[name]
+		if !ok || quantity.Cmp(other) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPodResources(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         corev1.PodSpec
+		wantRequests corev1.ResourceList
+		wantLimits   corev1.ResourceList
+	}{
+		{
+			name: "regular containers are summed",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				testContainer("app", "100m", "128Mi"),
+				testContainer("proxy", "50m", "64Mi"),
+			}},
+			wantRequests: resourceList("150m", "192Mi"),
+			wantLimits:   resourceList("300m", "384Mi"),
+		},
+		{
+			name: "biggest init container wins per resource",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					testContainer("setup", "500m", "64Mi"),
+					testContainer("migrate", "200m", "512Mi"),
+				},
+				Containers: []corev1.Container{testContainer("app", "100m", "128Mi")},
+			},
+			wantRequests: resourceList("500m", "512Mi"),
+			wantLimits:   resourceList("1", "1Gi"),
+		},
+		{
+			name: "regular containers win over a smaller init container",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{testContainer("setup", "50m", "32Mi")},
+				Containers:     []corev1.Container{testContainer("app", "100m", "128Mi")},
+			},
+			wantRequests: resourceList("100m", "128Mi"),
+			wantLimits:   resourceList("200m", "256Mi"),
+		},
+		{
+			name: "sidecars run with the regular containers and the init containers after them",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					testContainer("setup", "450m", "32Mi"),
+					testSidecar("mesh", "100m", "64Mi"),
+					testContainer("migrate", "400m", "128Mi"),
+				},
+				Containers: []corev1.Container{testContainer("app", "200m", "128Mi")},
+			},
+			// setup alone: 450m/32Mi, migrate with mesh: 500m/192Mi, app with mesh: 300m/192Mi
+			wantRequests: resourceList("500m", "192Mi"),
+			wantLimits:   resourceList("1", "384Mi"),
+		},
+		{
+			name: "overhead is added",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{testContainer("setup", "500m", "64Mi")},
+				Containers:     []corev1.Container{testContainer("app", "100m", "128Mi")},
+				Overhead:       resourceList("250m", "120Mi"),
+			},
+			wantRequests: resourceList("750m", "248Mi"),
+			wantLimits:   resourceList("1250m", "376Mi"),
+		},
+		{
+			name: "containers without limits add nothing to the limits",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				testContainer("app", "100m", "128Mi"),
+				{Name: "debug", Resources: corev1.ResourceRequirements{Requests: resourceList("10m", "")}},
+			}},
+			wantRequests: resourceList("110m", "128Mi"),
+			wantLimits:   resourceList("200m", "256Mi"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, limits := podResources(&corev1.Pod{Spec: tt.spec})
+			if !equalResources(requests, tt.wantRequests) {
+				t.Errorf("got requests %v, want %v", resourceListToMap(requests), resourceListToMap(tt.wantRequests))
+			}
+			if !equalResources(limits, tt.wantLimits) {
+				t.Errorf("got limits %v, want %v", resourceListToMap(limits), resourceListToMap(tt.wantLimits))
+			}
+		})
+	}
+}
+
+func TestPodRequestsAndLimits(t *testing.T) {
+	// The debug container has no CPU limit, the pod can use any free CPU
+	debug := testContainer("debug", "", "64Mi")
+	debug.Resources.Requests[corev1.ResourceCPU] = resource.MustParse("10m")
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{testContainer("setup", "500m", "1Gi")},
+		Containers:     []corev1.Container{testContainer("app", "100m", "128Mi"), debug},
+	}}
+
+	requests, limits := podRequestsAndLimits(pod)
+	if want := resourceList("500m", "1Gi"); !equalResources(requests, want) {
+		t.Errorf("got requests %v, want %v", resourceListToMap(requests), resourceListToMap(want))
+	}
+	if want := resourceList("", "2Gi"); !equalResources(limits, want) {
+		t.Errorf("got limits %v, want %v", resourceListToMap(limits), resourceListToMap(want))
+	}
+}
@@ -0,0 +1,588 @@
+package kuberclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// Supported workload kinds
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+)
+
+// errNoChange is returned by a mutate function when the workload must not be updated
+var errNoChange = errors.New("no change")
+
+// ParseKind normalizes a workload kind, an empty kind means Deployment
+func ParseKind(kind string) (string, error) {
+	switch strings.ToLower(kind) {
+	case "", "deployment", "deployments", "deploy":
+		return KindDeployment, nil
+	case "statefulset", "statefulsets", "sts":
+		return KindStatefulSet, nil
+	case "daemonset", "daemonsets", "ds":
+		return KindDaemonSet, nil
+	default:
+		return "", fmt.Errorf("unsupported workload kind %q", kind)
+	}
+}
+
+// workloadAPI is the part of a typed apps/v1 client needed to mutate a workload
+type workloadAPI[T any] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+}
+
+// localDryRunAPI answers dry-run updates with the mutated object instead of sending them,
+// for clientsets that ignore the dryRun option
+type localDryRunAPI[T any] struct {
+	workloadAPI[T]
+}
+
+func (api localDryRunAPI[T]) Update(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error) {
+	if len(opts.DryRun) > 0 {
+		return obj, nil
+	}
+	return api.workloadAPI.Update(ctx, obj, opts)
+}
+
+// dryRunAPI wraps api in localDryRunAPI if the client cannot rely on server-side dry runs
+func dryRunAPI[T any](c *Client, api workloadAPI[T]) workloadAPI[T] {
+	if c.localDryRun {
+		return localDryRunAPI[T]{api}
+	}
+	return api
+}
+
+// updateWorkload fetches a workload, applies mutate to it and writes it back, retrying on conflicts.
+// With dryRun the update is only evaluated by the API server and the diff of spec before and after is returned.
+func updateWorkload[T any](ctx context.Context, api workloadAPI[T], kind, namespace, name string, dryRun bool, spec func(T) interface{}, mutate func(T) error) (*MutationResult, error) {
+	result := &MutationResult{DryRun: dryRun}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get the workload
+		obj, err := api.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get %s %s in namespace %s: %v", strings.ToLower(kind), name, namespace, err)
+		}
+
+		before, err := toGeneric(spec(obj))
+		if err != nil {
+			return err
+		}
+
+		if err := mutate(obj); err != nil {
+			return err
+		}
+
+		options := metav1.UpdateOptions{}
+		if dryRun {
+			options.DryRun = []string{metav1.DryRunAll}
+		}
+
+		// Update the workload
+		updated, err := api.Update(ctx, obj, options)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			result.Changes, err = diffSpecs(before, spec(updated))
+		}
+		return err
+	})
+	if err != nil && !errors.Is(err, errNoChange) {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// updateStatefulSet is updateWorkload for statefulsets
+func (c *Client) updateStatefulSet(ctx context.Context, namespace, name string, dryRun bool, mutate func(*appsv1.StatefulSet) error) (*MutationResult, error) {
+	return updateWorkload(ctx, dryRunAPI[*appsv1.StatefulSet](c, c.clientset.AppsV1().StatefulSets(namespace)), KindStatefulSet, namespace, name, dryRun,
+		func(statefulSet *appsv1.StatefulSet) interface{} { return statefulSet.Spec }, mutate)
+}
+
+// updateDaemonSet is updateWorkload for daemonsets
+func (c *Client) updateDaemonSet(ctx context.Context, namespace, name string, dryRun bool, mutate func(*appsv1.DaemonSet) error) (*MutationResult, error) {
+	return updateWorkload(ctx, dryRunAPI[*appsv1.DaemonSet](c, c.clientset.AppsV1().DaemonSets(namespace)), KindDaemonSet, namespace, name, dryRun,
+		func(daemonSet *appsv1.DaemonSet) interface{} { return daemonSet.Spec }, mutate)
+}
+
+// restartTemplate adds a timestamp annotation to the pod template to force a restart
+func restartTemplate(template *corev1.PodTemplateSpec) {
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations["kubectl.kubernetes.io/restartedAt"] = metav1.Now().Format(metav1.RFC3339Micro)
+}
+
+// setTemplateImage updates the selected containers of a pod template with a new image or version.
+// It returns the names of the containers that were changed.
+func setTemplateImage(template *corev1.PodTemplateSpec, config ServiceConfig) ([]string, error) {
+	if config.Image != "" {
+		if _, err := ParseImageReference(config.Image); err != nil {
+			return nil, err
+		}
+	}
+
+	containers, err := selectContainers(&template.Spec, config)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(containers))
+	for _, container := range containers {
+		if config.Image != "" {
+			container.Image = config.Image
+		} else {
+			// Keep registry and repository, replace the tag or digest
+			current, err := ParseImageReference(container.Image)
+			if err != nil {
+				return nil, fmt.Errorf("cannot derive a new image for container %s: %v", container.Name, err)
+			}
+			updated, err := current.WithVersion(config.Version)
+			if err != nil {
+				return nil, err
+			}
+			container.Image = updated.String()
+		}
+		names = append(names, container.Name)
+	}
+
+	return names, nil
+}
+
+// selectContainers picks the containers an image update applies to.
+// Without an explicit container, a single-container pod updates that container and a multi-container pod
+// updates only the containers that already run the repository of the new image, so sidecars are left alone.
+func selectContainers(spec *corev1.PodSpec, config ServiceConfig) ([]*corev1.Container, error) {
+	if config.Container != "" {
+		for i := range spec.Containers {
+			if spec.Containers[i].Name == config.Container {
+				return []*corev1.Container{&spec.Containers[i]}, nil
+			}
+		}
+		for i := range spec.InitContainers {
+			if spec.InitContainers[i].Name == config.Container {
+				return []*corev1.Container{&spec.InitContainers[i]}, nil
+			}
+		}
+		return nil, fmt.Errorf("container %s not found, available containers: %s", config.Container, strings.Join(containerNames(spec), ", "))
+	}
+
+	if len(spec.Containers) == 1 {
+		return []*corev1.Container{&spec.Containers[0]}, nil
+	}
+
+	var selected []*corev1.Container
+	if config.Image != "" {
+		target, err := ParseImageReference(config.Image)
+		if err != nil {
+			return nil, err
+		}
+		for i := range spec.Containers {
+			current, err := ParseImageReference(spec.Containers[i].Image)
+			if err == nil && current.Repository() == target.Repository() {
+				selected = append(selected, &spec.Containers[i])
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("pod has %d containers (%s), specify which one to update with container",
+			len(spec.Containers), strings.Join(containerNames(spec), ", "))
+	}
+
+	return selected, nil
+}
+
+// containerNames lists the containers of a pod spec, init containers are marked as such
+func containerNames(spec *corev1.PodSpec) []string {
+	names := make([]string, 0, len(spec.Containers)+len(spec.InitContainers))
+	for _, container := range spec.Containers {
+		names = append(names, container.Name)
+	}
+	for _, container := range spec.InitContainers {
+		names = append(names, container.Name+" (init)")
+	}
+	return names
+}
+
+// templateContainers returns the named container of a pod template, or all regular containers if name is empty
+func templateContainers(template corev1.PodTemplateSpec, name string) []corev1.Container {
+	if name == "" {
+		return template.Spec.Containers
+	}
+	for _, containers := range [][]corev1.Container{template.Spec.Containers, template.Spec.InitContainers} {
+		for _, container := range containers {
+			if container.Name == name {
+				return []corev1.Container{container}
+			}
+		}
+	}
+	return nil
+}
+
+// ScaleWorkload scales a deployment or statefulset to the specified number of replicas.
+// A workload managed by an HPA is only scaled with AdjustHPA, which pins the HPA bounds first.
+func (c *Client) ScaleWorkload(ctx context.Context, config ServiceConfig) (*MutationResult, error) {
+	kind, err := ParseKind(config.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if config.Namespace == "" {
+		config.Namespace = "default"
+	}
+
+	if kind == KindDaemonSet {
+		return nil, fmt.Errorf("daemonset %s cannot be scaled, it runs one pod per eligible node", config.Name)
+	}
+
+	// An HPA would revert the new replica count within minutes. Scaling to zero is fine,
+	// the HPA stays inactive as long as its target has no replicas.
+	var adjustment *HPAAdjustment
+	if config.Replicas > 0 {
+		hpa, err := c.findWorkloadHPA(ctx, config.Namespace, kind, config.Name)
+		if err != nil {
+			return nil, err
+		}
+		if hpa != nil {
+			if !config.AdjustHPA {
+				return nil, fmt.Errorf("%w: %s %s is scaled by %s (min %d, max %d), set adjustHpa to pin its bounds to %d replicas",
+					ErrManagedByHPA, strings.ToLower(kind), config.Name, hpa.Name, hpaMinReplicas(hpa), hpa.Spec.MaxReplicas, config.Replicas)
+			}
+			adjustment, err = c.pinHPA(ctx, hpa, config.Replicas, config.DryRun)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var result *MutationResult
+	if kind == KindStatefulSet {
+		result, err = c.updateStatefulSet(ctx, config.Namespace, config.Name, config.DryRun, func(statefulSet *appsv1.StatefulSet) error {
+			statefulSet.Spec.Replicas = &config.Replicas
+			return nil
+		})
+	} else {
+		result, err = c.ScaleDeployment(ctx, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.HPA = adjustment
+	return result, nil
+}
+
+// RestartWorkload restarts all pods of a deployment, statefulset or daemonset
+func (c *Client) RestartWorkload(ctx context.Context, config ServiceConfig) (*MutationResult, error) {
+	kind, err := ParseKind(config.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if config.Namespace == "" {
+		config.Namespace = "default"
+	}
+
+	switch kind {
+	case KindStatefulSet:
+		return c.updateStatefulSet(ctx, config.Namespace, config.Name, config.DryRun, func(statefulSet *appsv1.StatefulSet) error {
+			restartTemplate(&statefulSet.Spec.Template)
+			return nil
+		})
+	case KindDaemonSet:
+		return c.updateDaemonSet(ctx, config.Namespace, config.Name, config.DryRun, func(daemonSet *appsv1.DaemonSet) error {
+			restartTemplate(&daemonSet.Spec.Template)
+			return nil
+		})
+	default:
+		return c.RestartDeployment(ctx, config)
+	}
+}
+
+// UpdateWorkload updates a deployment, statefulset or daemonset with a new image or version
+func (c *Client) UpdateWorkload(ctx context.Context, config ServiceConfig) (*MutationResult, error) {
+	kind, err := ParseKind(config.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if config.Namespace == "" {
+		config.Namespace = "default"
+	}
+	if config.Image == "" && config.Version == "" {
+		return nil, fmt.Errorf("either image or version must be specified")
+	}
+
+	switch kind {
+	case KindStatefulSet:
+		var containers []string
+		result, err := c.updateStatefulSet(ctx, config.Namespace, config.Name, config.DryRun, func(statefulSet *appsv1.StatefulSet) error {
+			containers, err = setTemplateImage(&statefulSet.Spec.Template, config)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Containers = containers
+		return result, nil
+	case KindDaemonSet:
+		var containers []string
+		result, err := c.updateDaemonSet(ctx, config.Namespace, config.Name, config.DryRun, func(daemonSet *appsv1.DaemonSet) error {
+			containers, err = setTemplateImage(&daemonSet.Spec.Template, config)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Containers = containers
+		return result, nil
+	default:
+		return c.UpdateDeployment(ctx, config)
+	}
+}
+
+// RollbackWorkload rolls back a deployment, statefulset or daemonset.
+// StatefulSets and DaemonSets take their history from ControllerRevisions.
+func (c *Client) RollbackWorkload(ctx context.Context, config ServiceConfig) (*RollbackResult, error) {
+	kind, err := ParseKind(config.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if config.Namespace == "" {
+		config.Namespace = "default"
+	}
+
+	var result *RollbackResult
+	var mutation *MutationResult
+
+	switch kind {
+	case KindStatefulSet:
+		mutation, err = c.updateStatefulSet(ctx, config.Namespace, config.Name, config.DryRun, func(statefulSet *appsv1.StatefulSet) error {
+			history, foreign, err := c.getControllerRevisions(ctx, statefulSet, statefulSet.Spec.Selector)
+			if err != nil {
+				return err
+			}
+
+			// The update revision is the one the controller rolls pods towards
+			var currentRevision int64
+			for _, revision := range history {
+				if revision.Name == statefulSet.Status.UpdateRevision {
+					currentRevision = revision.Number
+				}
+			}
+			if currentRevision == 0 && len(history) > 0 {
+				currentRevision = history[0].Number
+			}
+
+			var target *templateRevision
+			result, target, err = prepareRollback(kind, config, history, currentRevision, foreign, statefulSet.Spec.Template)
+			if err != nil {
+				return err
+			}
+
+			statefulSet.Spec.Template = target.Template
+			restoreChangeCause(statefulSet, target)
+			return nil
+		})
+	case KindDaemonSet:
+		mutation, err = c.updateDaemonSet(ctx, config.Namespace, config.Name, config.DryRun, func(daemonSet *appsv1.DaemonSet) error {
+			history, foreign, err := c.getControllerRevisions(ctx, daemonSet, daemonSet.Spec.Selector)
+			if err != nil {
+				return err
+			}
+
+			// The newest revision is the one the controller rolls pods towards
+			var currentRevision int64
+			if len(history) > 0 {
+				currentRevision = history[0].Number
+			}
+
+			var target *templateRevision
+			result, target, err = prepareRollback(kind, config, history, currentRevision, foreign, daemonSet.Spec.Template)
+			if err != nil {
+				return err
+			}
+
+			daemonSet.Spec.Template = target.Template
+			restoreChangeCause(daemonSet, target)
+			return nil
+		})
+	default:
+		return c.RollbackDeployment(ctx, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.MutationResult = *mutation
+	return result, nil
+}
+
+// GetWorkloadStatus gets the status of a deployment, statefulset or daemonset
+func (c *Client) GetWorkloadStatus(ctx context.Context, namespace, name, kind string) (map[string]interface{}, error) {
+	kind, err := ParseKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	var status map[string]interface{}
+
+	switch kind {
+	case KindStatefulSet:
+		statefulSet, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s in namespace %s: %v", name, namespace, err)
+		}
+
+		status = map[string]interface{}{
+			"kind":               KindStatefulSet,
+			"name":               statefulSet.Name,
+			"namespace":          statefulSet.Namespace,
+			"replicas":           statefulSet.Status.Replicas,
+			"available":          statefulSet.Status.AvailableReplicas,
+			"ready":              statefulSet.Status.ReadyReplicas,
+			"updated":            statefulSet.Status.UpdatedReplicas,
+			"unavailable":        statefulSet.Status.Replicas - statefulSet.Status.AvailableReplicas,
+			"currentRevision":    statefulSet.Status.CurrentRevision,
+			"updateRevision":     statefulSet.Status.UpdateRevision,
+			"conditions":         statefulSet.Status.Conditions,
+			"observedGeneration": statefulSet.Status.ObservedGeneration,
+			"creationTimestamp":  statefulSet.CreationTimestamp,
+		}
+	case KindDaemonSet:
+		daemonSet, err := c.clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daemonset %s in namespace %s: %v", name, namespace, err)
+		}
+
+		status = map[string]interface{}{
+			"kind":               KindDaemonSet,
+			"name":               daemonSet.Name,
+			"namespace":          daemonSet.Namespace,
+			"replicas":           daemonSet.Status.DesiredNumberScheduled,
+			"available":          daemonSet.Status.NumberAvailable,
+			"ready":              daemonSet.Status.NumberReady,
+			"updated":            daemonSet.Status.UpdatedNumberScheduled,
+			"unavailable":        daemonSet.Status.NumberUnavailable,
+			"misscheduled":       daemonSet.Status.NumberMisscheduled,
+			"conditions":         daemonSet.Status.Conditions,
+			"observedGeneration": daemonSet.Status.ObservedGeneration,
+			"creationTimestamp":  daemonSet.CreationTimestamp,
+		}
+	default:
+		status, err = c.GetDeploymentStatus(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		status["kind"] = KindDeployment
+		return status, nil
+	}
+
+	return toJSONMap(status)
+}
+
+// ListWorkloads retrieves deployments, statefulsets and daemonsets, optionally filtered by namespace and kind,
+// then filtered, sorted and paged by query
+func (c *Client) ListWorkloads(ctx context.Context, namespace, kind string, query ListQuery) ([]DeploymentInfo, *ListPage, error) {
+	kinds := []string{KindDeployment, KindStatefulSet, KindDaemonSet}
+	if kind != "" {
+		parsed, err := ParseKind(kind)
+		if err != nil {
+			return nil, nil, err
+		}
+		kinds = []string{parsed}
+	}
+
+	var workloads []DeploymentInfo
+	for _, kind := range kinds {
+		switch kind {
+		case KindStatefulSet:
+			statefulSets, err := c.listStatefulSets(ctx, namespace)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, statefulSet := range statefulSets {
+				workloads = append(workloads, DeploymentInfo{
+					Kind:              KindStatefulSet,
+					Name:              statefulSet.Name,
+					Namespace:         statefulSet.Namespace,
+					Labels:            statefulSet.Labels,
+					Status:            workloadStatus(derefReplicas(statefulSet.Spec.Replicas), statefulSet.Status.ReadyReplicas),
+					Replicas:          derefReplicas(statefulSet.Spec.Replicas),
+					AvailableReplicas: statefulSet.Status.AvailableReplicas,
+					ReadyReplicas:     statefulSet.Status.ReadyReplicas,
+					UpdatedReplicas:   statefulSet.Status.UpdatedReplicas,
+					CreationTimestamp: statefulSet.CreationTimestamp,
+				})
+			}
+		case KindDaemonSet:
+			daemonSets, err := c.listDaemonSets(ctx, namespace)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, daemonSet := range daemonSets {
+				workloads = append(workloads, DeploymentInfo{
+					Kind:              KindDaemonSet,
+					Name:              daemonSet.Name,
+					Namespace:         daemonSet.Namespace,
+					Labels:            daemonSet.Labels,
+					Status:            workloadStatus(daemonSet.Status.DesiredNumberScheduled, daemonSet.Status.NumberReady),
+					Replicas:          daemonSet.Status.DesiredNumberScheduled,
+					AvailableReplicas: daemonSet.Status.NumberAvailable,
+					ReadyReplicas:     daemonSet.Status.NumberReady,
+					UpdatedReplicas:   daemonSet.Status.UpdatedNumberScheduled,
+					CreationTimestamp: daemonSet.CreationTimestamp,
+				})
+			}
+		default:
+			deployments, err := c.ListDeployments(ctx, namespace)
+			if err != nil {
+				return nil, nil, err
+			}
+			workloads = append(workloads, deployments...)
+		}
+	}
+
+	// A continue token of one kind is not valid for another
+	schema := workloadListSchema
+	if len(kinds) == 1 {
+		schema.resource = strings.ToLower(kinds[0]) + "s"
+	}
+	return applyListQuery(workloads, query, schema, namespace, workloadListEntry)
+}
+
+// workloadStatus summarizes the readiness of a workload
+func workloadStatus(desired, ready int32) string {
+	switch {
+	case desired == 0:
+		return "ScaledDown"
+	case ready >= desired:
+		return "Ready"
+	case ready == 0:
+		return "Unavailable"
+	default:
+		return "Degraded"
+	}
+}
+
+// derefReplicas returns the replica count of a spec, which defaults to 1 when unset
+func derefReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
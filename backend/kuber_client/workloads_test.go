@@ -0,0 +1,136 @@
+package kuberclient
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestUpdateWorkload(t *testing.T) {
+	tests := []struct {
+		name        string
+		dryRun      bool
+		mutate      func(*appsv1.Deployment) error
+		wantChanges []SpecChange
+		wantImage   string
+		wantUpdates int
+	}{
+		{
+			name:   "dry run returns the spec diff",
+			dryRun: true,
+			mutate: func(deployment *appsv1.Deployment) error {
+				replicas := int32(5)
+				deployment.Spec.Replicas = &replicas
+				deployment.Spec.Template.Spec.Containers[0].Image = "web:v3"
+				return nil
+			},
+			wantChanges: []SpecChange{
+				{Path: "replicas", Before: nil, After: float64(5)},
+				{Path: "template.spec.containers[0].image", Before: "web:v2", After: "web:v3"},
+			},
+			wantImage:   "web:v2",
+			wantUpdates: 0, // Evaluated locally, the fake clientset would persist it
+		},
+		{
+			name:   "update is persisted without a diff",
+			dryRun: false,
+			mutate: func(deployment *appsv1.Deployment) error {
+				deployment.Spec.Template.Spec.Containers[0].Image = "web:v3"
+				return nil
+			},
+			wantImage:   "web:v3",
+			wantUpdates: 1,
+		},
+		{
+			name:   "no change is not sent",
+			dryRun: false,
+			mutate: func(*appsv1.Deployment) error {
+				return errNoChange
+			},
+			wantImage:   "web:v2",
+			wantUpdates: 0,
+		},
+		{
+			name:   "no change in a dry run",
+			dryRun: true,
+			mutate: func(*appsv1.Deployment) error {
+				return errNoChange
+			},
+			wantImage:   "web:v2",
+			wantUpdates: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, clientset := newTestClient(testDeployment("web:v2", 2, ""))
+
+			result, err := client.updateDeployment(context.Background(), "default", "web", tt.dryRun, tt.mutate)
+			if err != nil {
+				t.Fatalf("updateDeployment: %v", err)
+			}
+			if result.DryRun != tt.dryRun {
+				t.Errorf("got dryRun %v, want %v", result.DryRun, tt.dryRun)
+			}
+			if !reflect.DeepEqual(result.Changes, tt.wantChanges) {
+				t.Errorf("got changes %+v, want %+v", result.Changes, tt.wantChanges)
+			}
+
+			updates := 0
+			for _, action := range clientset.Actions() {
+				if action.GetVerb() == "update" {
+					updates++
+				}
+			}
+			if updates != tt.wantUpdates {
+				t.Errorf("got %d updates sent, want %d", updates, tt.wantUpdates)
+			}
+
+			deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if image := deployment.Spec.Template.Spec.Containers[0].Image; image != tt.wantImage {
+				t.Errorf("stored deployment runs %s, want %s", image, tt.wantImage)
+			}
+			if tt.dryRun && deployment.Spec.Replicas != nil {
+				t.Errorf("dry run stored replicas %d", *deployment.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestUpdateWorkloadServerSideDryRun(t *testing.T) {
+	client, clientset := newTestClient(testDeployment("web:v2", 2, ""))
+	client.localDryRun = false
+
+	// The API server answers a dry run with the object it would have stored, without persisting it
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, action.(k8stesting.UpdateAction).GetObject(), nil
+	})
+
+	result, err := client.updateDeployment(context.Background(), "default", "web", true, func(deployment *appsv1.Deployment) error {
+		deployment.Spec.Paused = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updateDeployment: %v", err)
+	}
+	want := []SpecChange{{Path: "paused", Before: nil, After: true}}
+	if !reflect.DeepEqual(result.Changes, want) {
+		t.Errorf("got changes %+v, want %+v", result.Changes, want)
+	}
+
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deployment.Spec.Paused {
+		t.Error("dry run paused the stored deployment")
+	}
+}
@@ -27,6 +27,6 @@ func main() {
 	err := app.Run()
 
 	if err != nil {
-		logger.Error("Failed to start server", err)
+		logger.Error("Failed to start server", "error", err)
 	}
 }